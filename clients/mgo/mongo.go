@@ -2,6 +2,7 @@ package mgo
 
 import (
 	"context"
+	"errors"
 	"net/url"
 	"os"
 	"time"
@@ -103,6 +104,20 @@ func IsNotFoundError(err error) bool {
 	return err == mongo.ErrNoDocuments || err == mongo.ErrNilDocument
 }
 
+// IsDuplicateKeyError 唯一索引冲突的错误
+func IsDuplicateKeyError(err error) bool {
+	var we mongo.WriteException
+	if errors.As(err, &we) {
+		for _, e := range we.WriteErrors {
+			if e.Code == 11000 {
+				return true
+			}
+		}
+	}
+	var ce mongo.CommandError
+	return errors.As(err, &ce) && ce.Code == 11000
+}
+
 // IsInvalidHex 判断是否ID格式错误
 func IsInvalidHex(err error) bool {
 	return err == primitive.ErrInvalidHex
@@ -180,3 +195,37 @@ func (c *Collection) Replace(filter interface{}, target interface{}, opt *option
 	}
 	return c.collection.FindOneAndReplace(context.Background(), filter, target, opt).Err()
 }
+
+// Upsert 按filter替换文档, 不存在时插入
+//
+// * Replace基于FindOneAndReplace, 插入新文档时会返回mongo.ErrNoDocuments, 写入新文档时使用Upsert
+func (c *Collection) Upsert(filter interface{}, target interface{}) error {
+	opt := options.Replace().SetUpsert(true)
+	_, err := c.collection.ReplaceOne(context.Background(), filter, target, opt)
+	return err
+}
+
+// CreateTTLIndex 在指定字段上创建过期索引, 文档在该字段的时间之后expire被自动删除
+func (c *Collection) CreateTTLIndex(field string, expire time.Duration) error {
+	opt := options.Index().SetExpireAfterSeconds(int32(expire.Seconds()))
+	model := mongo.IndexModel{
+		Keys:    bson.M{field: 1},
+		Options: opt,
+	}
+	_, err := c.collection.Indexes().CreateOne(context.Background(), model)
+	return err
+}
+
+// CreateUniqueIndex 在fields上创建联合唯一索引
+func (c *Collection) CreateUniqueIndex(fields ...string) error {
+	keys := bson.D{}
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: 1})
+	}
+	model := mongo.IndexModel{
+		Keys:    keys,
+		Options: options.Index().SetUnique(true),
+	}
+	_, err := c.collection.Indexes().CreateOne(context.Background(), model)
+	return err
+}
@@ -212,6 +212,17 @@ func (mq *AMQP) DeclareQueue(queueName string) (queue amqp.Queue, err error) {
 	return
 }
 
+// DeclareQueueWithArgs 创建带参数的Queue, 如消息过期时间和死信exchange
+func (mq *AMQP) DeclareQueueWithArgs(queueName string, args amqp.Table) (queue amqp.Queue, err error) {
+	channel, err := mq.GetChannel()
+	defer mq.ReleaseChannel(channel)
+	if err != nil {
+		return
+	}
+	queue, err = channel.QueueDeclare(queueName, true, false, false, false, args)
+	return
+}
+
 // DeleteQueue 删除对应的Queue
 func (mq *AMQP) DeleteQueue(queueName string) (err error) {
 	channel, err := mq.GetChannel()
@@ -1,6 +1,9 @@
 package rabbitmq
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/streadway/amqp"
 )
 
@@ -48,3 +51,30 @@ func (q *RabbitQueue) BindExchange(exchangeName, queueName, routingKey string) e
 	exchange.Queues = []*QueueContext{queue}
 	return exchange.BindWithQueues(q.conn)
 }
+
+// PushDelay 延时delay后把消息发布到指定的exchange
+//
+// * 每个延时对应一个设置了x-message-ttl的队列, 消息过期后经死信转发到exchangeName
+// * 同一队列中的消息延时相同, 不会被队头更长延时的消息阻塞
+func (q *RabbitQueue) PushDelay(exchangeName, routingKey string, data []byte, delay time.Duration) error {
+	delayExchange := &ExchangeContext{
+		ExchangeName: exchangeName + ".delay",
+		ExchangeKind: amqp.ExchangeDirect,
+	}
+	if err := q.conn.DeclareExchange(delayExchange); err != nil {
+		return err
+	}
+	queueName := fmt.Sprintf("%s.%s.delay.%d", exchangeName, routingKey, delay.Milliseconds())
+	args := amqp.Table{
+		"x-message-ttl":             delay.Milliseconds(),
+		"x-dead-letter-exchange":    exchangeName,
+		"x-dead-letter-routing-key": routingKey,
+	}
+	if _, err := q.conn.DeclareQueueWithArgs(queueName, args); err != nil {
+		return err
+	}
+	if err := q.conn.ExchangeBindWithQueue(delayExchange.ExchangeName, queueName, queueName); err != nil {
+		return err
+	}
+	return q.conn.Publish(delayExchange, queueName, string(data))
+}
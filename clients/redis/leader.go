@@ -0,0 +1,216 @@
+package redis
+
+import (
+	"ark-common/utils/system"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultLeaseTTL 租约的有效期
+	DefaultLeaseTTL = 15 * time.Second
+	// DefaultRenewInterval 续约和竞选的间隔, 需小于租约有效期
+	DefaultRenewInterval = 5 * time.Second
+)
+
+// ErrNotLeader 当前节点不是leader或fencing token已过期
+var ErrNotLeader = errors.New("not the leader")
+
+// acquireScript 租约不存在时自增fencing token并写入 节点:token, 返回token, 否则返回0
+var acquireScript = redis.NewScript(`
+if redis.call("exists", KEYS[1]) == 0 then
+	local token = redis.call("incr", KEYS[2])
+	redis.call("set", KEYS[1], ARGV[1] .. ":" .. token, "PX", ARGV[2])
+	return token
+end
+return 0
+`)
+
+// renewScript 租约仍属于自己时延长有效期
+var renewScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("pexpire", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript 租约仍属于自己时主动释放
+var releaseScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0
+`)
+
+// Elector 基于redis租约的leader选举
+//
+// * 每次当选时fencing token单调递增, 下游写入时应携带token并拒绝比已见过的更小的token
+// * 续约失败(包括redis不可用)时立即放弃leader身份, 不等待租约过期
+// * OnElected/OnRevoked在选举循环中同步调用以保证顺序, 回调不能阻塞, 否则会推迟续约, 耗时的操作需要自行放到goroutine中
+type Elector struct {
+	client *redis.Client
+	name   string
+
+	mu     sync.RWMutex
+	leader bool
+	token  int64
+
+	NodeID        string            // 节点标识, 默认为主机名
+	TTL           time.Duration     // 租约有效期
+	RenewInterval time.Duration     // 续约和竞选的间隔
+	OnElected     func(token int64) // 当选leader时回调, 不能阻塞
+	OnRevoked     func(token int64) // 失去leader身份时回调, 不能阻塞
+}
+
+// NewElector 返回名为name的选举, 同名的选举之间竞争同一个leader
+func NewElector(client *redis.Client, name string) *Elector {
+	return &Elector{
+		client:        client,
+		name:          name,
+		NodeID:        system.GetHostName(),
+		TTL:           DefaultLeaseTTL,
+		RenewInterval: DefaultRenewInterval,
+	}
+}
+
+func (e *Elector) leaseKey() string {
+	return "leader:" + e.name
+}
+
+func (e *Elector) tokenKey() string {
+	return "leader:" + e.name + ":fencing"
+}
+
+func (e *Elector) leaseValue(token int64) string {
+	return fmt.Sprintf("%s:%d", e.NodeID, token)
+}
+
+// IsLeader 返回当前节点是否为leader
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+// Token 返回当前的fencing token, 不是leader时返回0
+func (e *Elector) Token() int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if !e.leader {
+		return 0
+	}
+	return e.token
+}
+
+// Fence 确认token仍是redis中当前租约的token, 用于执行单例操作前的检查
+func (e *Elector) Fence(token int64) error {
+	value, err := e.client.Get(e.leaseKey()).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	if token == 0 || value != e.leaseValue(token) {
+		return ErrNotLeader
+	}
+	return nil
+}
+
+// Run 竞选并保持leader身份, 直到stop被关闭后释放租约
+func (e *Elector) Run(stop <-chan struct{}) {
+	interval := e.RenewInterval
+	if interval <= 0 || interval >= e.TTL {
+		interval = e.TTL / 3
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		e.tick()
+		select {
+		case <-stop:
+			e.Release()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Elector) tick() {
+	if e.IsLeader() {
+		if err := e.Renew(); err != nil {
+			log.Warnf("node [%s] renew lease [%s] failed: %v", e.NodeID, e.name, err)
+		}
+		return
+	}
+	if _, err := e.Acquire(); err != nil {
+		log.Errorf("node [%s] acquire lease [%s] failed: %v", e.NodeID, e.name, err)
+	}
+}
+
+// Acquire 租约不存在时竞选leader, 当选时返回新的fencing token, 租约被其他节点持有时返回0
+//
+// * 已经是leader时直接返回当前的token
+func (e *Elector) Acquire() (token int64, err error) {
+	if token = e.Token(); token != 0 {
+		return token, nil
+	}
+	token, err = acquireScript.Run(e.client, []string{e.leaseKey(), e.tokenKey()}, e.NodeID, e.TTL.Milliseconds()).Int64()
+	if err != nil || token == 0 {
+		return 0, err
+	}
+	e.mu.Lock()
+	e.leader, e.token = true, token
+	e.mu.Unlock()
+	log.Infof("node [%s] elected as leader of [%s] with token %d", e.NodeID, e.name, token)
+	if e.OnElected != nil {
+		e.OnElected(token)
+	}
+	return token, nil
+}
+
+// Renew 延长自己持有的租约, 续约失败(包括redis不可用)时立即放弃leader身份
+func (e *Elector) Renew() (err error) {
+	token := e.Token()
+	if token == 0 {
+		return ErrNotLeader
+	}
+	renewed, err := renewScript.Run(e.client, []string{e.leaseKey()}, e.leaseValue(token), e.TTL.Milliseconds()).Int64()
+	if err == nil && renewed == 0 {
+		err = ErrNotLeader
+	}
+	if err != nil {
+		e.revoke()
+	}
+	return err
+}
+
+// Release 主动释放自己持有的租约并放弃leader身份, 其他节点可以立即当选
+func (e *Elector) Release() (err error) {
+	token := e.Token()
+	if token == 0 {
+		return nil
+	}
+	if err = releaseScript.Run(e.client, []string{e.leaseKey()}, e.leaseValue(token)).Err(); err != nil {
+		log.Errorf("node [%s] release lease [%s] failed: %v", e.NodeID, e.name, err)
+	}
+	e.revoke()
+	return err
+}
+
+func (e *Elector) revoke() {
+	e.mu.Lock()
+	if !e.leader {
+		e.mu.Unlock()
+		return
+	}
+	token := e.token
+	e.leader = false
+	e.mu.Unlock()
+	log.Warnf("node [%s] lost leader of [%s] with token %d", e.NodeID, e.name, token)
+	if e.OnRevoked != nil {
+		e.OnRevoked(token)
+	}
+}
@@ -0,0 +1,114 @@
+package redis_test
+
+import (
+	cache "ark-common/clients/redis"
+	"ark-common/utils/testenv"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newElectors 返回两个竞争同一租约的节点, 测试结束后调用cleanup删除租约
+func newElectors(t *testing.T) (a, b *cache.Elector, cleanup func()) {
+	client := testenv.Redis(t)
+	name := fmt.Sprintf("test-elector-%d", time.Now().UnixNano())
+	cleanup = func() {
+		client.Del("leader:"+name, "leader:"+name+":fencing")
+	}
+	a = cache.NewElector(client, name)
+	a.NodeID = "node-a"
+	b = cache.NewElector(client, name)
+	b.NodeID = "node-b"
+	return a, b, cleanup
+}
+
+func TestElectorAcquire(t *testing.T) {
+	a, b, cleanup := newElectors(t)
+	defer cleanup()
+	elected := int64(0)
+	a.OnElected = func(token int64) { elected = token }
+
+	token, err := a.Acquire()
+	if err != nil || token == 0 {
+		t.Fatalf("node-a acquire failed: token %d, err %v", token, err)
+	}
+	if !a.IsLeader() || a.Token() != token || elected != token {
+		t.Fatalf("node-a should be leader with token %d, got leader %v token %d elected %d", token, a.IsLeader(), a.Token(), elected)
+	}
+	if again, err := a.Acquire(); err != nil || again != token {
+		t.Fatalf("leader acquire again should return current token %d, got %d, err %v", token, again, err)
+	}
+	if other, err := b.Acquire(); err != nil || other != 0 || b.IsLeader() {
+		t.Fatalf("node-b should not be elected while lease is held, got token %d, err %v", other, err)
+	}
+	if err = a.Fence(token); err != nil {
+		t.Fatalf("fence current token failed: %v", err)
+	}
+	if err = b.Fence(token); err != cache.ErrNotLeader {
+		t.Fatalf("node-b fence with node-a token should fail, got %v", err)
+	}
+}
+
+func TestElectorRenew(t *testing.T) {
+	a, b, cleanup := newElectors(t)
+	defer cleanup()
+	a.TTL = time.Second
+
+	if err := a.Renew(); err != cache.ErrNotLeader {
+		t.Fatalf("renew without lease should return ErrNotLeader, got %v", err)
+	}
+	token, err := a.Acquire()
+	if err != nil || token == 0 {
+		t.Fatalf("node-a acquire failed: token %d, err %v", token, err)
+	}
+	for i := 0; i < 3; i++ {
+		time.Sleep(500 * time.Millisecond)
+		if err = a.Renew(); err != nil {
+			t.Fatalf("renew %d failed: %v", i, err)
+		}
+	}
+	if other, _ := b.Acquire(); other != 0 {
+		t.Fatalf("renewed lease should not expire, node-b got token %d", other)
+	}
+
+	// 租约过期后被其他节点取得, 旧leader续约失败并放弃leader身份
+	revoked := int64(0)
+	a.OnRevoked = func(token int64) { revoked = token }
+	time.Sleep(1500 * time.Millisecond)
+	other, err := b.Acquire()
+	if err != nil || other <= token {
+		t.Fatalf("node-b should be elected with a larger token than %d, got %d, err %v", token, other, err)
+	}
+	if err = a.Renew(); err != cache.ErrNotLeader {
+		t.Fatalf("renew lost lease should return ErrNotLeader, got %v", err)
+	}
+	if a.IsLeader() || revoked != token {
+		t.Fatalf("node-a should be revoked with token %d, got leader %v revoked %d", token, a.IsLeader(), revoked)
+	}
+	if err = a.Fence(token); err != cache.ErrNotLeader {
+		t.Fatalf("fence stale token should fail, got %v", err)
+	}
+}
+
+func TestElectorRelease(t *testing.T) {
+	a, b, cleanup := newElectors(t)
+	defer cleanup()
+
+	if err := a.Release(); err != nil {
+		t.Fatalf("release without lease should be no-op, got %v", err)
+	}
+	token, err := a.Acquire()
+	if err != nil || token == 0 {
+		t.Fatalf("node-a acquire failed: token %d, err %v", token, err)
+	}
+	if err = a.Release(); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if a.IsLeader() || a.Token() != 0 {
+		t.Fatalf("node-a should not be leader after release")
+	}
+	other, err := b.Acquire()
+	if err != nil || other != token+1 {
+		t.Fatalf("node-b should be elected immediately with token %d, got %d, err %v", token+1, other, err)
+	}
+}
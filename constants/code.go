@@ -26,6 +26,12 @@ const (
 	InvalidResourceID = 400003
 	// InvalidCloudAccountID 非法的云商账户ID
 	InvalidCloudAccountID = 400004
+	// QuotaExceeded 超出云商配额
+	QuotaExceeded = 400005
+	// CidrOverlap 网段与已有网段重叠
+	CidrOverlap = 400006
+	// CidrExhausted 地址池中没有可分配的网段
+	CidrExhausted = 400007
 )
 
 // CodeMessage code和文本对应关系
@@ -55,5 +61,17 @@ var (
 			EN: "invalid cloudaccount id",
 			CN: "非法的云商账户ID",
 		},
+		QuotaExceeded: {
+			EN: "cloud quota exceeded",
+			CN: "超出云商配额",
+		},
+		CidrOverlap: {
+			EN: "cidr overlaps with existing cidr",
+			CN: "网段与已有网段重叠",
+		},
+		CidrExhausted: {
+			EN: "no free cidr in the pool",
+			CN: "地址池中没有可分配的网段",
+		},
 	}
 )
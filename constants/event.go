@@ -0,0 +1,15 @@
+package constants
+
+// 资源变更事件
+const (
+	EventCreated = "created" // 云端新出现的资源
+	EventUpdated = "updated" // 资源的字段发生变化
+	EventDeleted = "deleted" // 资源在云端已删除
+)
+
+// 发布变更事件的资源类型
+const (
+	ResourceInstance = "instance"
+	ResourceDisk     = "disk"
+	ResourceEip      = "eip"
+)
@@ -14,6 +14,14 @@ const (
 	HandleSyncVPC               = "SyncVPC"
 	HandleSyncSubnet            = "SyncSubnet"
 	HandleSyncEip               = "SyncEip"
+	HandleSyncQuota             = "SyncQuota"
+	HandleSyncAccount           = "SyncAccount" // 账号全量同步的父作业
+
+	// 监控类任务
+	HandleCollectMetric = "CollectMetric"
+
+	// 分析类任务
+	HandleLintSecurityGroup = "LintSecurityGroup"
 
 	// 资源维护类任务
 	HandleCreateEip = "createEip"
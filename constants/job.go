@@ -6,6 +6,22 @@ const (
 	FAILEDJOB  = "failed"
 	CANCELJOB  = "cancel"
 	WORKINGJOB = "working"
+	RETRYJOB   = "retrying" // 失败后等待重试
 
 	SYSTEMUSER = "system"
 )
+
+// 作业失败的错误类型, 用于判断能否重试
+const (
+	ErrorThrottling = "throttling" // 云商接口限流
+	ErrorNetwork    = "network"    // 网络超时、连接中断等
+	ErrorServer     = "server"     // 云商服务端内部错误
+	ErrorUnknown    = "unknown"    // 其他错误, 如参数错误、权限不足
+)
+
+// DefaultRetryableErrors 作业未指定RetryOn时可重试的错误类型
+var DefaultRetryableErrors = []string{
+	ErrorThrottling,
+	ErrorNetwork,
+	ErrorServer,
+}
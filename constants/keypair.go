@@ -0,0 +1,15 @@
+package constants
+
+// 密钥对类型
+const (
+	KeyTypeRSA     = "rsa"
+	KeyTypeEd25519 = "ed25519"
+	KeyTypeECDSA   = "ecdsa"
+)
+
+// 私钥库的操作
+const (
+	KeystoreStore    = "store"
+	KeystoreRetrieve = "retrieve"
+	KeystoreDelete   = "delete"
+)
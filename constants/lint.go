@@ -0,0 +1,20 @@
+package constants
+
+// 风险等级
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+)
+
+// 安全组规则的风险类型
+const (
+	LintOpenAdminPort  = "openAdminPort"  // 对全网开放管理/数据库端口
+	LintAllProtocol    = "allProtocol"    // 入站放行全部协议
+	LintWidePortRange  = "widePortRange"  // 端口范围过大
+	LintDuplicateRule  = "duplicateRule"  // 重复的规则
+	LintShadowedRule   = "shadowedRule"   // 被更高优先级规则覆盖而不生效的规则
+	LintRedundantRule  = "redundantRule"  // 被更高优先级规则包含的冗余规则
+	LintNoEgressRule   = "noEgressRule"   // 没有出站规则
+	LintUnparsableRule = "unparsableRule" // 无法解析的规则
+)
@@ -0,0 +1,21 @@
+package constants
+
+// 统一的监控指标名
+const (
+	MetricCPUUsage    = "cpuUsage"    // CPU使用率(%)
+	MetricMemoryUsage = "memoryUsage" // 内存使用率(%)
+	MetricDiskRead    = "diskRead"    // 磁盘读速率(Byte/s)
+	MetricDiskWrite   = "diskWrite"   // 磁盘写速率(Byte/s)
+	MetricNetIn       = "netIn"       // 公网入带宽(Byte/s)
+	MetricNetOut      = "netOut"      // 公网出带宽(Byte/s)
+)
+
+// AllMetric 采集的所有监控指标
+var AllMetric = []string{
+	MetricCPUUsage,
+	MetricMemoryUsage,
+	MetricDiskRead,
+	MetricDiskWrite,
+	MetricNetIn,
+	MetricNetOut,
+}
@@ -0,0 +1,15 @@
+package constants
+
+const (
+	// PriceUnitHour 按小时计价
+	PriceUnitHour = "hour"
+	// PriceUnitMonth 按月计价
+	PriceUnitMonth = "month"
+
+	// CurrencyCNY 人民币
+	CurrencyCNY = "CNY"
+
+	PriceResourceInstance = "instance"
+	PriceResourceDisk     = "disk"
+	PriceResourceEip      = "eip"
+)
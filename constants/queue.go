@@ -4,3 +4,15 @@ const (
 	LeaderExchange    = "SyncIaaS"
 	SyncJobRoutingKey = "syncjob.#"
 )
+
+const (
+	// ResourceExchange 资源变更事件, routingKey为 resource.<cloud>.<type>.<event>
+	ResourceExchange = "ResourceEvents"
+)
+
+const (
+	// DeadLetterExchange 重试耗尽后的作业, routingKey为 deadjob.<action>
+	DeadLetterExchange   = "SyncIaaS.dead"
+	DeadLetterQueue      = "SyncIaaS.dead"
+	DeadLetterRoutingKey = "deadjob.#"
+)
@@ -0,0 +1,10 @@
+package constants
+
+// 统一的配额名
+const (
+	QuotaInstanceVCPU  = "instanceVCPU"  // 按量实例的vCPU总数
+	QuotaInstanceCount = "instanceCount" // 按量实例数
+	QuotaDiskCapacity  = "diskCapacity"  // 按量云盘总容量(GiB)
+	QuotaEip           = "eip"           // 弹性公网IP数
+	QuotaSecurityGroup = "securityGroup" // 安全组数
+)
@@ -0,0 +1,7 @@
+package constants
+
+// 服务实例的状态
+const (
+	ServiceAlive = "alive" // 按时上报心跳
+	ServiceDead  = "dead"  // 主动注销或错过多次心跳
+)
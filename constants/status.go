@@ -0,0 +1,54 @@
+package constants
+
+// 与云商无关的实例状态
+const (
+	InstanceStatusPending     = "pending"
+	InstanceStatusStarting    = "starting"
+	InstanceStatusRunning     = "running"
+	InstanceStatusStopping    = "stopping"
+	InstanceStatusStopped     = "stopped"
+	InstanceStatusRebooting   = "rebooting"
+	InstanceStatusTerminating = "terminating"
+	InstanceStatusTerminated  = "terminated"
+	InstanceStatusFailed      = "failed"
+)
+
+// 与云商无关的磁盘状态
+const (
+	DiskStatusCreating  = "creating"
+	DiskStatusAvailable = "available" // 未挂载
+	DiskStatusAttaching = "attaching"
+	DiskStatusInUse     = "inUse" // 已挂载
+	DiskStatusDetaching = "detaching"
+	DiskStatusResizing  = "resizing"
+	DiskStatusDeleting  = "deleting"
+)
+
+// 与云商无关的弹性公网IP状态
+const (
+	EipStatusCreating  = "creating"
+	EipStatusAvailable = "available" // 未绑定
+	EipStatusBinding   = "binding"
+	EipStatusInUse     = "inUse" // 已绑定
+	EipStatusUnbinding = "unbinding"
+	EipStatusReleasing = "releasing"
+)
+
+// 与云商无关的付费方式
+const (
+	ChargeTypePrePaid  = "prePaid"  // 包年包月
+	ChargeTypePostPaid = "postPaid" // 按量付费
+	ChargeTypeSpot     = "spot"     // 竞价实例
+)
+
+// 与云商无关的磁盘类型
+const (
+	DiskCategoryBasic      = "basic"      // 普通云盘
+	DiskCategoryEfficiency = "efficiency" // 高效云盘
+	DiskCategorySSD        = "ssd"
+	DiskCategoryESSD       = "essd" // 增强型SSD
+	DiskCategoryLocal      = "local"
+)
+
+// StatusUnknown 没有对应的统一取值
+const StatusUnknown = "unknown"
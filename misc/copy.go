@@ -0,0 +1,79 @@
+package misc
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/plugin"
+	"ark-common/resource/navite"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// CopySecurityGroup 在目标账号的ac.RunRegionID下创建与src相同的安全组, 目标可以是其他云商
+//
+// * 规则的转换见plugin.ConvertSecurityGroupRules, 无法转换的规则记录在Skipped中, 不会中断复制
+// * 规则创建失败时删除已创建的安全组
+// * dryRun为true时只返回转换结果不做变更
+func CopySecurityGroup(rbd *mgo.Client, src *navite.SecurityGroup, rules []*navite.SecurityGroupRule, ac *navite.CloudAccount, vpcID string, dryRun bool) (result *navite.SecurityGroupCopyResult, err error) {
+	driver := plugin.GetCloudDriver(ac)
+	if driver == nil {
+		return nil, fmt.Errorf("not support cloud %s", ac.CloudName)
+	}
+	target := &navite.SecurityGroup{
+		CloudName:   ac.CloudName,
+		AccountID:   ac.AccountID(),
+		RegionID:    ac.RunRegionID,
+		GroupName:   src.GroupName,
+		VPCID:       vpcID,
+		Description: src.Description,
+	}
+	result = &navite.SecurityGroupCopyResult{
+		DryRun: dryRun,
+		Source: src,
+		Target: target,
+	}
+
+	result.Copied, result.Skipped = plugin.ConvertSecurityGroupRules(src.CloudName, ac.CloudName, rules)
+	for _, rule := range result.Copied {
+		rule.AccountID = target.AccountID
+		rule.RegionID = target.RegionID
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	if err = driver.NewSecurityGroup(target); err != nil {
+		return result, err
+	}
+	target.CreatedTime = time.Now()
+	target.SyncedTime = time.Now()
+	for _, rule := range result.Copied {
+		rule.GroupID = target.GroupID
+		rule.GroupName = target.GroupName
+	}
+	if len(result.Copied) > 0 {
+		if err = driver.NewSecurityGroupRules(result.Copied...); err != nil {
+			if e := driver.DeleteSecurityGroup(target.GroupID); e != nil {
+				log.Errorf("rollback securityGroup [%s] failed: %v", target.GroupID, e)
+			}
+			return result, err
+		}
+	}
+
+	filter := bson.M{
+		"accountId": target.AccountID,
+		"groupId":   target.GroupID,
+	}
+	if err = rbd.Table(navite.SecurityGroupTable).Upsert(filter, target); err != nil {
+		log.Errorf("upsert securityGroup [%+v] failed: %v", target, err)
+	}
+	copied := driver.GetSecurityGroupRuleList(target.GroupID)
+	if e := driver.SyncError(); e != nil {
+		log.Warnf("get securityGroup [%s] rules failed, rules will be saved by next sync: %v", target.GroupID, e)
+		return result, nil
+	}
+	SaveSecurityGroupRules(rbd, target.GroupID, copied)
+	return result, nil
+}
@@ -0,0 +1,108 @@
+package misc
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/constants"
+	"ark-common/param"
+	"ark-common/plugin"
+	"ark-common/resource/catalog"
+	"ark-common/resource/manage"
+	"ark-common/resource/navite"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// RunInstance 创建实例
+//
+// * 驱动只使用ImageID, 没有指定ImageID时先按OS*字段查找镜像, 两者都没有时返回参数错误
+// * 创建前检查剩余配额
+func RunInstance(rbd *mgo.Client, driver plugin.ResourceDriver, p *param.RunInstanceParam) (instanceIDList []string, err error) {
+	if errCode := catalog.ResolveImage(rbd, p); errCode != constants.Success {
+		return nil, fmt.Errorf("resolve image failed: %s", constants.CodeMessage[errCode][constants.EN])
+	}
+	if errCode := manage.CheckRunInstanceQuota(rbd, p); errCode != constants.Success {
+		return nil, fmt.Errorf("check quota failed: %s", constants.CodeMessage[errCode][constants.EN])
+	}
+	return driver.RunInstance(p)
+}
+
+// InquiryInstancePrice 创建实例询价, 没有指定ImageID时先按OS*字段查找镜像
+func InquiryInstancePrice(rbd *mgo.Client, driver plugin.ResourceDriver, p *param.RunInstanceParam) (price *navite.Price, err error) {
+	if errCode := catalog.ResolveImage(rbd, p); errCode != constants.Success {
+		return nil, fmt.Errorf("resolve image failed: %s", constants.CodeMessage[errCode][constants.EN])
+	}
+	return driver.InquiryInstancePrice(p)
+}
+
+// ModifyInstanceSpec 调整实例规格, 成功后更新实例记录
+func ModifyInstanceSpec(rbd *mgo.Client, driver plugin.ResourceDriver, instance *navite.Instance, instanceType string) (err error) {
+	if err = driver.ModifyInstanceSpec(instance, instanceType); err != nil {
+		return err
+	}
+	spec := &navite.InstanceSpec{}
+	filter := bson.M{
+		"accountId":      instance.AccountID,
+		"regionId":       instance.RegionID,
+		"instanceSpecId": instanceType,
+	}
+	if e := rbd.Table(navite.InstanceSpecTable).QueryOne(filter, spec, nil); e == nil {
+		instance.CPU = spec.CPU
+		instance.Memory = int(spec.Memory * 1024)
+	}
+	return saveInstance(rbd, instance)
+}
+
+// ModifyInstanceAttribute 修改实例名称/描述/主机名, 成功后更新实例记录
+func ModifyInstanceAttribute(rbd *mgo.Client, driver plugin.ResourceDriver, instance *navite.Instance, instanceName, description, hostName string) (err error) {
+	if err = driver.ModifyInstanceAttribute(instance, instanceName, description, hostName); err != nil {
+		return err
+	}
+	return saveInstance(rbd, instance)
+}
+
+// ResizeDisk 扩容云盘, 成功后更新云盘记录
+func ResizeDisk(rbd *mgo.Client, driver plugin.ResourceDriver, disk *navite.Disk, size int, online bool) (err error) {
+	if err = driver.ResizeDisk(disk, size, online); err != nil {
+		return err
+	}
+	plugin.UnifyDisk(disk)
+	filter := bson.M{
+		"accountId": disk.AccountID,
+		"diskId":    disk.DiskID,
+	}
+	if err = rbd.Table(navite.DiskTable).Upsert(filter, disk); err != nil {
+		log.Errorf("upsert disk [%+v] failed: %v", disk, err)
+	}
+	return err
+}
+
+// AttachKeypair 为实例绑定密钥对, 成功后更新实例记录
+func AttachKeypair(rbd *mgo.Client, driver plugin.ResourceDriver, instance *navite.Instance, keypairID string) (err error) {
+	if err = driver.AttachKeypair(instance, keypairID); err != nil {
+		return err
+	}
+	return saveInstance(rbd, instance)
+}
+
+// DetachKeypair 解绑实例的密钥对, 成功后更新实例记录
+func DetachKeypair(rbd *mgo.Client, driver plugin.ResourceDriver, instance *navite.Instance, keypairID string) (err error) {
+	if err = driver.DetachKeypair(instance, keypairID); err != nil {
+		return err
+	}
+	return saveInstance(rbd, instance)
+}
+
+// saveInstance 更新实例记录, 驱动可能修改了Status, 保存前重新计算统一状态
+func saveInstance(rbd *mgo.Client, instance *navite.Instance) (err error) {
+	plugin.UnifyInstance(instance)
+	filter := bson.M{
+		"accountId":  instance.AccountID,
+		"instanceId": instance.InstanceID,
+	}
+	if err = rbd.Table(navite.InstanceTable).Upsert(filter, instance); err != nil {
+		log.Errorf("upsert instance [%+v] failed: %v", instance, err)
+	}
+	return err
+}
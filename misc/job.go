@@ -0,0 +1,53 @@
+package misc
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/clients/rabbitmq"
+	"ark-common/constants"
+	"ark-common/resource/navite"
+	"ark-common/utils/tool"
+	"encoding/json"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ReplayJob 以新的作业重新执行已投递到死信队列的作业, 原作业保留用于排查
+func ReplayJob(rbd *mgo.Client, q *rabbitmq.RabbitQueue, jobID, operator string) (job *navite.Job, err error) {
+	dead := &navite.Job{}
+	filter := bson.M{
+		"jobId": jobID,
+	}
+	if err = rbd.Table(navite.JobTable).QueryOne(filter, dead, nil); err != nil {
+		log.Errorf("query job [%s] failed: %v", jobID, err)
+		return nil, err
+	}
+	if !dead.DeadLettered {
+		return nil, fmt.Errorf("job %s is not dead lettered", jobID)
+	}
+
+	job = &navite.Job{
+		Action:      dead.Action,
+		CloudName:   dead.CloudName,
+		AccountID:   dead.AccountID,
+		RegionID:    dead.RegionID,
+		Params:      dead.Params,
+		JobID:       tool.UUID(),
+		Owner:       operator,
+		MaxAttempts: dead.MaxAttempts,
+		Backoff:     dead.Backoff,
+		MaxBackoff:  dead.MaxBackoff,
+		RetryOn:     dead.RetryOn,
+	}
+	if err = job.SetPending(rbd); err != nil {
+		return nil, err
+	}
+	body, _ := json.Marshal(job)
+	if err = q.Push(constants.LeaderExchange, amqp.ExchangeTopic, constants.SyncJobRoutingKey, body); err != nil {
+		log.Errorf("push job [%s] failed: %v", job.JobID, err)
+		return nil, err
+	}
+	return job, nil
+}
@@ -0,0 +1,44 @@
+package misc
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/plugin"
+	"ark-common/resource/keystore"
+	"ark-common/resource/navite"
+	"ark-common/utils/tool"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// CreateKeypair 生成keyType类型的密钥对并导入云商, 私钥加密保存到私钥库
+//
+// * 私钥保存失败时删除已导入的密钥对, 避免出现找不到私钥的密钥对
+func CreateKeypair(rbd *mgo.Client, driver plugin.ResourceDriver, ks *keystore.Keystore, keypair *navite.Keypair, keyType, operator string) (err error) {
+	privateKey, publicKey, err := tool.NewKeyPair(keyType)
+	if err != nil {
+		return err
+	}
+	keypair.PublicKey = string(publicKey)
+	if err = keypair.SetFingerprint(); err != nil {
+		return err
+	}
+	if err = driver.NewKeypair(keypair); err != nil {
+		return err
+	}
+	if err = ks.Store(operator, keypair, privateKey); err != nil {
+		if e := driver.DeleteKeypair(keypair.KeypairID); e != nil {
+			log.Errorf("rollback keypair [%s] failed: %v", keypair.KeypairID, e)
+		}
+		return err
+	}
+
+	filter := bson.M{
+		"accountId": keypair.AccountID,
+		"keypairId": keypair.KeypairID,
+	}
+	if err = rbd.Table(navite.KeyPairTable).Upsert(filter, keypair); err != nil {
+		log.Errorf("upsert keypair [%+v] failed: %v", keypair, err)
+	}
+	return err
+}
@@ -0,0 +1,31 @@
+package misc
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/clients/rabbitmq"
+	"ark-common/constants"
+	"ark-common/resource/navite"
+	"ark-common/utils/tool"
+	"encoding/json"
+
+	"github.com/streadway/amqp"
+)
+
+// SendLintSecurityGroupJob 发送检查安全组风险的作业
+//
+// * 每次同步完安全组规则(HandleSyncSecurityGroupRule)后都需要重新检查
+func SendLintSecurityGroupJob(rbd *mgo.Client, q *rabbitmq.RabbitQueue, accountID, cloudName, regionID string) {
+	job := &navite.Job{
+		Action:    constants.HandleLintSecurityGroup,
+		CloudName: cloudName,
+		AccountID: accountID,
+		RegionID:  regionID,
+		JobID:     tool.UUID(),
+		Owner:     constants.SYSTEMUSER,
+	}
+	if err := job.SetPending(rbd); err != nil {
+		return
+	}
+	body, _ := json.Marshal(job)
+	q.Push(constants.LeaderExchange, amqp.ExchangeTopic, constants.SyncJobRoutingKey, body)
+}
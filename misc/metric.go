@@ -0,0 +1,92 @@
+package misc
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/clients/rabbitmq"
+	"ark-common/constants"
+	"ark-common/plugin"
+	"ark-common/resource/navite"
+	"ark-common/utils/tool"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SendCollectMetricJob 发送采集实例监控数据的作业
+func SendCollectMetricJob(rbd *mgo.Client, q *rabbitmq.RabbitQueue, accountID, cloudName, regionID string) {
+	job := &navite.Job{
+		Action:    constants.HandleCollectMetric,
+		CloudName: cloudName,
+		AccountID: accountID,
+		RegionID:  regionID,
+		JobID:     tool.UUID(),
+		Owner:     constants.SYSTEMUSER,
+	}
+	if err := job.SetPending(rbd); err != nil {
+		return
+	}
+	body, _ := json.Marshal(job)
+	q.Push(constants.LeaderExchange, amqp.ExchangeTopic, constants.SyncJobRoutingKey, body)
+}
+
+// SetupMetricIndex 在监控数据表上创建按时间过期的TTL索引
+//
+// * 只需要在worker启动时调用一次, 不要在每次采集时调用
+func SetupMetricIndex(rbd *mgo.Client) (err error) {
+	if err = rbd.Table(navite.InstanceMetricTable).CreateTTLIndex("timestamp", navite.MetricRetention); err != nil {
+		log.Errorf("create ttl index on %s failed: %v", navite.InstanceMetricTable, err)
+	}
+	return err
+}
+
+// CollectInstanceMetric 采集账号在ac.RunRegionID下所有实例的监控数据, 降采样后写入mongo
+func CollectInstanceMetric(rbd *mgo.Client, ac *navite.CloudAccount, startTime, endTime time.Time) (err error) {
+	driver := plugin.GetMetricDriver(ac)
+	if driver == nil {
+		return fmt.Errorf("not support cloud %s", ac.CloudName)
+	}
+	table := rbd.Table(navite.InstanceMetricTable)
+
+	filter := bson.M{
+		"accountId": ac.AccountID(),
+		"regionId":  ac.RunRegionID,
+	}
+	instanceList := []*navite.Instance{}
+	mctx := context.Background()
+	cur, err := rbd.Table(navite.InstanceTable).Query(filter, 0, 0, nil)
+	if err != nil {
+		log.Errorf("filter [%v] instances failed: %v", filter, err)
+		return err
+	}
+	defer cur.Close(mctx)
+	if err = cur.All(mctx, &instanceList); err != nil {
+		log.Errorf("decord mgo document failed: %v", err)
+		return err
+	}
+
+	for _, instance := range instanceList {
+		for _, metricName := range constants.AllMetric {
+			points, e := driver.GetInstanceMetric(instance, metricName, startTime, endTime)
+			if e != nil {
+				log.Warnf("collect instance [%s] metric [%s] failed: %v", instance.InstanceID, metricName, e)
+				continue
+			}
+			for _, p := range navite.DownsampleMetric(points, navite.MetricInterval) {
+				pf := bson.M{
+					"instanceId": p.InstanceID,
+					"metricName": p.MetricName,
+					"timestamp":  p.Timestamp,
+				}
+				if e = table.Upsert(pf, p); e != nil {
+					log.Errorf("upsert metric [%+v] failed: %v", p, e)
+				}
+			}
+		}
+	}
+	return nil
+}
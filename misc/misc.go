@@ -2,17 +2,23 @@ package misc
 
 import (
 	"ark-common/clients/mgo"
-	"ark-common/clients/rabbitmq"
 	"ark-common/constants"
 	"ark-common/resource/navite"
 	"ark-common/utils/tool"
 	"encoding/json"
+	"fmt"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/streadway/amqp"
 )
 
+// JobQueue 发布作业的队列, 由rabbitmq.RabbitQueue实现, 测试中可以替换
+type JobQueue interface {
+	Push(exchangeName, exchangeKind string, routingKey string, data []byte) error
+}
+
 // SendPullRegionJob 发送拉取地域的作业
-func SendPullRegionJob(rbd *mgo.Client, q *rabbitmq.RabbitQueue, accountID, cloudName string) {
+func SendPullRegionJob(rbd *mgo.Client, q JobQueue, accountID, cloudName string) {
 	job := &navite.Job{
 		Action:    constants.HandleSyncRegion,
 		CloudName: cloudName,
@@ -20,7 +26,79 @@ func SendPullRegionJob(rbd *mgo.Client, q *rabbitmq.RabbitQueue, accountID, clou
 		JobID:     tool.UUID(),
 		Owner:     constants.SYSTEMUSER,
 	}
-	job.SetPending(rbd)
+	pushJob(rbd, q, job)
+}
+
+// SendSyncJob 发送账号在指定地域下的同步作业, action为constants.Handle*
+func SendSyncJob(rbd *mgo.Client, q JobQueue, accountID, cloudName, regionID, action string) (job *navite.Job) {
+	job = &navite.Job{
+		Action:    action,
+		CloudName: cloudName,
+		AccountID: accountID,
+		RegionID:  regionID,
+		JobID:     tool.UUID(),
+		Owner:     constants.SYSTEMUSER,
+	}
+	return pushJob(rbd, q, job)
+}
+
+// NewParentJob 创建父作业并置为working, 父作业不发布到队列, 状态由子作业汇总
+func NewParentJob(rbd *mgo.Client, action, accountID, cloudName, owner string) (job *navite.Job, err error) {
+	job = &navite.Job{
+		Action:    action,
+		CloudName: cloudName,
+		AccountID: accountID,
+		JobID:     tool.UUID(),
+		Owner:     owner,
+	}
+	if err = job.SetPending(rbd); err != nil {
+		return nil, err
+	}
+	if err = job.SetWorking(rbd, constants.SYSTEMUSER); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// SendChildJob 发送父作业下的同步作业
+func SendChildJob(rbd *mgo.Client, q JobQueue, parent *navite.Job, regionID, action string) (job *navite.Job) {
+	job = &navite.Job{
+		Action:    action,
+		CloudName: parent.CloudName,
+		AccountID: parent.AccountID,
+		RegionID:  regionID,
+		JobID:     tool.UUID(),
+		Owner:     parent.Owner,
+		ParentID:  parent.JobID,
+	}
+	return pushJob(rbd, q, job)
+}
+
+// SealParentJob 子作业全部发送后记录子作业总数, 没有子作业时取消父作业
+//
+// * 发送期间已结束的子作业不会提前结束父作业, 记录总数后重新汇总一次
+func SealParentJob(rbd *mgo.Client, parent *navite.Job, total int) (err error) {
+	if total == 0 {
+		return parent.SetCancel(rbd, "no child job")
+	}
+	if err = parent.SetProgress(rbd, 0, total, ""); err != nil {
+		return err
+	}
+	return navite.AggregateParentJob(rbd, parent.JobID)
+}
+
+// pushJob 记录pending的作业并发布到队列, 失败时返回nil
+//
+// * 发布失败时取消作业并记录原因, 不会停留在pending阻塞之后的调度
+func pushJob(rbd *mgo.Client, q JobQueue, job *navite.Job) *navite.Job {
+	if err := job.SetPending(rbd); err != nil {
+		return nil
+	}
 	body, _ := json.Marshal(job)
-	q.Push(constants.LeaderExchange, amqp.ExchangeTopic, constants.SyncJobRoutingKey, body)
+	if err := q.Push(constants.LeaderExchange, amqp.ExchangeTopic, constants.SyncJobRoutingKey, body); err != nil {
+		log.Errorf("publish job [%s] failed: %v", job.JobID, err)
+		job.SetCancel(rbd, fmt.Sprintf("publish job failed: %v", err))
+		return nil
+	}
+	return job
 }
@@ -0,0 +1,99 @@
+package misc
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/plugin"
+	"ark-common/resource/navite"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ReconcileSecurityGroupRules 将安全组的规则调整为desired
+//
+// * 先对比云端当前的规则生成变更计划, dryRun为true时只返回计划不做变更
+// * 规则按统一的规则模型比较, 协议和策略的大小写以及等价的端口写法(如 1/65535 和 -1/-1)不影响比较
+// * 优先级不同的规则视为不同的规则, 期望的规则没有指定优先级时沿用云端相同规则的优先级
+// * 腾讯云的优先级为规则的序号, 不参与比较, 见plugin.SecurityGroupRulePriorityKey
+// * 先新增后删除, 避免变更过程中出现访问中断
+// * 读取云端规则失败时中止, 不能把读取失败当成云端没有规则
+func ReconcileSecurityGroupRules(rbd *mgo.Client, driver plugin.ResourceDriver, sg *navite.SecurityGroup, desired []*navite.SecurityGroupRule, dryRun bool) (plan *navite.SecurityGroupRulePlan, err error) {
+	for _, rule := range desired {
+		rule.CloudName = sg.CloudName
+		rule.AccountID = sg.AccountID
+		rule.RegionID = sg.RegionID
+		rule.GroupID = sg.GroupID
+	}
+	current := driver.GetSecurityGroupRuleList(sg.GroupID)
+	if err = driver.SyncError(); err != nil {
+		log.Errorf("get securityGroup [%s] rules failed, abort reconcile: %v", sg.GroupID, err)
+		return nil, err
+	}
+	plan = &navite.SecurityGroupRulePlan{
+		GroupID: sg.GroupID,
+		DryRun:  dryRun,
+	}
+	InheritRulePriority(current, desired)
+	plan.ToAdd, plan.ToRemove, plan.Unchanged = navite.DiffSecurityGroupRules(current, desired, plugin.SecurityGroupRulePriorityKey)
+	if dryRun || (len(plan.ToAdd) == 0 && len(plan.ToRemove) == 0) {
+		return plan, nil
+	}
+
+	if len(plan.ToAdd) > 0 {
+		if err = driver.NewSecurityGroupRules(plan.ToAdd...); err != nil {
+			return plan, err
+		}
+	}
+	if len(plan.ToRemove) > 0 {
+		if err = driver.DeleteSecurityGroupRules(plan.ToRemove...); err != nil {
+			return plan, err
+		}
+	}
+	rules := driver.GetSecurityGroupRuleList(sg.GroupID)
+	if err = driver.SyncError(); err != nil {
+		log.Errorf("get securityGroup [%s] rules failed, keep stored rules: %v", sg.GroupID, err)
+		return plan, err
+	}
+	SaveSecurityGroupRules(rbd, sg.GroupID, rules)
+	return plan, nil
+}
+
+// InheritRulePriority 期望的规则没有指定优先级时, 沿用云端相同规则的优先级
+func InheritRulePriority(current, desired []*navite.SecurityGroupRule) {
+	priorities := map[string]string{}
+	for _, r := range current {
+		k := plugin.SecurityGroupRuleKey(r)
+		if _, ok := priorities[k]; !ok {
+			priorities[k] = r.Priority
+		}
+	}
+	for _, r := range desired {
+		if r.Priority != "" {
+			continue
+		}
+		if p, ok := priorities[plugin.SecurityGroupRuleKey(r)]; ok {
+			r.Priority = p
+		}
+	}
+}
+
+// SaveSecurityGroupRules 用云端最新的规则替换安全组在mongo中的规则
+func SaveSecurityGroupRules(rbd *mgo.Client, groupID string, rules []*navite.SecurityGroupRule) {
+	filter := bson.M{
+		"groupId": groupID,
+	}
+	if _, err := rbd.Table(navite.SecurityGroupRuleTable).DeleteMany(filter); err != nil {
+		log.Errorf("delete [%v] securityGroupRules failed: %v", filter, err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+	documents := make([]interface{}, 0, len(rules))
+	for _, rule := range rules {
+		documents = append(documents, rule)
+	}
+	if _, err := rbd.Table(navite.SecurityGroupRuleTable).InsertMany(documents, nil); err != nil {
+		log.Errorf("insert securityGroup [%s] rules failed: %v", groupID, err)
+	}
+}
@@ -0,0 +1,82 @@
+package misc_test
+
+import (
+	"ark-common/constants"
+	"ark-common/misc"
+	"ark-common/plugin"
+	"ark-common/resource/navite"
+	"strconv"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// tencentRuleDriver 模拟腾讯云的安全组规则, 优先级为规则在方向内的序号
+type tencentRuleDriver struct {
+	plugin.ResourceDriver
+	rules   []*navite.SecurityGroupRule
+	added   int
+	removed int
+}
+
+func (d *tencentRuleDriver) SyncError() error {
+	return nil
+}
+
+func (d *tencentRuleDriver) GetSecurityGroupRuleList(groupID string) []*navite.SecurityGroupRule {
+	index := map[string]int{}
+	list := make([]*navite.SecurityGroupRule, 0, len(d.rules))
+	for _, rule := range d.rules {
+		r := *rule
+		r.Priority = strconv.Itoa(index[r.Direction])
+		index[r.Direction]++
+		list = append(list, &r)
+	}
+	return list
+}
+
+func (d *tencentRuleDriver) NewSecurityGroupRules(rules ...*navite.SecurityGroupRule) error {
+	d.added += len(rules)
+	d.rules = append(d.rules, rules...)
+	return nil
+}
+
+func (d *tencentRuleDriver) DeleteSecurityGroupRules(rules ...*navite.SecurityGroupRule) error {
+	d.removed += len(rules)
+	return nil
+}
+
+func newTencentRule(port string, priority string) *navite.SecurityGroupRule {
+	return &navite.SecurityGroupRule{
+		CloudName:    constants.Tencent,
+		GroupID:      "sg-ohuuioma",
+		SourceCidrIP: "10.10.1.0/24",
+		Direction:    constants.FlowIngress,
+		PortRange:    port,
+		Protocol:     "TCP",
+		Priority:     priority,
+		Action:       "ACCEPT",
+	}
+}
+
+func TestReconcileTencentSecurityGroupRules(t *testing.T) {
+	Convey("腾讯云规则调整后再次调整不做变更", t, func() {
+		sg := &navite.SecurityGroup{
+			CloudName: constants.Tencent,
+			GroupID:   "sg-ohuuioma",
+		}
+		// 云端的规则是上次按期望的规则调整后的结果, 序号与期望的优先级不同
+		driver := &tencentRuleDriver{
+			rules: []*navite.SecurityGroupRule{newTencentRule("22", ""), newTencentRule("443", "")},
+		}
+		desired := []*navite.SecurityGroupRule{newTencentRule("443", "5"), newTencentRule("22", "9")}
+
+		plan, err := misc.ReconcileSecurityGroupRules(nil, driver, sg, desired, false)
+		So(err, ShouldBeNil)
+		So(plan.ToAdd, ShouldBeEmpty)
+		So(plan.ToRemove, ShouldBeEmpty)
+		So(plan.Unchanged, ShouldEqual, 2)
+		So(driver.added, ShouldEqual, 0)
+		So(driver.removed, ShouldEqual, 0)
+	})
+}
@@ -1,5 +1,10 @@
 package param
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 // SearchRegionParam 搜索地域参数
 type SearchRegionParam struct {
 	CloudName string `form:"cloudName"`
@@ -10,7 +15,10 @@ type RunInstanceParam struct {
 	AccountID       string `json:"accountId" form:"accountId" binding:"required"`
 	RegionID        string `json:"regionId" form:"regionId" binding:"required"`
 	ZoneID          string `json:"zoneId" form:"zoneId" binding:"required"`
-	ImageID         string `json:"imageId" form:"imageId" binding:"required"`
+	ImageID         string `json:"imageId" form:"imageId"` // 为空时按OS*字段查找镜像, 需要通过misc.RunInstance创建, 驱动不会查找镜像
+	OSDistribution  string `json:"osDistribution" form:"osDistribution"`
+	OSVersion       string `json:"osVersion" form:"osVersion"`
+	OSArch          string `json:"osArch" form:"osArch"`
 	InstanceType    string `json:"instanceType" form:"instanceType" binding:"required"`
 	HostName        string `json:"hostName" form:"hostName" binding:"required"`
 	InstanceName    string `json:"instanceName" form:"instanceName" binding:"required"`
@@ -49,3 +57,45 @@ type SearchSecurityGroupParam struct {
 type SearchDiskParam struct{}
 
 type SearchKeypairParam struct{}
+
+// ResetInstancePasswordParam 重置实例登录密码参数
+type ResetInstancePasswordParam struct {
+	AccountID  string `json:"accountId" form:"accountId" binding:"required"`
+	RegionID   string `json:"regionId" form:"regionId" binding:"required"`
+	InstanceID string `json:"instanceId" form:"instanceId" binding:"required"`
+	Password   string `json:"password" form:"password" binding:"required"`
+}
+
+// String 隐藏密码, 防止打印日志时泄露
+func (p ResetInstancePasswordParam) String() string {
+	return fmt.Sprintf("{AccountID:%s RegionID:%s InstanceID:%s Password:******}", p.AccountID, p.RegionID, p.InstanceID)
+}
+
+// MarshalJSON 序列化时隐藏密码, 防止参数被转成json记录到日志或作业中
+//
+// * 只影响序列化, 解析请求参数时仍然读取password字段
+func (p ResetInstancePasswordParam) MarshalJSON() ([]byte, error) {
+	type masked ResetInstancePasswordParam
+	m := masked(p)
+	m.Password = "******"
+	return json.Marshal(m)
+}
+
+// InstanceKeypairParam 绑定/解绑实例密钥对参数
+type InstanceKeypairParam struct {
+	AccountID  string `json:"accountId" form:"accountId" binding:"required"`
+	RegionID   string `json:"regionId" form:"regionId" binding:"required"`
+	InstanceID string `json:"instanceId" form:"instanceId" binding:"required"`
+	KeyPairID  string `json:"keyPairId" form:"keyPairId" binding:"required"`
+}
+
+// MatchInstanceSpecParam 跨云商匹配实例规格参数
+type MatchInstanceSpecParam struct {
+	RegionIDs []string `json:"regionIds" form:"regionIds" binding:"required"` // 不同云商的地域ID不同, 可以同时指定多个
+	CloudName string   `json:"cloudName" form:"cloudName"`
+	CPU       int      `json:"cpu" form:"cpu" binding:"required"`
+	Memory    float64  `json:"memory" form:"memory" binding:"required"` // GB
+	GPU       int      `json:"gpu" form:"gpu"`                          // 为0时不返回GPU规格
+	Families  []string `json:"families" form:"families"`                // 规格族前缀, 如 ecs.g6、S5
+	Limit     int      `json:"limit,default=20" form:"limit,default=20"`
+}
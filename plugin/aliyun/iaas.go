@@ -6,6 +6,9 @@ import (
 	"ark-common/resource/navite"
 	"ark-common/utils/tool"
 	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -16,10 +19,19 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	instanceRunning = "Running"
+	instanceStopped = "Stopped"
+)
+
+// VncViewerURL 管理终端页面的地址, 设置了环境变量 ARK_ALIYUN_VNC_VIEWER 时使用环境变量
+var VncViewerURL = "https://g.alicdn.com/aliyun/ecs-console-vnc2/0.0.8/index.html"
+
 // AliyunResource 阿里云驱动
 type AliyunResource struct {
 	client  *ecs.Client
 	account *navite.CloudAccount
+	syncErr error // 最近一次同步接口的错误
 }
 
 var rateLimit = map[string]int{
@@ -35,6 +47,7 @@ var rateLimit = map[string]int{
 	constants.HandleSyncVPC:               100,
 	constants.HandleSyncSubnet:            100,
 	constants.HandleSyncEip:               100,
+	constants.HandleSyncQuota:             100,
 }
 
 // RateLimit 获取对应账号执行action的每秒并发数
@@ -67,6 +80,11 @@ func (ali *AliyunResource) GetCloudName() string {
 	return constants.Aliyun
 }
 
+// SyncError 返回最近一次同步接口的错误, 用于区分调用失败和云端没有资源
+func (ali *AliyunResource) SyncError() error {
+	return ali.syncErr
+}
+
 // SyncJobs 返回自动同步的作业
 func (ali *AliyunResource) SyncJobs() []string {
 	return []string{
@@ -81,15 +99,18 @@ func (ali *AliyunResource) SyncJobs() []string {
 		constants.HandleSyncVPC,
 		constants.HandleSyncSubnet,
 		constants.HandleSyncEip,
+		constants.HandleSyncQuota,
 	}
 }
 
 // GetRegionList 获取地域列表
 func (ali *AliyunResource) GetRegionList() (regionList []*navite.CloudRegion) {
+	ali.syncErr = nil
 	req := ecs.CreateDescribeRegionsRequest()
 	resp, err := ali.client.DescribeRegions(req)
 	if err != nil {
 		log.Errorf("aliyun describe regions failed: %v", err)
+		ali.syncErr = err
 		return
 	}
 	for _, res := range resp.Regions.Region {
@@ -106,10 +127,12 @@ func (ali *AliyunResource) GetRegionList() (regionList []*navite.CloudRegion) {
 
 // GetZoneList 获取可用区列表
 func (ali *AliyunResource) GetZoneList() (zoneList []*navite.CloudZone) {
+	ali.syncErr = nil
 	req := ecs.CreateDescribeZonesRequest()
 	resp, err := ali.client.DescribeZones(req)
 	if err != nil {
 		log.Errorf("aliyun describe zones failed: %v", err)
+		ali.syncErr = err
 		return
 	}
 	for _, res := range resp.Zones.Zone {
@@ -127,12 +150,14 @@ func (ali *AliyunResource) GetZoneList() (zoneList []*navite.CloudZone) {
 
 // GetImageList 获取镜像列表
 func (ali *AliyunResource) GetImageList(pageSize, currentPage int) (count int, imgs []*navite.Image) {
+	ali.syncErr = nil
 	req := ecs.CreateDescribeImagesRequest()
 	req.PageSize = requests.NewInteger(pageSize)
 	req.PageNumber = requests.NewInteger(currentPage)
 	resp, err := ali.client.DescribeImages(req)
 	if err != nil {
 		log.Errorf("aliyun describe images failed: %v", err)
+		ali.syncErr = err
 		return
 	}
 	for _, res := range resp.Images.Image {
@@ -157,12 +182,14 @@ func (ali *AliyunResource) GetImageList(pageSize, currentPage int) (count int, i
 
 // GetInstanceList 获取实例列表
 func (ali *AliyunResource) GetInstanceList(pageSize, currentPage int) (count int, instanceList []*navite.Instance) {
+	ali.syncErr = nil
 	req := ecs.CreateDescribeInstancesRequest()
 	req.PageSize = requests.NewInteger(pageSize)
 	req.PageNumber = requests.NewInteger(currentPage)
 	resp, err := ali.client.DescribeInstances(req)
 	if err != nil {
 		log.Errorf("aliyun describe instance failed: %v", err)
+		ali.syncErr = err
 		return
 	}
 	for _, res := range resp.Instances.Instance {
@@ -191,6 +218,7 @@ func (ali *AliyunResource) GetInstanceList(pageSize, currentPage int) (count int
 			CreatedTime:       tool.TimeForISO8601(res.CreationTime),
 			SyncedTime:        time.Now(),
 		}
+		unifyInstance(instance, res.SpotStrategy)
 		instanceList = append(instanceList, instance)
 	}
 	return resp.TotalCount, instanceList
@@ -198,12 +226,14 @@ func (ali *AliyunResource) GetInstanceList(pageSize, currentPage int) (count int
 
 // GetSecurityGroupList 获取安全组列表
 func (ali *AliyunResource) GetSecurityGroupList(pageSize, currentPage int) (count int, sgList []*navite.SecurityGroup) {
+	ali.syncErr = nil
 	req := ecs.CreateDescribeSecurityGroupsRequest()
 	req.PageSize = requests.NewInteger(pageSize)
 	req.PageNumber = requests.NewInteger(currentPage)
 	resp, err := ali.client.DescribeSecurityGroups(req)
 	if err != nil {
 		log.Errorf("aliyun describe securityGroup failed: %v", err)
+		ali.syncErr = err
 		return
 	}
 	for _, res := range resp.SecurityGroups.SecurityGroup {
@@ -225,12 +255,14 @@ func (ali *AliyunResource) GetSecurityGroupList(pageSize, currentPage int) (coun
 
 // GetDiskList 获取磁盘列表
 func (ali *AliyunResource) GetDiskList(pageSize, currentPage int) (count int, diskList []*navite.Disk) {
+	ali.syncErr = nil
 	req := ecs.CreateDescribeDisksRequest()
 	req.PageSize = requests.NewInteger(pageSize)
 	req.PageNumber = requests.NewInteger(currentPage)
 	resp, err := ali.client.DescribeDisks(req)
 	if err != nil {
 		log.Errorf("aliyun describe disks failed: %v", err)
+		ali.syncErr = err
 		return
 	}
 	for _, res := range resp.Disks.Disk {
@@ -254,6 +286,7 @@ func (ali *AliyunResource) GetDiskList(pageSize, currentPage int) (count int, di
 			CreatedTime:      tool.TimeForISO8601(res.CreationTime),
 			SyncedTime:       time.Now(),
 		}
+		UnifyDisk(disk)
 		diskList = append(diskList, disk)
 	}
 	return int(resp.TotalCount), diskList
@@ -261,12 +294,14 @@ func (ali *AliyunResource) GetDiskList(pageSize, currentPage int) (count int, di
 
 // GetKeypairList 获取密钥对
 func (ali *AliyunResource) GetKeypairList(pageSize, currentPage int) (count int, keypairList []*navite.Keypair) {
+	ali.syncErr = nil
 	req := ecs.CreateDescribeKeyPairsRequest()
 	req.PageSize = requests.NewInteger(pageSize)
 	req.PageNumber = requests.NewInteger(currentPage)
 	resp, err := ali.client.DescribeKeyPairs(req)
 	if err != nil {
 		log.Errorf("aliyun describe keypairs failed: %v", err)
+		ali.syncErr = err
 		return
 	}
 	for _, res := range resp.KeyPairs.KeyPair {
@@ -278,6 +313,8 @@ func (ali *AliyunResource) GetKeypairList(pageSize, currentPage int) (count int,
 			KeypairName: res.KeyPairName,
 			SyncedTime:  time.Now(),
 		}
+		// 阿里云不返回公钥, 只返回MD5指纹
+		keypair.FingerprintMD5 = tool.NormalizeMD5Fingerprint(res.KeyPairFingerPrint)
 		keypairList = append(keypairList, keypair)
 	}
 	return int(resp.TotalCount), keypairList
@@ -285,16 +322,20 @@ func (ali *AliyunResource) GetKeypairList(pageSize, currentPage int) (count int,
 
 // GetSecurityGroupRuleList 获取安全组规则
 func (ali *AliyunResource) GetSecurityGroupRuleList(securityGroupID string) (sgrList []*navite.SecurityGroupRule) {
+	ali.syncErr = nil
 	req := ecs.CreateDescribeSecurityGroupAttributeRequest()
 	req.SecurityGroupId = securityGroupID
 	resp, err := ali.client.DescribeSecurityGroupAttribute(req)
 	if err != nil {
 		log.Errorf("aliyun describe securityGroupRules failed: %v", err)
+		ali.syncErr = err
 		return
 	}
 	for _, res := range resp.Permissions.Permission {
 		sgr := &navite.SecurityGroupRule{
 			CloudName:    constants.Aliyun,
+			AccountID:    ali.account.AccountID(),
+			RegionID:     ali.account.RunRegionID,
 			GroupID:      securityGroupID,
 			GroupName:    res.DestGroupName,
 			DestCidrIP:   res.DestCidrIp,
@@ -314,10 +355,12 @@ func (ali *AliyunResource) GetSecurityGroupRuleList(securityGroupID string) (sgr
 
 // GetInstanceSpecsList 获取实例规格
 func (ali *AliyunResource) GetInstanceSpecsList() (instantSpecList []*navite.InstanceSpec) {
+	ali.syncErr = nil
 	req := ecs.CreateDescribeInstanceTypesRequest()
 	resp, err := ali.client.DescribeInstanceTypes(req)
 	if err != nil {
 		log.Errorf("aliyun describe instanceTypes failed: %v", err)
+		ali.syncErr = err
 		return
 	}
 	for _, res := range resp.InstanceTypes.InstanceType {
@@ -330,6 +373,8 @@ func (ali *AliyunResource) GetInstanceSpecsList() (instantSpecList []*navite.Ins
 			InstanceFamily:   res.InstanceTypeFamily,
 			CPU:              res.CpuCoreCount,
 			Memory:           res.MemorySize,
+			GPU:              res.GPUAmount,
+			GPUSpec:          res.GPUSpec,
 			SyncedTime:       time.Now(),
 		}
 		instantSpecList = append(instantSpecList, spec)
@@ -339,12 +384,14 @@ func (ali *AliyunResource) GetInstanceSpecsList() (instantSpecList []*navite.Ins
 
 // GetVPCList 获取VPC列表
 func (ali *AliyunResource) GetVPCList(pageSize, currentPage int) (count int, vpcList []*navite.VPC) {
+	ali.syncErr = nil
 	req := ecs.CreateDescribeVpcsRequest()
 	req.PageSize = requests.NewInteger(pageSize)
 	req.PageNumber = requests.NewInteger(currentPage)
 	resp, err := ali.client.DescribeVpcs(req)
 	if err != nil {
 		log.Errorf("aliyun describe vpcs failed: %v", err)
+		ali.syncErr = err
 		return
 	}
 	for _, res := range resp.Vpcs.Vpc {
@@ -369,12 +416,14 @@ func (ali *AliyunResource) GetVPCList(pageSize, currentPage int) (count int, vpc
 
 // GetSubnetList 获取子网列表
 func (ali *AliyunResource) GetSubnetList(pageSize, currentPage int) (count int, subnetList []*navite.Subnet) {
+	ali.syncErr = nil
 	req := ecs.CreateDescribeVSwitchesRequest()
 	req.PageSize = requests.NewInteger(pageSize)
 	req.PageNumber = requests.NewInteger(currentPage)
 	resp, err := ali.client.DescribeVSwitches(req)
 	if err != nil {
 		log.Errorf("aliyun describe vswitch failed: %v", err)
+		ali.syncErr = err
 		return
 	}
 	for _, res := range resp.VSwitches.VSwitch {
@@ -400,12 +449,14 @@ func (ali *AliyunResource) GetSubnetList(pageSize, currentPage int) (count int,
 
 // GetEipList 获取弹性公网IP列表
 func (ali *AliyunResource) GetEipList(pageSize, currentPage int) (count int, eipList []*navite.Eip) {
+	ali.syncErr = nil
 	req := ecs.CreateDescribeEipAddressesRequest()
 	req.PageSize = requests.NewInteger(pageSize)
 	req.PageNumber = requests.NewInteger(currentPage)
 	resp, err := ali.client.DescribeEipAddresses(req)
 	if err != nil {
 		log.Errorf("aliyun describe eip failed: %v", err)
+		ali.syncErr = err
 		return
 	}
 	for _, res := range resp.EipAddresses.EipAddress {
@@ -426,6 +477,7 @@ func (ali *AliyunResource) GetEipList(pageSize, currentPage int) (count int, eip
 			CreatedTime:      tool.TimeForISO8601(res.AllocationTime),
 			SyncedTime:       time.Now(),
 		}
+		unifyEip(eip)
 		eipList = append(eipList, eip)
 	}
 	return resp.TotalCount, eipList
@@ -442,7 +494,11 @@ func (ali *AliyunResource) NewKeypair(keypair *navite.Keypair) (err error) {
 		return err
 	}
 	keypair.KeypairID = resp.KeyPairName
-	return
+	if err = keypair.SetFingerprint(); err != nil {
+		log.Warnf("parse keypair [%s] public key failed: %v", keypair.KeypairName, err)
+		keypair.FingerprintMD5 = tool.NormalizeMD5Fingerprint(resp.KeyPairFingerPrint)
+	}
+	return nil
 }
 
 // DeleteKeypair 删除密钥对
@@ -517,6 +573,8 @@ func (ali *AliyunResource) NewSecurityGroupRule(rule *navite.SecurityGroupRule)
 }
 
 // DeleteSecurityGroupRule 删除安全组规则
+//
+// * 带上优先级匹配, 只修改了优先级时先新增的规则不会被一起删除
 func (ali *AliyunResource) DeleteSecurityGroupRule(rule *navite.SecurityGroupRule) (err error) {
 	switch rule.Direction {
 	case constants.FlowIngress:
@@ -525,6 +583,7 @@ func (ali *AliyunResource) DeleteSecurityGroupRule(rule *navite.SecurityGroupRul
 		req.PortRange = rule.PortRange
 		req.NicType = "internet"
 		req.Policy = rule.Action
+		req.Priority = rule.Priority
 		req.SourceCidrIp = rule.SourceCidrIP
 		req.SecurityGroupId = rule.GroupID
 		if _, err = ali.client.RevokeSecurityGroup(req); err != nil {
@@ -537,6 +596,7 @@ func (ali *AliyunResource) DeleteSecurityGroupRule(rule *navite.SecurityGroupRul
 		req.PortRange = rule.PortRange
 		req.NicType = "internet"
 		req.Policy = rule.Action
+		req.Priority = rule.Priority
 		req.DestCidrIp = rule.DestCidrIP
 		req.SecurityGroupId = rule.GroupID
 		if _, err = ali.client.RevokeSecurityGroupEgress(req); err != nil {
@@ -814,3 +874,225 @@ func (ali *AliyunResource) ModifyEIPBandWidth(eip *navite.Eip, bandWidth int64)
 	eip.BandWidth = bandWidth
 	return
 }
+
+// waitInstanceStatus 等待实例变为指定的状态
+func (ali *AliyunResource) waitInstanceStatus(instanceID, status string, timeout time.Duration) (err error) {
+	req := ecs.CreateDescribeInstancesRequest()
+	b, _ := json.Marshal([]string{instanceID})
+	req.InstanceIds = string(b)
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		resp, err := ali.client.DescribeInstances(req)
+		if err != nil {
+			log.Errorf("aliyun describe instance [%s] failed: %v", req.GetQueryParams(), err)
+			return err
+		}
+		if len(resp.Instances.Instance) == 0 {
+			return fmt.Errorf("aliyun instance %s not found", instanceID)
+		}
+		if resp.Instances.Instance[0].Status == status {
+			return nil
+		}
+		time.Sleep(5 * time.Second)
+	}
+	return fmt.Errorf("wait aliyun instance %s to %s timeout", instanceID, status)
+}
+
+// ModifyInstanceSpec 调整实例规格
+//
+// * 按量付费的实例需要先停机, 调整完成后如果原来是运行中会重新启动
+// * 包年包月的实例调整完成后自动重启
+func (ali *AliyunResource) ModifyInstanceSpec(instance *navite.Instance, instanceType string) (err error) {
+	if instance.ChargeType == prePaid {
+		req := ecs.CreateModifyPrepayInstanceSpecRequest()
+		req.InstanceId = instance.InstanceID
+		req.InstanceType = instanceType
+		req.AutoPay = requests.NewBoolean(true)
+		req.RebootWhenFinished = requests.NewBoolean(true)
+		if _, err = ali.client.ModifyPrepayInstanceSpec(req); err != nil {
+			log.Errorf("aliyun modifyPrepayInstanceSpec [%s] failed: %v", req.GetQueryParams(), err)
+			return err
+		}
+		instance.InstanceType = instanceType
+		return nil
+	}
+
+	running := instance.Status == instanceRunning
+	if running {
+		if err = ali.StopInstance(instance.InstanceID); err != nil {
+			return err
+		}
+		if err = ali.waitInstanceStatus(instance.InstanceID, instanceStopped, 5*time.Minute); err != nil {
+			return err
+		}
+	}
+	req := ecs.CreateModifyInstanceSpecRequest()
+	req.InstanceId = instance.InstanceID
+	req.InstanceType = instanceType
+	if _, err = ali.client.ModifyInstanceSpec(req); err != nil {
+		log.Errorf("aliyun modifyInstanceSpec [%s] failed: %v", req.GetQueryParams(), err)
+		return err
+	}
+	instance.InstanceType = instanceType
+	instance.Status = instanceStopped
+	if running {
+		if err = ali.StartInstance(instance.InstanceID); err != nil {
+			return err
+		}
+		instance.Status = instanceRunning
+	}
+	return nil
+}
+
+// ResizeDisk 扩容云盘
+//
+// * online为false时需要重启实例后才能生效
+func (ali *AliyunResource) ResizeDisk(disk *navite.Disk, size int, online bool) (err error) {
+	req := ecs.CreateResizeDiskRequest()
+	req.DiskId = disk.DiskID
+	req.NewSize = requests.NewInteger(size)
+	req.Type = "offline"
+	if online {
+		req.Type = "online"
+	}
+	if _, err = ali.client.ResizeDisk(req); err != nil {
+		log.Errorf("aliyun resizeDisk [%s] failed: %v", req.GetQueryParams(), err)
+		return err
+	}
+	disk.DiskSize = size
+	return nil
+}
+
+// ModifyInstanceAttribute 修改实例的名称、描述和主机名, 参数为空代表不修改
+//
+// * 修改主机名后需要重启实例才能生效
+func (ali *AliyunResource) ModifyInstanceAttribute(instance *navite.Instance, instanceName, description, hostName string) (err error) {
+	req := ecs.CreateModifyInstanceAttributeRequest()
+	req.InstanceId = instance.InstanceID
+	req.InstanceName = instanceName
+	req.Description = description
+	req.HostName = hostName
+	if _, err = ali.client.ModifyInstanceAttribute(req); err != nil {
+		log.Errorf("aliyun modifyInstanceAttribute [%s] failed: %v", req.GetQueryParams(), err)
+		return err
+	}
+	if instanceName != "" {
+		instance.InstanceName = instanceName
+	}
+	if description != "" {
+		instance.Description = description
+	}
+	if hostName != "" {
+		instance.HostName = hostName
+	}
+	return nil
+}
+
+// ResetInstancePassword 重置实例的登录密码
+//
+// * 重启实例后生效
+// * 请求参数中包含密码, 失败时不能打印请求参数
+func (ali *AliyunResource) ResetInstancePassword(instance *navite.Instance, password string) (err error) {
+	req := ecs.CreateModifyInstanceAttributeRequest()
+	req.InstanceId = instance.InstanceID
+	req.Password = password
+	if _, err = ali.client.ModifyInstanceAttribute(req); err != nil {
+		log.Errorf("aliyun reset instance [%s] password failed: %v", instance.InstanceID, err)
+	}
+	return err
+}
+
+// AttachKeypair 为实例绑定密钥对
+//
+// * 阿里云实例只能绑定一个密钥对, 重启实例后生效
+func (ali *AliyunResource) AttachKeypair(instance *navite.Instance, keypairID string) (err error) {
+	req := ecs.CreateAttachKeyPairRequest()
+	b, _ := json.Marshal([]string{instance.InstanceID})
+	req.InstanceIds = string(b)
+	req.KeyPairName = keypairID
+	if _, err = ali.client.AttachKeyPair(req); err != nil {
+		log.Errorf("aliyun attachKeyPair [%s] failed: %v", req.GetQueryParams(), err)
+		return err
+	}
+	instance.KeyPairList = []string{keypairID}
+	return nil
+}
+
+// DetachKeypair 解绑实例的密钥对
+func (ali *AliyunResource) DetachKeypair(instance *navite.Instance, keypairID string) (err error) {
+	req := ecs.CreateDetachKeyPairRequest()
+	b, _ := json.Marshal([]string{instance.InstanceID})
+	req.InstanceIds = string(b)
+	req.KeyPairName = keypairID
+	if _, err = ali.client.DetachKeyPair(req); err != nil {
+		log.Errorf("aliyun detachKeyPair [%s] failed: %v", req.GetQueryParams(), err)
+		return err
+	}
+	instance.KeyPairList = []string{}
+	return nil
+}
+
+// ResetVncPassword 修改登录管理终端的VNC密码
+//
+// * 阿里云登录管理终端需要VNC密码, 密码为6位字母和数字
+// * 非I/O优化实例修改VNC密码后需要重启实例才能生效
+// * 请求参数中包含密码, 失败时不能打印请求参数
+func (ali *AliyunResource) ResetVncPassword(instance *navite.Instance, password string) (err error) {
+	req := ecs.CreateModifyInstanceVncPasswdRequest()
+	req.InstanceId = instance.InstanceID
+	req.VncPassword = password
+	if _, err = ali.client.ModifyInstanceVncPasswd(req); err != nil {
+		log.Errorf("aliyun modifyInstanceVncPasswd [%s] failed: %v", instance.InstanceID, err)
+	}
+	return err
+}
+
+// GetVncConsole 获取实例的管理终端地址
+//
+// * 地址15秒内有效, 且只能使用一次
+// * 登录时使用ResetVncPassword设置的VNC密码, 获取地址不会修改密码
+func (ali *AliyunResource) GetVncConsole(instance *navite.Instance) (console *navite.VncConsole, err error) {
+	req := ecs.CreateDescribeInstanceVncUrlRequest()
+	req.InstanceId = instance.InstanceID
+	resp, err := ali.client.DescribeInstanceVncUrl(req)
+	if err != nil {
+		log.Errorf("aliyun describeInstanceVncUrl [%s] failed: %v", req.GetQueryParams(), err)
+		return nil, err
+	}
+	viewer := VncViewerURL
+	if env := os.Getenv("ARK_ALIYUN_VNC_VIEWER"); env != "" {
+		viewer = env
+	}
+	isWindows := strconv.FormatBool(strings.Contains(strings.ToLower(instance.OSName), "windows"))
+	console = &navite.VncConsole{
+		InstanceID: instance.InstanceID,
+		URL: viewer + "?vncUrl=" + url.QueryEscape(resp.VncUrl) +
+			"&instanceId=" + instance.InstanceID + "&isWindows=" + isWindows,
+		ExpiredTime: time.Now().Add(15 * time.Second),
+	}
+	return console, nil
+}
+
+// NewSecurityGroupRules 批量创建安全组规则
+//
+// * 阿里云不支持批量创建, 这里逐条创建, 遇到错误立即返回
+func (ali *AliyunResource) NewSecurityGroupRules(rules ...*navite.SecurityGroupRule) (err error) {
+	for _, rule := range rules {
+		if err = ali.NewSecurityGroupRule(rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteSecurityGroupRules 批量删除安全组规则
+//
+// * 阿里云不支持批量删除, 这里逐条删除, 遇到错误立即返回
+func (ali *AliyunResource) DeleteSecurityGroupRules(rules ...*navite.SecurityGroupRule) (err error) {
+	for _, rule := range rules {
+		if err = ali.DeleteSecurityGroupRule(rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package aliyun
+
+import (
+	"ark-common/constants"
+	"ark-common/resource/navite"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/cms"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const metricNamespace = "acs_ecs_dashboard"
+
+// aliyunMetric 云监控指标名, scale用于将数值换算为统一的单位
+type aliyunMetric struct {
+	name  string
+	scale float64
+}
+
+var metricMapping = map[string]aliyunMetric{
+	constants.MetricCPUUsage:    {"CPUUtilization", 1},
+	constants.MetricMemoryUsage: {"memory_usedutilization", 1}, // 需要安装云监控插件
+	constants.MetricDiskRead:    {"DiskReadBPS", 1},
+	constants.MetricDiskWrite:   {"DiskWriteBPS", 1},
+	constants.MetricNetIn:       {"InternetInRate", 1.0 / 8}, // bit/s
+	constants.MetricNetOut:      {"InternetOutRate", 1.0 / 8},
+}
+
+// AliyunMonitor 阿里云云监控驱动
+type AliyunMonitor struct {
+	client  *cms.Client
+	account *navite.CloudAccount
+}
+
+// NewAliyunMonitorPlugin 初始化阿里云云监控驱动
+func NewAliyunMonitorPlugin(ac *navite.CloudAccount) *AliyunMonitor {
+	client, err := cms.NewClientWithAccessKey(ac.RunRegionID, ac.AccessKey, ac.GetSK())
+	if err != nil {
+		log.Errorf("initialize cms client failed: %v", err)
+	}
+	return &AliyunMonitor{
+		client:  client,
+		account: ac,
+	}
+}
+
+type datapoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Average   float64 `json:"Average"`
+	Maximum   float64 `json:"Maximum"`
+	Minimum   float64 `json:"Minimum"`
+}
+
+// GetInstanceMetric 获取实例在时间段内的监控数据
+func (ali *AliyunMonitor) GetInstanceMetric(instance *navite.Instance, metricName string, startTime, endTime time.Time) (points []*navite.MetricPoint, err error) {
+	metric, ok := metricMapping[metricName]
+	if !ok {
+		return nil, fmt.Errorf("aliyun not support metric %s", metricName)
+	}
+	dimensions, _ := json.Marshal([]map[string]string{
+		{"instanceId": instance.InstanceID},
+	})
+	req := cms.CreateDescribeMetricListRequest()
+	req.Namespace = metricNamespace
+	req.MetricName = metric.name
+	req.Dimensions = string(dimensions)
+	req.Period = "60"
+	req.StartTime = strconv.FormatInt(startTime.UnixNano()/int64(time.Millisecond), 10)
+	req.EndTime = strconv.FormatInt(endTime.UnixNano()/int64(time.Millisecond), 10)
+	for {
+		resp, err := ali.client.DescribeMetricList(req)
+		if err != nil {
+			log.Errorf("aliyun describe metric [%s] failed: %v", req.GetQueryParams(), err)
+			return nil, err
+		}
+		if !resp.Success {
+			return nil, fmt.Errorf("aliyun describe metric failed: %s", resp.Message)
+		}
+		dps := []*datapoint{}
+		if resp.Datapoints != "" {
+			if err = json.Unmarshal([]byte(resp.Datapoints), &dps); err != nil {
+				return nil, err
+			}
+		}
+		for _, dp := range dps {
+			points = append(points, &navite.MetricPoint{
+				CloudName:  constants.Aliyun,
+				AccountID:  ali.account.AccountID(),
+				RegionID:   instance.RegionID,
+				InstanceID: instance.InstanceID,
+				MetricName: metricName,
+				Timestamp:  time.Unix(0, dp.Timestamp*int64(time.Millisecond)),
+				Average:    dp.Average * metric.scale,
+				Maximum:    dp.Maximum * metric.scale,
+				Minimum:    dp.Minimum * metric.scale,
+			})
+		}
+		if resp.NextToken == "" {
+			break
+		}
+		req.NextToken = resp.NextToken
+	}
+	return points, nil
+}
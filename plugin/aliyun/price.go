@@ -0,0 +1,101 @@
+package aliyun
+
+import (
+	"ark-common/constants"
+	"ark-common/param"
+	"ark-common/resource/navite"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	priceUnitHour  = "Hour"
+	priceUnitMonth = "Month"
+	prePaid        = "PrePaid"
+)
+
+// InquiryInstancePrice 创建实例询价
+//
+// * RunInstance 默认为按量付费, 这里返回的是按小时计价的价格
+func (ali *AliyunResource) InquiryInstancePrice(instance *param.RunInstanceParam) (price *navite.Price, err error) {
+	req := ecs.CreateDescribePriceRequest()
+	req.ResourceType = "instance"
+	req.ZoneId = instance.ZoneID
+	req.ImageId = instance.ImageID
+	req.InstanceType = instance.InstanceType
+	if instance.DiskSize != 0 {
+		req.DataDisk1Category = instance.DiskType
+		req.DataDisk1Size = requests.NewInteger(instance.DiskSize)
+	}
+	// Amount为0时阿里云返回参数错误, 未指定数量时按1台询价
+	amount := instance.Numbers
+	if amount <= 0 {
+		amount = 1
+	}
+	req.Amount = requests.NewInteger(amount)
+	req.PriceUnit = priceUnitHour
+	resp, err := ali.client.DescribePrice(req)
+	if err != nil {
+		log.Errorf("aliyun inquiry instance price [%s] failed: %v", req.GetQueryParams(), err)
+		return nil, err
+	}
+	return newPrice(constants.PriceResourceInstance, req.PriceUnit, resp), nil
+}
+
+// InquiryDiskPrice 创建云盘询价
+func (ali *AliyunResource) InquiryDiskPrice(disk *navite.Disk) (price *navite.Price, err error) {
+	req := ecs.CreateDescribePriceRequest()
+	req.ResourceType = "disk"
+	req.ZoneId = disk.ZoneID
+	req.DataDisk1Category = disk.DiskType
+	req.DataDisk1Size = requests.NewInteger(disk.DiskSize)
+	req.PriceUnit = priceUnitHour
+	if disk.ChargeType == prePaid {
+		req.PriceUnit = priceUnitMonth
+		req.Period = requests.NewInteger(1)
+	}
+	resp, err := ali.client.DescribePrice(req)
+	if err != nil {
+		log.Errorf("aliyun inquiry disk price [%s] failed: %v", req.GetQueryParams(), err)
+		return nil, err
+	}
+	return newPrice(constants.PriceResourceDisk, req.PriceUnit, resp), nil
+}
+
+// InquiryEIPPrice 申请弹性公网IP询价
+//
+// * 按公网带宽计价, 不包含按流量计费时的流量费用
+func (ali *AliyunResource) InquiryEIPPrice(eip *navite.Eip) (price *navite.Price, err error) {
+	req := ecs.CreateDescribePriceRequest()
+	req.ResourceType = "bandwidth"
+	req.InternetChargeType = eip.BandWidthChargeType
+	req.InternetMaxBandwidthOut = requests.NewInteger(int(eip.BandWidth))
+	req.PriceUnit = priceUnitHour
+	resp, err := ali.client.DescribePrice(req)
+	if err != nil {
+		log.Errorf("aliyun inquiry eip price [%s] failed: %v", req.GetQueryParams(), err)
+		return nil, err
+	}
+	return newPrice(constants.PriceResourceEip, req.PriceUnit, resp), nil
+}
+
+func newPrice(resourceType, priceUnit string, resp *ecs.DescribePriceResponse) *navite.Price {
+	price := &navite.Price{
+		CloudName:     constants.Aliyun,
+		ResourceType:  resourceType,
+		Currency:      resp.PriceInfo.Price.Currency,
+		PriceUnit:     constants.PriceUnitHour,
+		OriginalPrice: resp.PriceInfo.Price.OriginalPrice,
+		DiscountPrice: resp.PriceInfo.Price.TradePrice,
+	}
+	if priceUnit == priceUnitMonth {
+		price.PriceUnit = constants.PriceUnitMonth
+	}
+	if price.Currency == "" {
+		price.Currency = constants.CurrencyCNY
+	}
+	return price
+}
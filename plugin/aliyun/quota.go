@@ -0,0 +1,109 @@
+package aliyun
+
+import (
+	"ark-common/constants"
+	"ark-common/resource/navite"
+	"strconv"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// quotaAttribute 账号属性和统一配额名的对应关系
+type quotaAttribute struct {
+	quotaName string
+	used      bool // true代表已使用量, false代表上限
+}
+
+var quotaAttributes = map[string]quotaAttribute{
+	"max-postpaid-instance-vcpu-count":  {constants.QuotaInstanceVCPU, false},
+	"used-postpaid-instance-vcpu-count": {constants.QuotaInstanceVCPU, true},
+	"max-postpaid-yundisk-capacity":     {constants.QuotaDiskCapacity, false},
+	"used-postpaid-yundisk-capacity":    {constants.QuotaDiskCapacity, true},
+	"max-security-groups":               {constants.QuotaSecurityGroup, false},
+}
+
+// GetQuotaList 获取账号在当前地域的配额
+//
+// * 弹性公网IP的配额不在ECS的账号属性中, 这里不返回
+func (ali *AliyunResource) GetQuotaList() (quotaList []*navite.Quota) {
+	ali.syncErr = nil
+	req := ecs.CreateDescribeAccountAttributesRequest()
+	attributeNames := []string{}
+	for name := range quotaAttributes {
+		attributeNames = append(attributeNames, name)
+	}
+	req.AttributeName = &attributeNames
+	resp, err := ali.client.DescribeAccountAttributes(req)
+	if err != nil {
+		log.Errorf("aliyun describe account attributes failed: %v", err)
+		ali.syncErr = err
+		return
+	}
+	values := []attributeValue{}
+	for _, res := range resp.AccountAttributeItems.AccountAttributeItem {
+		for _, item := range res.AttributeValues.ValueItem {
+			value, err := strconv.ParseInt(item.Value, 10, 64)
+			if err != nil {
+				continue
+			}
+			values = append(values, attributeValue{name: res.AttributeName, zoneID: item.ZoneId, value: value})
+		}
+	}
+	base := navite.Quota{
+		CloudName:  constants.Aliyun,
+		AccountID:  ali.account.AccountID(),
+		RegionID:   ali.account.RunRegionID,
+		SyncedTime: time.Now(),
+	}
+	return mergeQuotas(base, values)
+}
+
+// attributeValue 账号属性在某个可用区下的取值
+type attributeValue struct {
+	name   string
+	zoneID string
+	value  int64
+}
+
+// mergeQuotas 把上限和已使用量两个账号属性合并成配额
+//
+// * 只返回了已使用量、没有上限的配额直接丢弃, 否则Limit为0会被当成配额已用完
+func mergeQuotas(base navite.Quota, values []attributeValue) (quotaList []*navite.Quota) {
+	// key Example: instanceVCPU-cn-beijing-g
+	quotas := map[string]*navite.Quota{}
+	keys := []string{}
+	for _, v := range values {
+		attr, ok := quotaAttributes[v.name]
+		if !ok {
+			continue
+		}
+		key := attr.quotaName + "-" + v.zoneID
+		quota, ok := quotas[key]
+		if !ok {
+			q := base
+			quota = &q
+			quota.ZoneID = v.zoneID
+			quota.QuotaName = attr.quotaName
+			quotas[key] = quota
+			keys = append(keys, key)
+		}
+		if attr.used {
+			quota.Used = v.value
+		} else {
+			quota.Limit = v.value
+			quota.RawName = v.name
+		}
+	}
+	for _, key := range keys {
+		quota := quotas[key]
+		if quota.RawName == "" {
+			log.Warnf("aliyun quota [%s] has no limit attribute, skip it", key)
+			continue
+		}
+		quotaList = append(quotaList, quota)
+	}
+	return quotaList
+}
@@ -0,0 +1,41 @@
+package aliyun
+
+import (
+	"ark-common/constants"
+	"ark-common/resource/navite"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMergeQuotas(t *testing.T) {
+	base := navite.Quota{CloudName: constants.Aliyun, AccountID: "test", RegionID: "cn-beijing"}
+	Convey("合并阿里云账号属性为配额", t, func() {
+		Convey("上限和已使用量合并到同一个可用区的配额", func() {
+			quotaList := mergeQuotas(base, []attributeValue{
+				{name: "max-postpaid-instance-vcpu-count", zoneID: "cn-beijing-g", value: 100},
+				{name: "used-postpaid-instance-vcpu-count", zoneID: "cn-beijing-g", value: 30},
+				{name: "max-postpaid-instance-vcpu-count", zoneID: "cn-beijing-h", value: 50},
+			})
+			So(len(quotaList), ShouldEqual, 2)
+			So(quotaList[0].ZoneID, ShouldEqual, "cn-beijing-g")
+			So(quotaList[0].QuotaName, ShouldEqual, constants.QuotaInstanceVCPU)
+			So(quotaList[0].Remaining(), ShouldEqual, 70)
+			So(quotaList[1].Remaining(), ShouldEqual, 50)
+			So(quotaList[1].AccountID, ShouldEqual, "test")
+		})
+		Convey("只有已使用量的配额被丢弃", func() {
+			quotaList := mergeQuotas(base, []attributeValue{
+				{name: "used-postpaid-yundisk-capacity", zoneID: "", value: 500},
+				{name: "max-security-groups", zoneID: "", value: 100},
+			})
+			So(len(quotaList), ShouldEqual, 1)
+			So(quotaList[0].QuotaName, ShouldEqual, constants.QuotaSecurityGroup)
+			So(quotaList[0].RawName, ShouldEqual, "max-security-groups")
+		})
+		Convey("未知的账号属性被忽略", func() {
+			quotaList := mergeQuotas(base, []attributeValue{{name: "instance-network-type", value: 1}})
+			So(quotaList, ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,117 @@
+package aliyun
+
+import (
+	"ark-common/constants"
+	"ark-common/resource/navite"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var protocols = map[string]navite.RuleProtocol{
+	"tcp":    navite.ProtocolTCP,
+	"udp":    navite.ProtocolUDP,
+	"icmp":   navite.ProtocolICMP,
+	"icmpv6": navite.ProtocolICMPv6,
+	"gre":    navite.ProtocolGRE,
+	"all":    navite.ProtocolAll,
+}
+
+// ToCanonicalRule 将阿里云的安全组规则转换为统一的规则
+//
+// * PortRange 为 ?/? 格式, -1/-1 代表全部端口
+// * Priority 取值 1-100, 为空时默认为1
+func ToCanonicalRule(rule *navite.SecurityGroupRule) (c *navite.CanonicalRule, err error) {
+	protocol, ok := protocols[strings.ToLower(rule.Protocol)]
+	if !ok {
+		return nil, fmt.Errorf("aliyun unknown protocol %s", rule.Protocol)
+	}
+	c = &navite.CanonicalRule{
+		Direction:   strings.ToLower(rule.Direction),
+		Protocol:    protocol,
+		Priority:    1,
+		Action:      navite.RuleAction(strings.ToUpper(rule.Action)),
+		Description: rule.Description,
+	}
+	switch c.Direction {
+	case constants.FlowIngress:
+		c.CidrIP = rule.SourceCidrIP
+	case constants.FlowEgress:
+		c.CidrIP = rule.DestCidrIP
+	default:
+		return nil, fmt.Errorf("aliyun unknown direction %s", rule.Direction)
+	}
+	if c.CidrIP == "" {
+		return nil, fmt.Errorf("aliyun rule without cidr is not supported")
+	}
+	if c.Action != navite.ActionAccept && c.Action != navite.ActionDrop {
+		return nil, fmt.Errorf("aliyun unknown policy %s", rule.Action)
+	}
+	if rule.Priority != "" {
+		if c.Priority, err = strconv.Atoi(rule.Priority); err != nil {
+			return nil, fmt.Errorf("aliyun invalid priority %s", rule.Priority)
+		}
+	}
+
+	ports := strings.Split(rule.PortRange, "/")
+	if len(ports) != 2 {
+		return nil, fmt.Errorf("aliyun invalid port range %s", rule.PortRange)
+	}
+	if c.PortFrom, err = strconv.Atoi(ports[0]); err != nil {
+		return nil, fmt.Errorf("aliyun invalid port range %s", rule.PortRange)
+	}
+	if c.PortTo, err = strconv.Atoi(ports[1]); err != nil {
+		return nil, fmt.Errorf("aliyun invalid port range %s", rule.PortRange)
+	}
+	if c.PortFrom == navite.PortAll || c.AllPorts() {
+		c.PortFrom, c.PortTo = navite.PortAll, navite.PortAll
+	}
+	return c, nil
+}
+
+// FromCanonicalRule 将统一的规则转换为阿里云的安全组规则
+//
+// * Priority 小于1时取1, 大于100时返回错误, 避免多条规则被合并成相同的优先级
+func FromCanonicalRule(groupID string, c *navite.CanonicalRule) (rule *navite.SecurityGroupRule, err error) {
+	if _, ok := protocols[string(c.Protocol)]; !ok {
+		return nil, fmt.Errorf("aliyun not support protocol %s", c.Protocol)
+	}
+	rule = &navite.SecurityGroupRule{
+		CloudName:   constants.Aliyun,
+		GroupID:     groupID,
+		Direction:   c.Direction,
+		Protocol:    string(c.Protocol),
+		Action:      strings.ToLower(string(c.Action)),
+		Description: c.Description,
+	}
+	switch c.Direction {
+	case constants.FlowIngress:
+		rule.SourceCidrIP = c.CidrIP
+	case constants.FlowEgress:
+		rule.DestCidrIP = c.CidrIP
+	default:
+		return nil, fmt.Errorf("aliyun not support direction %s", c.Direction)
+	}
+
+	switch {
+	case c.Protocol != navite.ProtocolTCP && c.Protocol != navite.ProtocolUDP:
+		if !c.AllPorts() {
+			return nil, fmt.Errorf("aliyun protocol %s only support all ports", c.Protocol)
+		}
+		rule.PortRange = "-1/-1"
+	case c.AllPorts():
+		rule.PortRange = "1/65535"
+	default:
+		rule.PortRange = strconv.Itoa(c.PortFrom) + "/" + strconv.Itoa(c.PortTo)
+	}
+
+	priority := c.Priority
+	if priority < 1 {
+		priority = 1
+	}
+	if priority > 100 {
+		return nil, fmt.Errorf("aliyun priority %d out of range 1-100", priority)
+	}
+	rule.Priority = strconv.Itoa(priority)
+	return rule, nil
+}
@@ -0,0 +1,82 @@
+package aliyun
+
+import (
+	"ark-common/constants"
+	"ark-common/resource/navite"
+)
+
+var instanceStatus = map[string]string{
+	"Pending":  constants.InstanceStatusPending,
+	"Starting": constants.InstanceStatusStarting,
+	"Running":  constants.InstanceStatusRunning,
+	"Stopping": constants.InstanceStatusStopping,
+	"Stopped":  constants.InstanceStatusStopped,
+}
+
+var diskStatus = map[string]string{
+	"Creating":  constants.DiskStatusCreating,
+	"Available": constants.DiskStatusAvailable,
+	"Attaching": constants.DiskStatusAttaching,
+	"In_use":    constants.DiskStatusInUse,
+	"Detaching": constants.DiskStatusDetaching,
+	"ReIniting": constants.DiskStatusInUse,
+}
+
+var eipStatus = map[string]string{
+	"Available":     constants.EipStatusAvailable,
+	"Associating":   constants.EipStatusBinding,
+	"InUse":         constants.EipStatusInUse,
+	"Unassociating": constants.EipStatusUnbinding,
+	"Releasing":     constants.EipStatusReleasing,
+}
+
+var chargeType = map[string]string{
+	"PrePaid":  constants.ChargeTypePrePaid,
+	"PostPaid": constants.ChargeTypePostPaid,
+}
+
+var diskCategory = map[string]string{
+	"cloud":            constants.DiskCategoryBasic,
+	"cloud_efficiency": constants.DiskCategoryEfficiency,
+	"cloud_ssd":        constants.DiskCategorySSD,
+	"cloud_essd":       constants.DiskCategoryESSD,
+	"ephemeral":        constants.DiskCategoryLocal,
+	"ephemeral_ssd":    constants.DiskCategoryLocal,
+	"local_ssd_pro":    constants.DiskCategoryLocal,
+	"local_hdd_pro":    constants.DiskCategoryLocal,
+}
+
+// unifyInstance 设置实例的统一状态和付费方式
+//
+// * 抢占式实例的付费方式为PostPaid, 需要结合SpotStrategy判断
+func unifyInstance(instance *navite.Instance, spotStrategy string) {
+	instance.UnifiedStatus = navite.UnifiedValue(instanceStatus, instance.Status)
+	instance.UnifiedChargeType = navite.UnifiedValue(chargeType, instance.ChargeType)
+	if spotStrategy != "" && spotStrategy != "NoSpot" {
+		instance.UnifiedChargeType = constants.ChargeTypeSpot
+	}
+}
+
+// UnifyInstance 修改实例的Status后重新计算统一状态和付费方式
+//
+// * 实例记录中没有SpotStrategy, 已经是抢占式的实例保持抢占式
+func UnifyInstance(instance *navite.Instance) {
+	spot := instance.UnifiedChargeType == constants.ChargeTypeSpot
+	unifyInstance(instance, "")
+	if spot {
+		instance.UnifiedChargeType = constants.ChargeTypeSpot
+	}
+}
+
+// UnifyDisk 设置磁盘的统一状态、付费方式和类型
+func UnifyDisk(disk *navite.Disk) {
+	disk.UnifiedStatus = navite.UnifiedValue(diskStatus, disk.Status)
+	disk.UnifiedChargeType = navite.UnifiedValue(chargeType, disk.ChargeType)
+	disk.UnifiedDiskType = navite.UnifiedValue(diskCategory, disk.DiskType)
+}
+
+// unifyEip 设置弹性公网IP的统一状态和付费方式
+func unifyEip(eip *navite.Eip) {
+	eip.UnifiedStatus = navite.UnifiedValue(eipStatus, eip.AddressStatus)
+	eip.UnifiedChargeType = navite.UnifiedValue(chargeType, eip.ChargeType)
+}
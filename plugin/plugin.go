@@ -37,6 +37,9 @@ type ResourceDriver interface {
 	GetVPCList(pageSize, currentPage int) (count int, vpcList []*navite.VPC)                    // 同步VPC
 	GetSubnetList(pageSize, currentPage int) (count int, subnetList []*navite.Subnet)           // 同步子网
 	GetEipList(pageSize, currentPage int) (count int, eipList []*navite.Eip)                    // 同步弹性公网
+	GetQuotaList() (quotaList []*navite.Quota)                                                  // 同步配额
+
+	SyncError() error // 返回最近一次同步接口的错误, 同步接口出错时返回空列表, 需要用它区分云端确实没有资源
 
 	NewKeypair(keypair *navite.Keypair) (err error)                                    // 创建密钥对
 	DeleteKeypair(keypairIDList ...string) (err error)                                 // 删除密钥对
@@ -62,6 +65,28 @@ type ResourceDriver interface {
 	DetachDisk(instance *navite.Instance, disk *navite.Disk) (err error)               // 卸载磁盘
 	AttachEipToInstance(instance *navite.Instance, eip *navite.Eip) (err error)        // 绑定弹性公网IP到实例上
 	DetachEipFromInstance(instance *navite.Instance, eip *navite.Eip) (err error)      // 从实例上解绑弹性公网IP
+
+	NewSecurityGroupRules(rules ...*navite.SecurityGroupRule) (err error)    // 批量创建安全组规则
+	DeleteSecurityGroupRules(rules ...*navite.SecurityGroupRule) (err error) // 批量删除安全组规则
+
+	ModifyInstanceSpec(instance *navite.Instance, instanceType string) (err error)                             // 调整实例规格
+	ModifyInstanceAttribute(instance *navite.Instance, instanceName, description, hostName string) (err error) // 修改实例名称/描述/主机名
+	ResizeDisk(disk *navite.Disk, size int, online bool) (err error)                                           // 扩容磁盘
+
+	ResetInstancePassword(instance *navite.Instance, password string) (err error)    // 重置实例登录密码
+	AttachKeypair(instance *navite.Instance, keypairID string) (err error)           // 为实例绑定密钥对
+	DetachKeypair(instance *navite.Instance, keypairID string) (err error)           // 解绑实例的密钥对
+	ResetVncPassword(instance *navite.Instance, password string) (err error)         // 修改登录管理终端的VNC密码
+	GetVncConsole(instance *navite.Instance) (console *navite.VncConsole, err error) // 获取实例的管理终端地址
+
+	InquiryInstancePrice(instance *param.RunInstanceParam) (price *navite.Price, err error) // 创建实例询价
+	InquiryDiskPrice(disk *navite.Disk) (price *navite.Price, err error)                    // 创建磁盘询价
+	InquiryEIPPrice(eip *navite.Eip) (price *navite.Price, err error)                       // 申请弹性公网IP询价
+}
+
+// MetricDriver 云商监控接口
+type MetricDriver interface {
+	GetInstanceMetric(instance *navite.Instance, metricName string, startTime, endTime time.Time) (points []*navite.MetricPoint, err error) // 获取实例监控数据
 }
 
 // GetCloudDriver 返回对应的云商资源驱动
@@ -79,6 +104,21 @@ func GetCloudDriver(ac *navite.CloudAccount) ResourceDriver {
 	return nil
 }
 
+// GetMetricDriver 返回对应的云商监控驱动
+func GetMetricDriver(ac *navite.CloudAccount) MetricDriver {
+	if ac == nil {
+		return nil
+	}
+	switch ac.CloudName {
+	case constants.Aliyun:
+		return aliyun.NewAliyunMonitorPlugin(ac)
+	case constants.Tencent:
+		return tencent.NewTencentMonitorPlugin(ac)
+	}
+	log.Errorf("not support cloud %s", ac.CloudName)
+	return nil
+}
+
 // GetCloudAccountDriver 根据云账号返回对应的云商账号驱动
 func GetCloudAccountDriver(rbd *mgo.Client, cloudName string) AccountDriver {
 	switch cloudName {
@@ -0,0 +1,37 @@
+package plugin
+
+import (
+	"ark-common/constants"
+	"errors"
+	"net"
+	"strings"
+)
+
+var errorKeywords = []struct {
+	class    string
+	keywords []string
+}{
+	{constants.ErrorThrottling, []string{"throttling", "requestlimitexceeded", "too many requests", "flow control"}},
+	{constants.ErrorNetwork, []string{"timeout", "connection reset", "connection refused", "broken pipe", "no such host", "eof"}},
+	{constants.ErrorServer, []string{"internalerror", "internal error", "serviceunavailable", "service unavailable"}},
+}
+
+// ClassifyError 按阿里云和腾讯云的错误码及常见网络错误返回错误类型, 如 constants.ErrorThrottling
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+	msg := strings.ToLower(err.Error())
+	for _, ek := range errorKeywords {
+		for _, keyword := range ek.keywords {
+			if strings.Contains(msg, keyword) {
+				return ek.class
+			}
+		}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return constants.ErrorNetwork
+	}
+	return constants.ErrorUnknown
+}
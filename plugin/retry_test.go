@@ -0,0 +1,39 @@
+package plugin_test
+
+import (
+	"ark-common/constants"
+	"ark-common/plugin"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClassifyError(t *testing.T) {
+	Convey("按错误信息判断能否重试", t, func() {
+		So(plugin.ClassifyError(nil), ShouldEqual, "")
+		So(plugin.ClassifyError(errors.New("SDK.ServerError\nErrorCode: Throttling.User")), ShouldEqual, constants.ErrorThrottling)
+		So(plugin.ClassifyError(errors.New("[TencentCloudSDKError] Code=RequestLimitExceeded")), ShouldEqual, constants.ErrorThrottling)
+		So(plugin.ClassifyError(errors.New("read tcp: i/o timeout")), ShouldEqual, constants.ErrorNetwork)
+		So(plugin.ClassifyError(errors.New("ErrorCode: InternalError")), ShouldEqual, constants.ErrorServer)
+		So(plugin.ClassifyError(errors.New("ErrorCode: InvalidAccessKeyId.NotFound")), ShouldEqual, constants.ErrorUnknown)
+	})
+}
+
+func TestClassifyHandlerError(t *testing.T) {
+	Convey("同步作业处理函数返回的错误", t, func() {
+		Convey("包装后的云商错误仍按错误码分类", func() {
+			err := fmt.Errorf("get instance list failed: %w", errors.New("[TencentCloudSDKError] Code=InternalError"))
+			So(plugin.ClassifyError(err), ShouldEqual, constants.ErrorServer)
+		})
+		Convey("没有关键字的网络错误按net.Error分类", func() {
+			err := fmt.Errorf("dial: %w", &net.AddrError{Err: "missing port", Addr: "ecs.aliyuncs.com"})
+			So(plugin.ClassifyError(err), ShouldEqual, constants.ErrorNetwork)
+		})
+		Convey("部分资源写入mongo失败不重试", func() {
+			So(plugin.ClassifyError(errors.New("upsert 1 of 3 instances failed")), ShouldEqual, constants.ErrorUnknown)
+		})
+	})
+}
@@ -0,0 +1,103 @@
+package plugin
+
+import (
+	"ark-common/constants"
+	"ark-common/plugin/aliyun"
+	"ark-common/plugin/tencent"
+	"ark-common/resource/navite"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ToCanonicalRule 根据规则所属的云商将其转换为统一的规则
+func ToCanonicalRule(rule *navite.SecurityGroupRule) (*navite.CanonicalRule, error) {
+	switch rule.CloudName {
+	case constants.Aliyun:
+		return aliyun.ToCanonicalRule(rule)
+	case constants.Tencent:
+		return tencent.ToCanonicalRule(rule)
+	}
+	return nil, fmt.Errorf("not support cloud %s", rule.CloudName)
+}
+
+// FromCanonicalRule 将统一的规则转换为指定云商的安全组规则
+func FromCanonicalRule(cloudName, groupID string, c *navite.CanonicalRule) (*navite.SecurityGroupRule, error) {
+	switch cloudName {
+	case constants.Aliyun:
+		return aliyun.FromCanonicalRule(groupID, c)
+	case constants.Tencent:
+		return tencent.FromCanonicalRule(groupID, c)
+	}
+	return nil, fmt.Errorf("not support cloud %s", cloudName)
+}
+
+// ConvertSecurityGroupRules 将srcCloud的安全组规则转换为dstCloud的规则
+//
+// * 规则先转换为统一的规则再转换为目标云商的规则, 无法转换的规则记录在skipped中
+// * 跨云商转换时按规则的生效顺序重新编号优先级, 保持规则之间的先后关系
+// * 重新编号后超出目标云商优先级范围的规则(如阿里云超过100)记录在skipped中
+func ConvertSecurityGroupRules(srcCloud, dstCloud string, rules []*navite.SecurityGroupRule) (copied []*navite.SecurityGroupRule, skipped []*navite.SkippedRule) {
+	type pair struct {
+		raw       *navite.SecurityGroupRule
+		canonical *navite.CanonicalRule
+	}
+	pairs := []*pair{}
+	for _, rule := range rules {
+		c, err := ToCanonicalRule(rule)
+		if err != nil {
+			skipped = append(skipped, &navite.SkippedRule{Rule: rule, Reason: err.Error()})
+			continue
+		}
+		pairs = append(pairs, &pair{raw: rule, canonical: c})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].canonical.Before(pairs[j].canonical)
+	})
+	if srcCloud != dstCloud {
+		ranks := map[string]int{}
+		last := map[string]*navite.CanonicalRule{}
+		for _, p := range pairs {
+			c := p.canonical
+			prev, ok := last[c.Direction]
+			if !ok || prev.Before(c) {
+				ranks[c.Direction]++
+			}
+			last[c.Direction] = c
+			c.Priority = ranks[c.Direction]
+		}
+	}
+	for _, p := range pairs {
+		rule, err := FromCanonicalRule(dstCloud, "", p.canonical)
+		if err != nil {
+			skipped = append(skipped, &navite.SkippedRule{Rule: p.raw, Reason: err.Error()})
+			continue
+		}
+		copied = append(copied, rule)
+	}
+	return copied, skipped
+}
+
+// SecurityGroupRuleKey 返回与云商无关的规则标识, 无法转换的规则使用原始的标识
+func SecurityGroupRuleKey(rule *navite.SecurityGroupRule) string {
+	c, err := ToCanonicalRule(rule)
+	if err != nil {
+		return rule.Key()
+	}
+	return c.Key()
+}
+
+// SecurityGroupRulePriorityKey 在SecurityGroupRuleKey的基础上加上优先级
+//
+// * 各云商优先级的含义不同, 只用于比较同一云商下的规则, 只修改了优先级的规则Key不同
+// * 腾讯云的优先级是规则在方向内的序号(PolicyIndex), 新增和删除规则时其他规则的序号随之变化, 不参与比较
+func SecurityGroupRulePriorityKey(rule *navite.SecurityGroupRule) string {
+	c, err := ToCanonicalRule(rule)
+	if err != nil {
+		return rule.Key()
+	}
+	if rule.CloudName == constants.Tencent {
+		return c.Key()
+	}
+	return c.Key() + "|" + strconv.Itoa(c.Priority)
+}
@@ -0,0 +1,145 @@
+package plugin_test
+
+import (
+	"ark-common/constants"
+	"ark-common/plugin"
+	"ark-common/resource/navite"
+	"strconv"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCanonicalRule(t *testing.T) {
+	Convey("安全组规则在云商之间转换", t, func() {
+		aliRule := &navite.SecurityGroupRule{
+			CloudName:    constants.Aliyun,
+			GroupID:      "sg-2zefpzvxfxkw5nkflw3k",
+			SourceCidrIP: "10.10.1.0/24",
+			Direction:    constants.FlowIngress,
+			PortRange:    "8081/8090",
+			Protocol:     "TCP",
+			Priority:     "10",
+			Action:       "ACCEPT",
+		}
+		tenRule := &navite.SecurityGroupRule{
+			CloudName:    constants.Tencent,
+			GroupID:      "sg-ohuuioma",
+			SourceCidrIP: "10.10.1.0/24",
+			Direction:    constants.FlowIngress,
+			PortRange:    "8081-8090",
+			Protocol:     "TCP",
+			Priority:     "0",
+			Action:       "ACCEPT",
+		}
+
+		Convey("相同含义的规则Key相同", func() {
+			So(plugin.SecurityGroupRuleKey(aliRule), ShouldEqual, plugin.SecurityGroupRuleKey(tenRule))
+		})
+
+		Convey("阿里云规则转换为腾讯云规则", func() {
+			c, err := plugin.ToCanonicalRule(aliRule)
+			So(err, ShouldBeNil)
+			So(c.Protocol, ShouldEqual, navite.ProtocolTCP)
+			So(c.Priority, ShouldEqual, 10)
+			r, err := plugin.FromCanonicalRule(constants.Tencent, "sg-ohuuioma", c)
+			So(err, ShouldBeNil)
+			So(r.PortRange, ShouldEqual, "8081-8090")
+			So(r.Protocol, ShouldEqual, "TCP")
+		})
+
+		Convey("全部端口的写法等价", func() {
+			aliRule.PortRange = "1/65535"
+			tenRule.PortRange = "ALL"
+			So(plugin.SecurityGroupRuleKey(aliRule), ShouldEqual, plugin.SecurityGroupRuleKey(tenRule))
+			c, err := plugin.ToCanonicalRule(tenRule)
+			So(err, ShouldBeNil)
+			r, err := plugin.FromCanonicalRule(constants.Aliyun, "sg-2zefpzvxfxkw5nkflw3k", c)
+			So(err, ShouldBeNil)
+			So(r.PortRange, ShouldEqual, "1/65535")
+			So(r.Protocol, ShouldEqual, "tcp")
+			So(r.Action, ShouldEqual, "accept")
+		})
+
+		Convey("优先级不同的规则PriorityKey不同", func() {
+			other := *aliRule
+			other.Priority = "20"
+			So(plugin.SecurityGroupRuleKey(&other), ShouldEqual, plugin.SecurityGroupRuleKey(aliRule))
+			So(plugin.SecurityGroupRulePriorityKey(&other), ShouldNotEqual, plugin.SecurityGroupRulePriorityKey(aliRule))
+			other.Priority = ""
+			aliRule.Priority = "1"
+			So(plugin.SecurityGroupRulePriorityKey(&other), ShouldEqual, plugin.SecurityGroupRulePriorityKey(aliRule))
+		})
+
+		Convey("腾讯云规则的序号不参与比较", func() {
+			other := *tenRule
+			other.Priority = "7"
+			So(plugin.SecurityGroupRulePriorityKey(&other), ShouldEqual, plugin.SecurityGroupRulePriorityKey(tenRule))
+		})
+
+		Convey("腾讯云多端口规则无法转换", func() {
+			tenRule.PortRange = "80,443"
+			_, err := plugin.ToCanonicalRule(tenRule)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestConvertSecurityGroupRules(t *testing.T) {
+	tenRule := func(port string, priority int) *navite.SecurityGroupRule {
+		return &navite.SecurityGroupRule{
+			CloudName:    constants.Tencent,
+			GroupID:      "sg-ohuuioma",
+			SourceCidrIP: "10.10.1.0/24",
+			Direction:    constants.FlowIngress,
+			PortRange:    port,
+			Protocol:     "TCP",
+			Priority:     strconv.Itoa(priority),
+			Action:       "ACCEPT",
+		}
+	}
+	Convey("跨云商转换安全组规则", t, func() {
+		Convey("按生效顺序重新编号优先级", func() {
+			copied, skipped := plugin.ConvertSecurityGroupRules(constants.Tencent, constants.Aliyun, []*navite.SecurityGroupRule{
+				tenRule("443", 2),
+				tenRule("22", 0),
+				tenRule("80", 1),
+			})
+			So(skipped, ShouldBeEmpty)
+			So(len(copied), ShouldEqual, 3)
+			So(copied[0].PortRange, ShouldEqual, "22/22")
+			So(copied[0].Priority, ShouldEqual, "1")
+			So(copied[1].PortRange, ShouldEqual, "80/80")
+			So(copied[1].Priority, ShouldEqual, "2")
+			So(copied[2].PortRange, ShouldEqual, "443/443")
+			So(copied[2].Priority, ShouldEqual, "3")
+		})
+		Convey("无法转换的规则记录在skipped中", func() {
+			copied, skipped := plugin.ConvertSecurityGroupRules(constants.Tencent, constants.Aliyun, []*navite.SecurityGroupRule{
+				tenRule("80,443", 0),
+				tenRule("22", 1),
+			})
+			So(len(copied), ShouldEqual, 1)
+			So(len(skipped), ShouldEqual, 1)
+			So(skipped[0].Rule.PortRange, ShouldEqual, "80,443")
+		})
+		Convey("超过阿里云优先级范围的规则记录在skipped中", func() {
+			rules := []*navite.SecurityGroupRule{}
+			for i := 0; i < 102; i++ {
+				rules = append(rules, tenRule(strconv.Itoa(1000+i), i))
+			}
+			copied, skipped := plugin.ConvertSecurityGroupRules(constants.Tencent, constants.Aliyun, rules)
+			So(len(copied), ShouldEqual, 100)
+			So(copied[99].Priority, ShouldEqual, "100")
+			So(len(skipped), ShouldEqual, 2)
+			So(skipped[0].Rule.PortRange, ShouldEqual, "1100")
+		})
+		Convey("同云商复制时保留原优先级", func() {
+			copied, skipped := plugin.ConvertSecurityGroupRules(constants.Tencent, constants.Tencent, []*navite.SecurityGroupRule{
+				tenRule("22", 5),
+			})
+			So(skipped, ShouldBeEmpty)
+			So(copied[0].Priority, ShouldEqual, "5")
+		})
+	})
+}
@@ -0,0 +1,28 @@
+package plugin
+
+import (
+	"ark-common/constants"
+	"ark-common/plugin/aliyun"
+	"ark-common/plugin/tencent"
+	"ark-common/resource/navite"
+)
+
+// UnifyInstance 根据实例所属的云商重新计算统一状态和付费方式, 修改了Status/ChargeType后需要调用
+func UnifyInstance(instance *navite.Instance) {
+	switch instance.CloudName {
+	case constants.Aliyun:
+		aliyun.UnifyInstance(instance)
+	case constants.Tencent:
+		tencent.UnifyInstance(instance)
+	}
+}
+
+// UnifyDisk 根据磁盘所属的云商重新计算统一状态、付费方式和类型, 修改了Status/ChargeType/DiskType后需要调用
+func UnifyDisk(disk *navite.Disk) {
+	switch disk.CloudName {
+	case constants.Aliyun:
+		aliyun.UnifyDisk(disk)
+	case constants.Tencent:
+		tencent.UnifyDisk(disk)
+	}
+}
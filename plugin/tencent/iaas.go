@@ -6,6 +6,8 @@ import (
 	"ark-common/resource/navite"
 	"ark-common/utils/tool"
 	"fmt"
+	"net/url"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -19,12 +21,25 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	instanceRunning = "RUNNING"
+	instanceStopped = "STOPPED"
+
+	// 实例最近一次操作的状态
+	operationSuccess = "SUCCESS"
+	operationFailed  = "FAILED"
+)
+
+// VncViewerURL 管理终端页面的地址, 设置了环境变量 ARK_TENCENT_VNC_VIEWER 时使用环境变量
+var VncViewerURL = "https://img.qcloud.com/qcloud/app/active_vnc/index.html"
+
 // TencentResource 腾讯云驱动
 type TencentResource struct {
 	cvm     *cvm.Client
 	vpc     *vpc.Client
 	cbs     *cbs.Client
 	account *navite.CloudAccount
+	syncErr error // 最近一次同步接口的错误
 }
 
 var rateLimit = map[string]int{
@@ -41,6 +56,7 @@ var rateLimit = map[string]int{
 	constants.HandleSyncSubnet:            100, // https://cloud.tencent.com/document/api/215/15784
 	constants.HandleSyncEip:               10,  // https://cloud.tencent.com/document/api/215/16702
 	constants.HandleCreateEip:             10,  // https://cloud.tencent.com/document/api/215/16699
+	constants.HandleSyncQuota:             20,  // https://cloud.tencent.com/document/api/213/55628
 }
 
 // RateLimit 获取对应账号执行action的每秒并发数
@@ -92,6 +108,11 @@ func (ten *TencentResource) GetCloudName() string {
 	return constants.Tencent
 }
 
+// SyncError 返回最近一次同步接口的错误, 用于区分调用失败和云端没有资源
+func (ten *TencentResource) SyncError() error {
+	return ten.syncErr
+}
+
 // SyncJobs 返回自动同步的作业
 func (ten *TencentResource) SyncJobs() []string {
 	return []string{
@@ -106,6 +127,7 @@ func (ten *TencentResource) SyncJobs() []string {
 		constants.HandleSyncVPC,
 		constants.HandleSyncSubnet,
 		constants.HandleSyncEip,
+		constants.HandleSyncQuota,
 	}
 }
 
@@ -141,10 +163,12 @@ func GetPageLimitString(pageSize, currentPage int) (limit, offset *string) {
 
 // GetRegionList 获取地域列表
 func (ten *TencentResource) GetRegionList() (regionList []*navite.CloudRegion) {
+	ten.syncErr = nil
 	req := cvm.NewDescribeRegionsRequest()
 	resp, err := ten.cvm.DescribeRegions(req)
 	if err != nil {
 		log.Errorf("tencent describe regions failed: %v", err)
+		ten.syncErr = err
 		return
 	}
 	for _, res := range resp.Response.RegionSet {
@@ -161,10 +185,12 @@ func (ten *TencentResource) GetRegionList() (regionList []*navite.CloudRegion) {
 
 // GetZoneList 获取可用区列表
 func (ten *TencentResource) GetZoneList() (zoneList []*navite.CloudZone) {
+	ten.syncErr = nil
 	req := cvm.NewDescribeZonesRequest()
 	resp, err := ten.cvm.DescribeZones(req)
 	if err != nil {
 		log.Errorf("tencent describe zones failed: %v", err)
+		ten.syncErr = err
 		return
 	}
 	for _, res := range resp.Response.ZoneSet {
@@ -182,11 +208,13 @@ func (ten *TencentResource) GetZoneList() (zoneList []*navite.CloudZone) {
 
 // GetImageList 获取镜像列表
 func (ten *TencentResource) GetImageList(pageSize, currentPage int) (count int, imgs []*navite.Image) {
+	ten.syncErr = nil
 	req := cvm.NewDescribeImagesRequest()
 	req.Limit, req.Offset = GetPageLimitUint64(pageSize, currentPage)
 	resp, err := ten.cvm.DescribeImages(req)
 	if err != nil {
 		log.Errorf("tencent describe images failed: %v", err)
+		ten.syncErr = err
 		return
 	}
 	for _, res := range resp.Response.ImageSet {
@@ -203,6 +231,9 @@ func (ten *TencentResource) GetImageList(pageSize, currentPage int) (count int,
 			Description: *res.ImageDescription,
 			SyncedTime:  time.Now(),
 		}
+		if res.ImageType != nil {
+			img.Owner = *res.ImageType // PUBLIC_IMAGE/PRIVATE_IMAGE/SHARED_IMAGE
+		}
 		imgs = append(imgs, img)
 	}
 	count = int(*resp.Response.TotalCount)
@@ -211,11 +242,13 @@ func (ten *TencentResource) GetImageList(pageSize, currentPage int) (count int,
 
 // GetInstanceList 获取实例列表
 func (ten *TencentResource) GetInstanceList(pageSize, currentPage int) (count int, instanceList []*navite.Instance) {
+	ten.syncErr = nil
 	req := cvm.NewDescribeInstancesRequest()
 	req.Limit, req.Offset = GetPageLimitInt64(pageSize, currentPage)
 	resp, err := ten.cvm.DescribeInstances(req)
 	if err != nil {
 		log.Errorf("tencent describe instance failed: %v", err)
+		ten.syncErr = err
 		return
 	}
 	for _, res := range resp.Response.InstanceSet {
@@ -258,6 +291,7 @@ func (ten *TencentResource) GetInstanceList(pageSize, currentPage int) (count in
 			CreatedTime: tool.TimeForISO8601(*res.CreatedTime),
 			SyncedTime:  time.Now(),
 		}
+		UnifyInstance(instance)
 		instanceList = append(instanceList, instance)
 	}
 	count = int(*resp.Response.TotalCount)
@@ -266,11 +300,13 @@ func (ten *TencentResource) GetInstanceList(pageSize, currentPage int) (count in
 
 // GetSecurityGroupList 获取安全组列表
 func (ten *TencentResource) GetSecurityGroupList(pageSize, currentPage int) (count int, sgList []*navite.SecurityGroup) {
+	ten.syncErr = nil
 	req := vpc.NewDescribeSecurityGroupsRequest()
 	req.Limit, req.Offset = GetPageLimitString(pageSize, currentPage)
 	resp, err := ten.vpc.DescribeSecurityGroups(req)
 	if err != nil {
 		log.Errorf("tencent describe securityGroup failed: %v", err)
+		ten.syncErr = err
 		return
 	}
 	for _, res := range resp.Response.SecurityGroupSet {
@@ -293,11 +329,13 @@ func (ten *TencentResource) GetSecurityGroupList(pageSize, currentPage int) (cou
 
 // GetDiskList 获取磁盘列表
 func (ten *TencentResource) GetDiskList(pageSize, currentPage int) (count int, diskList []*navite.Disk) {
+	ten.syncErr = nil
 	req := cbs.NewDescribeDisksRequest()
 	req.Limit, req.Offset = GetPageLimitUint64(pageSize, currentPage)
 	resp, err := ten.cbs.DescribeDisks(req)
 	if err != nil {
 		log.Errorf("tencent describe disks failed: %v", err)
+		ten.syncErr = err
 		return
 	}
 	for _, res := range resp.Response.DiskSet {
@@ -317,6 +355,7 @@ func (ten *TencentResource) GetDiskList(pageSize, currentPage int) (count int, d
 			CreatedTime:      tool.TimeForISO8601(*res.CreateTime),
 			SyncedTime:       time.Now(),
 		}
+		UnifyDisk(disk)
 		diskList = append(diskList, disk)
 	}
 	count = int(*resp.Response.TotalCount)
@@ -325,11 +364,13 @@ func (ten *TencentResource) GetDiskList(pageSize, currentPage int) (count int, d
 
 // GetKeypairList 获取密钥对
 func (ten *TencentResource) GetKeypairList(pageSize, currentPage int) (count int, keypairList []*navite.Keypair) {
+	ten.syncErr = nil
 	req := cvm.NewDescribeKeyPairsRequest()
 	req.Limit, req.Offset = GetPageLimitInt64(pageSize, currentPage)
 	resp, err := ten.cvm.DescribeKeyPairs(req)
 	if err != nil {
 		log.Errorf("tencent describe keypairs failed: %v", err)
+		ten.syncErr = err
 		return
 	}
 	for _, res := range resp.Response.KeyPairSet {
@@ -344,6 +385,9 @@ func (ten *TencentResource) GetKeypairList(pageSize, currentPage int) (count int
 			CreatedTime: tool.TimeForISO8601(*res.CreatedTime),
 			SyncedTime:  time.Now(),
 		}
+		if err = keypair.SetFingerprint(); err != nil {
+			log.Warnf("parse keypair [%s] public key failed: %v", keypair.KeypairID, err)
+		}
 		keypairList = append(keypairList, keypair)
 	}
 	count = int(*resp.Response.TotalCount)
@@ -352,21 +396,26 @@ func (ten *TencentResource) GetKeypairList(pageSize, currentPage int) (count int
 
 // GetSecurityGroupRuleList 获取安全组规则
 func (ten *TencentResource) GetSecurityGroupRuleList(securityGroupID string) (sgrList []*navite.SecurityGroupRule) {
+	ten.syncErr = nil
 	req := vpc.NewDescribeSecurityGroupPoliciesRequest()
 	req.SecurityGroupId = &securityGroupID
 	resp, err := ten.vpc.DescribeSecurityGroupPolicies(req)
 	if err != nil {
 		log.Errorf("tencnet describe securityGroupRules failed: %v", err)
+		ten.syncErr = err
 		return
 	}
 	for _, res := range resp.Response.SecurityGroupPolicySet.Egress {
 		sgr := &navite.SecurityGroupRule{
 			CloudName:   constants.Tencent,
+			AccountID:   ten.account.AccountID(),
+			RegionID:    ten.account.RunRegionID,
 			GroupID:     securityGroupID,
 			DestCidrIP:  *res.CidrBlock,
 			Direction:   constants.FlowEgress,
 			Protocol:    *res.Protocol,
 			PortRange:   *res.Port,
+			Priority:    strconv.Itoa(int(*res.PolicyIndex)),
 			Action:      *res.Action,
 			Description: *res.PolicyDescription,
 			SyncedTime:  time.Now(),
@@ -376,11 +425,14 @@ func (ten *TencentResource) GetSecurityGroupRuleList(securityGroupID string) (sg
 	for _, res := range resp.Response.SecurityGroupPolicySet.Ingress {
 		sgr := &navite.SecurityGroupRule{
 			CloudName:    constants.Tencent,
+			AccountID:    ten.account.AccountID(),
+			RegionID:     ten.account.RunRegionID,
 			GroupID:      securityGroupID,
 			SourceCidrIP: *res.CidrBlock,
 			Direction:    constants.FlowIngress,
 			Protocol:     *res.Protocol,
 			PortRange:    *res.Port,
+			Priority:     strconv.Itoa(int(*res.PolicyIndex)),
 			Action:       *res.Action,
 			Description:  *res.PolicyDescription,
 			SyncedTime:   time.Now(),
@@ -392,10 +444,12 @@ func (ten *TencentResource) GetSecurityGroupRuleList(securityGroupID string) (sg
 
 // GetInstanceSpecsList 获取实例规格
 func (ten *TencentResource) GetInstanceSpecsList() (instantSpecList []*navite.InstanceSpec) {
+	ten.syncErr = nil
 	req := cvm.NewDescribeZoneInstanceConfigInfosRequest()
 	resp, err := ten.cvm.DescribeZoneInstanceConfigInfos(req)
 	if err != nil {
 		log.Errorf("tencent describe instanceTypes failed: %v", err)
+		ten.syncErr = err
 		return
 	}
 	for _, res := range resp.Response.InstanceTypeQuotaSet {
@@ -412,6 +466,15 @@ func (ten *TencentResource) GetInstanceSpecsList() (instantSpecList []*navite.In
 			Status:           *res.Status,
 			SyncedTime:       time.Now(),
 		}
+		if res.Gpu != nil {
+			spec.GPU = int(*res.Gpu)
+		}
+		if res.Price != nil && res.Price.UnitPrice != nil {
+			spec.HourlyPrice = *res.Price.UnitPrice
+			if res.Price.UnitPriceDiscount != nil {
+				spec.HourlyPrice = *res.Price.UnitPriceDiscount
+			}
+		}
 		instantSpecList = append(instantSpecList, spec)
 	}
 	return
@@ -419,11 +482,13 @@ func (ten *TencentResource) GetInstanceSpecsList() (instantSpecList []*navite.In
 
 // GetVPCList 获取VPC资源列表
 func (ten *TencentResource) GetVPCList(pageSize, currentPage int) (count int, vpcList []*navite.VPC) {
+	ten.syncErr = nil
 	req := vpc.NewDescribeVpcsRequest()
 	req.Limit, req.Offset = GetPageLimitString(pageSize, currentPage)
 	resp, err := ten.vpc.DescribeVpcs(req)
 	if err != nil {
 		log.Errorf("tencnet describe vpcs failed: %v", err)
+		ten.syncErr = err
 		return
 	}
 	for _, res := range resp.Response.VpcSet {
@@ -445,11 +510,13 @@ func (ten *TencentResource) GetVPCList(pageSize, currentPage int) (count int, vp
 
 // GetSubnetList 获取子网列表
 func (ten *TencentResource) GetSubnetList(pageSize, currentPage int) (count int, subnetList []*navite.Subnet) {
+	ten.syncErr = nil
 	req := vpc.NewDescribeSubnetsRequest()
 	req.Limit, req.Offset = GetPageLimitString(pageSize, currentPage)
 	resp, err := ten.vpc.DescribeSubnets(req)
 	if err != nil {
 		log.Errorf("tencnet describe subnets failed: %v", err)
+		ten.syncErr = err
 		return
 	}
 	for _, res := range resp.Response.SubnetSet {
@@ -476,11 +543,13 @@ func (ten *TencentResource) GetSubnetList(pageSize, currentPage int) (count int,
 
 // GetEipList 获取弹性公网IP列表
 func (ten *TencentResource) GetEipList(pageSize, currentPage int) (count int, eipList []*navite.Eip) {
+	ten.syncErr = nil
 	req := vpc.NewDescribeAddressesRequest()
 	req.Limit, req.Offset = GetPageLimitInt64(pageSize, currentPage)
 	resp, err := ten.vpc.DescribeAddresses(req)
 	if err != nil {
 		log.Errorf("tencent describe eips failed: %v", err)
+		ten.syncErr = err
 		return
 	}
 	for _, res := range resp.Response.AddressSet {
@@ -498,6 +567,7 @@ func (ten *TencentResource) GetEipList(pageSize, currentPage int) (count int, ei
 			CreatedTime:        tool.TimeForISO8601(*res.CreatedTime),
 			SyncedTime:         time.Now(),
 		}
+		unifyEip(eip)
 		eipList = append(eipList, eip)
 	}
 	return int(*resp.Response.TotalCount), eipList
@@ -516,7 +586,10 @@ func (ten *TencentResource) NewKeypair(keypair *navite.Keypair) (err error) {
 		return err
 	}
 	keypair.KeypairID = *resp.Response.KeyId
-	return
+	if err = keypair.SetFingerprint(); err != nil {
+		log.Warnf("parse keypair [%s] public key failed: %v", keypair.KeypairID, err)
+	}
+	return nil
 }
 
 // DeleteKeypair 删除密钥对
@@ -945,3 +1018,289 @@ func (ten *TencentResource) ModifyEIPBandWidth(eip *navite.Eip, bandWidth int64)
 	eip.BandWidth = bandWidth
 	return
 }
+
+// waitInstanceStatus 等待实例变为指定的状态
+func (ten *TencentResource) waitInstanceStatus(instanceID, status string, timeout time.Duration) (err error) {
+	req := cvm.NewDescribeInstancesStatusRequest()
+	req.InstanceIds = []*string{&instanceID}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		resp, err := ten.cvm.DescribeInstancesStatus(req)
+		if err != nil {
+			log.Errorf("tencent describe instance status [%s] failed: %v", req.ToJsonString(), err)
+			return err
+		}
+		if len(resp.Response.InstanceStatusSet) == 0 {
+			return fmt.Errorf("tencent instance %s not found", instanceID)
+		}
+		if *resp.Response.InstanceStatusSet[0].InstanceState == status {
+			return nil
+		}
+		time.Sleep(5 * time.Second)
+	}
+	return fmt.Errorf("wait tencent instance %s to %s timeout", instanceID, status)
+}
+
+// waitInstanceOperation 等待实例上RequestId为requestID的操作结束
+//
+// * 操作刚提交时LatestOperation可能还是上一次的同名操作, 按LatestOperationRequestId匹配本次提交的操作
+func (ten *TencentResource) waitInstanceOperation(instanceID, requestID string, timeout time.Duration) (err error) {
+	req := cvm.NewDescribeInstancesRequest()
+	req.InstanceIds = []*string{&instanceID}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		resp, err := ten.cvm.DescribeInstances(req)
+		if err != nil {
+			log.Errorf("tencent describe instances [%s] failed: %v", req.ToJsonString(), err)
+			return err
+		}
+		if len(resp.Response.InstanceSet) == 0 {
+			return fmt.Errorf("tencent instance %s not found", instanceID)
+		}
+		ins := resp.Response.InstanceSet[0]
+		if ins.LatestOperationRequestId != nil && *ins.LatestOperationRequestId == requestID && ins.LatestOperationState != nil {
+			switch *ins.LatestOperationState {
+			case operationSuccess:
+				return nil
+			case operationFailed:
+				return fmt.Errorf("tencent instance %s operation %s failed", instanceID, requestID)
+			}
+		}
+		time.Sleep(5 * time.Second)
+	}
+	return fmt.Errorf("wait tencent instance %s operation %s timeout", instanceID, requestID)
+}
+
+// ModifyInstanceSpec 调整实例规格
+//
+// * 只有状态为STOPPED的实例才能调整, 运行中的实例会先停机, 调整完成后重新启动
+func (ten *TencentResource) ModifyInstanceSpec(instance *navite.Instance, instanceType string) (err error) {
+	running := instance.Status == instanceRunning
+	if running {
+		if err = ten.StopInstance(instance.InstanceID); err != nil {
+			return err
+		}
+		if err = ten.waitInstanceStatus(instance.InstanceID, instanceStopped, 5*time.Minute); err != nil {
+			return err
+		}
+	}
+	req := cvm.NewResetInstancesTypeRequest()
+	req.InstanceIds = []*string{&instance.InstanceID}
+	req.InstanceType = &instanceType
+	resp, err := ten.cvm.ResetInstancesType(req)
+	if err != nil {
+		log.Errorf("tencent resetInstancesType [%s] failed: %v", req.ToJsonString(), err)
+		return err
+	}
+	instance.InstanceType = instanceType
+	instance.Status = instanceStopped
+	if running {
+		// 调整规格是异步的, 实例状态一直是STOPPED, 需要等ResetInstancesType操作结束后再启动
+		if err = ten.waitInstanceOperation(instance.InstanceID, *resp.Response.RequestId, 10*time.Minute); err != nil {
+			return err
+		}
+		if err = ten.StartInstance(instance.InstanceID); err != nil {
+			return err
+		}
+		instance.Status = instanceRunning
+	}
+	return nil
+}
+
+// ResizeDisk 扩容云盘
+//
+// * 腾讯云支持在线扩容, 忽略online参数
+func (ten *TencentResource) ResizeDisk(disk *navite.Disk, size int, online bool) (err error) {
+	req := cbs.NewResizeDiskRequest()
+	diskSize := uint64(size)
+	req.DiskId = &disk.DiskID
+	req.DiskSize = &diskSize
+	if _, err = ten.cbs.ResizeDisk(req); err != nil {
+		log.Errorf("tencent resizeDisk [%s] failed: %v", req.ToJsonString(), err)
+		return err
+	}
+	disk.DiskSize = size
+	return nil
+}
+
+// ModifyInstanceAttribute 修改实例的名称, 参数为空代表不修改
+//
+// * 腾讯云实例没有描述, 也不支持通过接口修改主机名
+func (ten *TencentResource) ModifyInstanceAttribute(instance *navite.Instance, instanceName, description, hostName string) (err error) {
+	if description != "" || hostName != "" {
+		return fmt.Errorf("tencent not support modify instance description or hostname")
+	}
+	if instanceName == "" {
+		return nil
+	}
+	req := cvm.NewModifyInstancesAttributeRequest()
+	req.InstanceIds = []*string{&instance.InstanceID}
+	req.InstanceName = &instanceName
+	if _, err = ten.cvm.ModifyInstancesAttribute(req); err != nil {
+		log.Errorf("tencent modifyInstancesAttribute [%s] failed: %v", req.ToJsonString(), err)
+		return err
+	}
+	instance.InstanceName = instanceName
+	return nil
+}
+
+// ResetInstancePassword 重置实例的登录密码
+//
+// * 运行中的实例会被强制关机
+// * 请求参数中包含密码, 失败时不能打印请求参数
+func (ten *TencentResource) ResetInstancePassword(instance *navite.Instance, password string) (err error) {
+	forceStop := true
+	req := cvm.NewResetInstancesPasswordRequest()
+	req.InstanceIds = []*string{&instance.InstanceID}
+	req.Password = &password
+	req.ForceStop = &forceStop
+	if _, err = ten.cvm.ResetInstancesPassword(req); err != nil {
+		log.Errorf("tencent reset instance [%s] password failed: %v", instance.InstanceID, err)
+	}
+	return err
+}
+
+// AttachKeypair 为实例绑定密钥对
+//
+// * 运行中的实例会被强制关机
+func (ten *TencentResource) AttachKeypair(instance *navite.Instance, keypairID string) (err error) {
+	forceStop := true
+	req := cvm.NewAssociateInstancesKeyPairsRequest()
+	req.InstanceIds = []*string{&instance.InstanceID}
+	req.KeyIds = []*string{&keypairID}
+	req.ForceStop = &forceStop
+	if _, err = ten.cvm.AssociateInstancesKeyPairs(req); err != nil {
+		log.Errorf("tencent associateInstancesKeyPairs [%s] failed: %v", req.ToJsonString(), err)
+		return err
+	}
+	for _, v := range instance.KeyPairList {
+		if v == keypairID {
+			return nil
+		}
+	}
+	instance.KeyPairList = append(instance.KeyPairList, keypairID)
+	return nil
+}
+
+// DetachKeypair 解绑实例的密钥对
+//
+// * 运行中的实例会被强制关机
+func (ten *TencentResource) DetachKeypair(instance *navite.Instance, keypairID string) (err error) {
+	forceStop := true
+	req := cvm.NewDisassociateInstancesKeyPairsRequest()
+	req.InstanceIds = []*string{&instance.InstanceID}
+	req.KeyIds = []*string{&keypairID}
+	req.ForceStop = &forceStop
+	if _, err = ten.cvm.DisassociateInstancesKeyPairs(req); err != nil {
+		log.Errorf("tencent disassociateInstancesKeyPairs [%s] failed: %v", req.ToJsonString(), err)
+		return err
+	}
+	kList := []string{}
+	for _, v := range instance.KeyPairList {
+		if v != keypairID {
+			kList = append(kList, v)
+		}
+	}
+	instance.KeyPairList = kList
+	return nil
+}
+
+// ResetVncPassword 腾讯云登录管理终端不需要VNC密码
+func (ten *TencentResource) ResetVncPassword(instance *navite.Instance, password string) (err error) {
+	return fmt.Errorf("tencent not support vnc password")
+}
+
+// GetVncConsole 获取实例的管理终端地址
+//
+// * 地址15秒内有效, 且只能使用一次
+func (ten *TencentResource) GetVncConsole(instance *navite.Instance) (console *navite.VncConsole, err error) {
+	req := cvm.NewDescribeInstanceVncUrlRequest()
+	req.InstanceId = &instance.InstanceID
+	resp, err := ten.cvm.DescribeInstanceVncUrl(req)
+	if err != nil {
+		log.Errorf("tencent describeInstanceVncUrl [%s] failed: %v", req.ToJsonString(), err)
+		return nil, err
+	}
+	viewer := VncViewerURL
+	if env := os.Getenv("ARK_TENCENT_VNC_VIEWER"); env != "" {
+		viewer = env
+	}
+	console = &navite.VncConsole{
+		InstanceID:  instance.InstanceID,
+		URL:         viewer + "?InstanceVncUrl=" + url.QueryEscape(*resp.Response.InstanceVncUrl),
+		ExpiredTime: time.Now().Add(15 * time.Second),
+	}
+	return console, nil
+}
+
+// groupPolicies 将规则按安全组和方向分组, 腾讯云同一个请求中只能包含一个方向的规则
+func groupPolicies(rules []*navite.SecurityGroupRule) map[string]map[string][]*vpc.SecurityGroupPolicy {
+	groups := map[string]map[string][]*vpc.SecurityGroupPolicy{}
+	for _, rule := range rules {
+		direction := strings.ToLower(rule.Direction)
+		policy := &vpc.SecurityGroupPolicy{
+			Port:              &rule.PortRange,
+			Protocol:          &rule.Protocol,
+			Action:            &rule.Action,
+			PolicyDescription: &rule.Description,
+		}
+		switch direction {
+		case constants.FlowEgress:
+			policy.CidrBlock = &rule.DestCidrIP
+		case constants.FlowIngress:
+			policy.CidrBlock = &rule.SourceCidrIP
+		default:
+			continue
+		}
+		if _, ok := groups[rule.GroupID]; !ok {
+			groups[rule.GroupID] = map[string][]*vpc.SecurityGroupPolicy{}
+		}
+		groups[rule.GroupID][direction] = append(groups[rule.GroupID][direction], policy)
+	}
+	return groups
+}
+
+func newPolicySet(direction string, policies []*vpc.SecurityGroupPolicy) *vpc.SecurityGroupPolicySet {
+	if direction == constants.FlowEgress {
+		return &vpc.SecurityGroupPolicySet{Egress: policies}
+	}
+	return &vpc.SecurityGroupPolicySet{Ingress: policies}
+}
+
+// NewSecurityGroupRules 批量创建安全组规则
+//
+// * 每个安全组的每个方向发起一次请求
+func (ten *TencentResource) NewSecurityGroupRules(rules ...*navite.SecurityGroupRule) (err error) {
+	for groupID, directions := range groupPolicies(rules) {
+		for direction, policies := range directions {
+			sgID := groupID
+			req := vpc.NewCreateSecurityGroupPoliciesRequest()
+			req.SecurityGroupId = &sgID
+			req.SecurityGroupPolicySet = newPolicySet(direction, policies)
+			if _, err = ten.vpc.CreateSecurityGroupPolicies(req); err != nil {
+				log.Errorf("tencent create securityGroupRules [%s] failed: %v", req.ToJsonString(), err)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteSecurityGroupRules 批量删除安全组规则
+//
+// * 每个安全组的每个方向发起一次请求
+func (ten *TencentResource) DeleteSecurityGroupRules(rules ...*navite.SecurityGroupRule) (err error) {
+	for groupID, directions := range groupPolicies(rules) {
+		for direction, policies := range directions {
+			sgID := groupID
+			req := vpc.NewDeleteSecurityGroupPoliciesRequest()
+			req.SecurityGroupId = &sgID
+			req.SecurityGroupPolicySet = newPolicySet(direction, policies)
+			if _, err = ten.vpc.DeleteSecurityGroupPolicies(req); err != nil {
+				log.Errorf("tencent delete securityGroupRules [%s] failed: %v", req.ToJsonString(), err)
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package tencent
+
+import (
+	"ark-common/constants"
+	"ark-common/resource/navite"
+	"fmt"
+	"sort"
+	"time"
+
+	cbs "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cbs/v20170312"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	monitor "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/monitor/v20180724"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	cvmNamespace  = "QCE/CVM"
+	diskNamespace = "QCE/BLOCK_STORAGE"
+)
+
+// tencentMetric 云监控指标名, scale用于将数值换算为统一的单位
+type tencentMetric struct {
+	namespace string
+	name      string
+	scale     float64
+}
+
+var metricMapping = map[string]tencentMetric{
+	constants.MetricCPUUsage:    {cvmNamespace, "CpuUsage", 1},
+	constants.MetricMemoryUsage: {cvmNamespace, "MemUsage", 1},
+	constants.MetricDiskRead:    {diskNamespace, "DiskReadTraffic", 1024}, // KB/s
+	constants.MetricDiskWrite:   {diskNamespace, "DiskWriteTraffic", 1024},
+	constants.MetricNetIn:       {cvmNamespace, "WanIntraffic", 1000 * 1000 / 8}, // Mbps
+	constants.MetricNetOut:      {cvmNamespace, "WanOuttraffic", 1000 * 1000 / 8},
+}
+
+// TencentMonitor 腾讯云云监控驱动
+type TencentMonitor struct {
+	monitor *monitor.Client
+	cbs     *cbs.Client
+	account *navite.CloudAccount
+}
+
+// NewTencentMonitorPlugin 初始化腾讯云云监控驱动
+func NewTencentMonitorPlugin(ac *navite.CloudAccount) *TencentMonitor {
+	credential := common.NewCredential(ac.AccessKey, ac.GetSK())
+	cpf := profile.NewClientProfile()
+	cpf.HttpProfile.Endpoint = "monitor.tencentcloudapi.com"
+	mc, err := monitor.NewClient(credential, ac.RunRegionID, cpf)
+	if err != nil {
+		log.Errorf("initialize monitor client failed: %v", err)
+	}
+	cpf = profile.NewClientProfile()
+	cpf.HttpProfile.Endpoint = "cbs.tencentcloudapi.com"
+	cc, err := cbs.NewClient(credential, ac.RunRegionID, cpf)
+	if err != nil {
+		log.Errorf("initialize cbs client failed: %v", err)
+	}
+	return &TencentMonitor{
+		monitor: mc,
+		cbs:     cc,
+		account: ac,
+	}
+}
+
+// GetInstanceMetric 获取实例在时间段内的监控数据
+//
+// * 磁盘读写是云硬盘维度的指标, 这里返回实例挂载的所有云硬盘之和
+func (ten *TencentMonitor) GetInstanceMetric(instance *navite.Instance, metricName string, startTime, endTime time.Time) (points []*navite.MetricPoint, err error) {
+	metric, ok := metricMapping[metricName]
+	if !ok {
+		return nil, fmt.Errorf("tencent not support metric %s", metricName)
+	}
+	dimensionName := "InstanceId"
+	dimensionValues := []string{instance.InstanceID}
+	if metric.namespace == diskNamespace {
+		dimensionName = "diskId"
+		if dimensionValues, err = ten.getAttachedDiskIDs(instance.InstanceID); err != nil {
+			return nil, err
+		}
+	}
+	if len(dimensionValues) == 0 {
+		return
+	}
+
+	req := monitor.NewGetMonitorDataRequest()
+	period := uint64(60)
+	start := startTime.Format(time.RFC3339)
+	end := endTime.Format(time.RFC3339)
+	req.Namespace = &metric.namespace
+	req.MetricName = &metric.name
+	req.Period = &period
+	req.StartTime = &start
+	req.EndTime = &end
+	for i := range dimensionValues {
+		req.Instances = append(req.Instances, &monitor.Instance{
+			Dimensions: []*monitor.Dimension{
+				&monitor.Dimension{
+					Name:  &dimensionName,
+					Value: &dimensionValues[i],
+				},
+			},
+		})
+	}
+	resp, err := ten.monitor.GetMonitorData(req)
+	if err != nil {
+		log.Errorf("tencent get monitor data [%s] failed: %v", req.ToJsonString(), err)
+		return nil, err
+	}
+
+	// 多个维度的数据按时间点求和
+	sums := map[int64]float64{}
+	for _, dp := range resp.Response.DataPoints {
+		for idx, ts := range dp.Timestamps {
+			if idx >= len(dp.Values) || dp.Values[idx] == nil {
+				continue
+			}
+			sums[int64(*ts)] += *dp.Values[idx]
+		}
+	}
+	for ts, value := range sums {
+		value = value * metric.scale
+		points = append(points, &navite.MetricPoint{
+			CloudName:  constants.Tencent,
+			AccountID:  ten.account.AccountID(),
+			RegionID:   instance.RegionID,
+			InstanceID: instance.InstanceID,
+			MetricName: metricName,
+			Timestamp:  time.Unix(ts, 0),
+			Average:    value,
+			Maximum:    value,
+			Minimum:    value,
+		})
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Timestamp.Before(points[j].Timestamp)
+	})
+	return points, nil
+}
+
+func (ten *TencentMonitor) getAttachedDiskIDs(instanceID string) (diskIDList []string, err error) {
+	req := cbs.NewDescribeDisksRequest()
+	filterName := "instance-id"
+	req.Filters = []*cbs.Filter{
+		&cbs.Filter{
+			Name:   &filterName,
+			Values: []*string{&instanceID},
+		},
+	}
+	resp, err := ten.cbs.DescribeDisks(req)
+	if err != nil {
+		log.Errorf("tencent describe disks [%s] failed: %v", req.ToJsonString(), err)
+		return nil, err
+	}
+	for _, res := range resp.Response.DiskSet {
+		diskIDList = append(diskIDList, *res.DiskId)
+	}
+	return diskIDList, nil
+}
@@ -0,0 +1,117 @@
+package tencent
+
+import (
+	"ark-common/constants"
+	"ark-common/param"
+	"ark-common/resource/navite"
+	"fmt"
+	"strings"
+
+	cbs "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cbs/v20170312"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const prePaid = "PREPAID"
+
+// InquiryInstancePrice 创建实例询价
+//
+// * RunInstance 默认为按小时后付费, 这里返回的是按小时计价的价格
+func (ten *TencentResource) InquiryInstancePrice(instance *param.RunInstanceParam) (price *navite.Price, err error) {
+	req := cvm.NewInquiryPriceRunInstancesRequest()
+	req.Placement = &cvm.Placement{
+		Zone: &instance.ZoneID,
+	}
+	req.ImageId = &instance.ImageID
+	req.InstanceType = &instance.InstanceType
+	if instance.DiskSize != 0 {
+		dsize := int64(instance.DiskSize)
+		req.DataDisks = []*cvm.DataDisk{
+			&cvm.DataDisk{
+				DiskSize: &dsize,
+				DiskType: &instance.DiskType,
+			},
+		}
+	}
+	instanceCount := int64(instance.Numbers)
+	if instanceCount <= 0 {
+		instanceCount = 1
+	}
+	req.InstanceCount = &instanceCount
+	resp, err := ten.cvm.InquiryPriceRunInstances(req)
+	if err != nil {
+		log.Errorf("tencent inquiry instance price [%s] failed: %v", req.ToJsonString(), err)
+		return nil, err
+	}
+	if resp.Response.Price == nil || resp.Response.Price.InstancePrice == nil {
+		return nil, fmt.Errorf("tencent inquiry instance price [%s] returned no price", req.ToJsonString())
+	}
+	p := resp.Response.Price.InstancePrice
+	price = &navite.Price{
+		CloudName:     constants.Tencent,
+		ResourceType:  constants.PriceResourceInstance,
+		Currency:      constants.CurrencyCNY,
+		PriceUnit:     constants.PriceUnitHour,
+		OriginalPrice: float64Value(p.UnitPrice),
+		DiscountPrice: float64Value(p.UnitPriceDiscount),
+	}
+	return price, nil
+}
+
+// InquiryDiskPrice 创建云盘询价
+func (ten *TencentResource) InquiryDiskPrice(disk *navite.Disk) (price *navite.Price, err error) {
+	req := cbs.NewInquiryPriceCreateDisksRequest()
+	chargeType := strings.ToUpper(disk.ChargeType)
+	diskType := strings.ToUpper(disk.DiskType)
+	size := uint64(disk.DiskSize)
+	diskCount := uint64(1)
+	req.DiskType = &diskType
+	req.DiskChargeType = &chargeType
+	req.DiskSize = &size
+	req.DiskCount = &diskCount
+	if chargeType == prePaid {
+		period := uint64(1)
+		req.DiskChargePrepaid = &cbs.DiskChargePrepaid{
+			Period: &period,
+		}
+	}
+	resp, err := ten.cbs.InquiryPriceCreateDisks(req)
+	if err != nil {
+		log.Errorf("tencent inquiry disk price [%s] failed: %v", req.ToJsonString(), err)
+		return nil, err
+	}
+	if resp.Response.DiskPrice == nil {
+		return nil, fmt.Errorf("tencent inquiry disk price [%s] returned no price", req.ToJsonString())
+	}
+	p := resp.Response.DiskPrice
+	price = &navite.Price{
+		CloudName:    constants.Tencent,
+		ResourceType: constants.PriceResourceDisk,
+		Currency:     constants.CurrencyCNY,
+	}
+	if chargeType == prePaid {
+		price.PriceUnit = constants.PriceUnitMonth
+		price.OriginalPrice = float64Value(p.OriginalPrice)
+		price.DiscountPrice = float64Value(p.DiscountPrice)
+	} else {
+		price.PriceUnit = constants.PriceUnitHour
+		price.OriginalPrice = float64Value(p.UnitPrice)
+		price.DiscountPrice = float64Value(p.UnitPriceDiscount)
+	}
+	return price, nil
+}
+
+// InquiryEIPPrice 申请弹性公网IP询价
+//
+// * 腾讯云没有弹性公网IP的询价接口
+func (ten *TencentResource) InquiryEIPPrice(eip *navite.Eip) (price *navite.Price, err error) {
+	return nil, fmt.Errorf("tencent not support inquiry eip price")
+}
+
+func float64Value(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
@@ -0,0 +1,108 @@
+package tencent
+
+import (
+	"ark-common/constants"
+	"ark-common/resource/navite"
+	"fmt"
+	"time"
+
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// GetQuotaList 获取账号在当前地域的配额
+//
+// * 腾讯云按量实例的配额是按可用区的实例数计算的, 不是vCPU数
+func (ten *TencentResource) GetQuotaList() (quotaList []*navite.Quota) {
+	ten.syncErr = nil
+	quotaList = append(quotaList, ten.getInstanceQuotaList()...)
+	quotaList = append(quotaList, ten.getEipQuotaList()...)
+	quotaList = append(quotaList, ten.getSecurityGroupQuotaList()...)
+	return quotaList
+}
+
+func (ten *TencentResource) newQuota(quotaName, rawName string) *navite.Quota {
+	return &navite.Quota{
+		CloudName:  constants.Tencent,
+		AccountID:  ten.account.AccountID(),
+		RegionID:   ten.account.RunRegionID,
+		QuotaName:  quotaName,
+		RawName:    rawName,
+		SyncedTime: time.Now(),
+	}
+}
+
+func (ten *TencentResource) getInstanceQuotaList() (quotaList []*navite.Quota) {
+	req := cvm.NewDescribeAccountQuotaRequest()
+	resp, err := ten.cvm.DescribeAccountQuota(req)
+	if err != nil {
+		log.Errorf("tencent describe account quota failed: %v", err)
+		ten.syncErr = err
+		return
+	}
+	overview := resp.Response.AccountQuotaOverview
+	if overview == nil || overview.AccountQuota == nil {
+		ten.syncErr = fmt.Errorf("tencent describe account quota returned no quota")
+		return
+	}
+	for _, res := range overview.AccountQuota.PostPaidQuotaSet {
+		if res.Zone == nil || res.TotalQuota == nil || res.UsedQuota == nil {
+			log.Warnf("tencent postPaidQuota [%s] is incomplete, skip it", res.ToJsonString())
+			continue
+		}
+		quota := ten.newQuota(constants.QuotaInstanceCount, "PostPaidQuota")
+		quota.ZoneID = *res.Zone
+		quota.Limit = int64(*res.TotalQuota)
+		quota.Used = int64(*res.UsedQuota)
+		quotaList = append(quotaList, quota)
+	}
+	return quotaList
+}
+
+func (ten *TencentResource) getEipQuotaList() (quotaList []*navite.Quota) {
+	req := vpc.NewDescribeAddressQuotaRequest()
+	resp, err := ten.vpc.DescribeAddressQuota(req)
+	if err != nil {
+		log.Errorf("tencent describe address quota failed: %v", err)
+		ten.syncErr = err
+		return
+	}
+	for _, res := range resp.Response.QuotaSet {
+		if res.QuotaId == nil || *res.QuotaId != "TOTAL_EIP_QUOTA" {
+			continue
+		}
+		if res.QuotaLimit == nil || res.QuotaCurrent == nil {
+			log.Warnf("tencent address quota [%s] is incomplete, skip it", res.ToJsonString())
+			continue
+		}
+		quota := ten.newQuota(constants.QuotaEip, *res.QuotaId)
+		quota.Limit = *res.QuotaLimit
+		quota.Used = *res.QuotaCurrent
+		quotaList = append(quotaList, quota)
+	}
+	return quotaList
+}
+
+// getSecurityGroupQuotaList 安全组配额
+//
+// * 接口只返回上限, 不返回已使用量
+func (ten *TencentResource) getSecurityGroupQuotaList() (quotaList []*navite.Quota) {
+	req := vpc.NewDescribeSecurityGroupLimitsRequest()
+	resp, err := ten.vpc.DescribeSecurityGroupLimits(req)
+	if err != nil {
+		log.Errorf("tencent describe securityGroup limits failed: %v", err)
+		ten.syncErr = err
+		return
+	}
+	limits := resp.Response.SecurityGroupLimitSet
+	if limits == nil || limits.SecurityGroupLimit == nil {
+		ten.syncErr = fmt.Errorf("tencent describe securityGroup limits returned no limit")
+		return
+	}
+	quota := ten.newQuota(constants.QuotaSecurityGroup, "SecurityGroupLimit")
+	quota.Limit = int64(*limits.SecurityGroupLimit)
+	quotaList = append(quotaList, quota)
+	return quotaList
+}
@@ -0,0 +1,127 @@
+package tencent
+
+import (
+	"ark-common/constants"
+	"ark-common/resource/navite"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var protocols = map[string]navite.RuleProtocol{
+	"TCP":    navite.ProtocolTCP,
+	"UDP":    navite.ProtocolUDP,
+	"ICMP":   navite.ProtocolICMP,
+	"ICMPV6": navite.ProtocolICMPv6,
+	"GRE":    navite.ProtocolGRE,
+	"ALL":    navite.ProtocolAll,
+}
+
+var protocolNames = map[navite.RuleProtocol]string{
+	navite.ProtocolTCP:    "TCP",
+	navite.ProtocolUDP:    "UDP",
+	navite.ProtocolICMP:   "ICMP",
+	navite.ProtocolICMPv6: "ICMPv6",
+	navite.ProtocolGRE:    "GRE",
+	navite.ProtocolAll:    "ALL",
+}
+
+// ToCanonicalRule 将腾讯云的安全组规则转换为统一的规则
+//
+// * Port 为 ALL、单个端口或者 ?-? 格式, 不支持逗号分隔的多个端口
+// * Priority 为规则在同方向规则中的序号, 越小越优先
+func ToCanonicalRule(rule *navite.SecurityGroupRule) (c *navite.CanonicalRule, err error) {
+	protocol, ok := protocols[strings.ToUpper(rule.Protocol)]
+	if !ok {
+		return nil, fmt.Errorf("tencent unknown protocol %s", rule.Protocol)
+	}
+	c = &navite.CanonicalRule{
+		Direction:   strings.ToLower(rule.Direction),
+		Protocol:    protocol,
+		Action:      navite.RuleAction(strings.ToUpper(rule.Action)),
+		Description: rule.Description,
+	}
+	switch c.Direction {
+	case constants.FlowIngress:
+		c.CidrIP = rule.SourceCidrIP
+	case constants.FlowEgress:
+		c.CidrIP = rule.DestCidrIP
+	default:
+		return nil, fmt.Errorf("tencent unknown direction %s", rule.Direction)
+	}
+	if c.CidrIP == "" {
+		return nil, fmt.Errorf("tencent rule without cidr is not supported")
+	}
+	if c.Action != navite.ActionAccept && c.Action != navite.ActionDrop {
+		return nil, fmt.Errorf("tencent unknown action %s", rule.Action)
+	}
+	if rule.Priority != "" {
+		if c.Priority, err = strconv.Atoi(rule.Priority); err != nil {
+			return nil, fmt.Errorf("tencent invalid priority %s", rule.Priority)
+		}
+	}
+
+	port := strings.ToUpper(strings.TrimSpace(rule.PortRange))
+	switch {
+	case port == "" || port == "ALL":
+		c.PortFrom, c.PortTo = navite.PortAll, navite.PortAll
+	case strings.Contains(port, ","):
+		return nil, fmt.Errorf("tencent port list %s is not supported", rule.PortRange)
+	case strings.Contains(port, "-"):
+		ports := strings.SplitN(port, "-", 2)
+		if c.PortFrom, err = strconv.Atoi(ports[0]); err != nil {
+			return nil, fmt.Errorf("tencent invalid port %s", rule.PortRange)
+		}
+		if c.PortTo, err = strconv.Atoi(ports[1]); err != nil {
+			return nil, fmt.Errorf("tencent invalid port %s", rule.PortRange)
+		}
+	default:
+		if c.PortFrom, err = strconv.Atoi(port); err != nil {
+			return nil, fmt.Errorf("tencent invalid port %s", rule.PortRange)
+		}
+		c.PortTo = c.PortFrom
+	}
+	if c.AllPorts() {
+		c.PortFrom, c.PortTo = navite.PortAll, navite.PortAll
+	}
+	return c, nil
+}
+
+// FromCanonicalRule 将统一的规则转换为腾讯云的安全组规则
+//
+// * Protocol、Action 为大写
+func FromCanonicalRule(groupID string, c *navite.CanonicalRule) (rule *navite.SecurityGroupRule, err error) {
+	protocol, ok := protocolNames[c.Protocol]
+	if !ok {
+		return nil, fmt.Errorf("tencent not support protocol %s", c.Protocol)
+	}
+	rule = &navite.SecurityGroupRule{
+		CloudName:   constants.Tencent,
+		GroupID:     groupID,
+		Direction:   c.Direction,
+		Protocol:    protocol,
+		Priority:    strconv.Itoa(c.Priority),
+		Action:      strings.ToUpper(string(c.Action)),
+		Description: c.Description,
+	}
+	switch c.Direction {
+	case constants.FlowIngress:
+		rule.SourceCidrIP = c.CidrIP
+	case constants.FlowEgress:
+		rule.DestCidrIP = c.CidrIP
+	default:
+		return nil, fmt.Errorf("tencent not support direction %s", c.Direction)
+	}
+
+	switch {
+	case c.AllPorts():
+		rule.PortRange = "ALL"
+	case c.Protocol != navite.ProtocolTCP && c.Protocol != navite.ProtocolUDP:
+		return nil, fmt.Errorf("tencent protocol %s only support all ports", c.Protocol)
+	case c.PortFrom == c.PortTo:
+		rule.PortRange = strconv.Itoa(c.PortFrom)
+	default:
+		rule.PortRange = strconv.Itoa(c.PortFrom) + "-" + strconv.Itoa(c.PortTo)
+	}
+	return rule, nil
+}
@@ -0,0 +1,76 @@
+package tencent
+
+import (
+	"ark-common/constants"
+	"ark-common/resource/navite"
+)
+
+var instanceStatus = map[string]string{
+	"PENDING":       constants.InstanceStatusPending,
+	"LAUNCH_FAILED": constants.InstanceStatusFailed,
+	"STARTING":      constants.InstanceStatusStarting,
+	"RUNNING":       constants.InstanceStatusRunning,
+	"STOPPING":      constants.InstanceStatusStopping,
+	"STOPPED":       constants.InstanceStatusStopped,
+	"REBOOTING":     constants.InstanceStatusRebooting,
+	"SHUTDOWN":      constants.InstanceStatusStopped, // 停止待销毁
+	"TERMINATING":   constants.InstanceStatusTerminating,
+}
+
+var diskStatus = map[string]string{
+	"UNATTACHED":  constants.DiskStatusAvailable,
+	"ATTACHING":   constants.DiskStatusAttaching,
+	"ATTACHED":    constants.DiskStatusInUse,
+	"DETACHING":   constants.DiskStatusDetaching,
+	"EXPANDING":   constants.DiskStatusResizing,
+	"ROLLBACKING": constants.DiskStatusInUse,
+	"TORECYCLE":   constants.DiskStatusDeleting,
+	"DUMPING":     constants.DiskStatusInUse,
+}
+
+var eipStatus = map[string]string{
+	"CREATING":  constants.EipStatusCreating,
+	"BINDING":   constants.EipStatusBinding,
+	"BIND":      constants.EipStatusInUse,
+	"BIND_ENI":  constants.EipStatusInUse,
+	"UNBINDING": constants.EipStatusUnbinding,
+	"UNBIND":    constants.EipStatusAvailable,
+	"OFFLINING": constants.EipStatusReleasing,
+}
+
+var chargeType = map[string]string{
+	"PREPAID":          constants.ChargeTypePrePaid,
+	"POSTPAID_BY_HOUR": constants.ChargeTypePostPaid,
+	"SPOTPAID":         constants.ChargeTypeSpot,
+	"CDHPAID":          constants.ChargeTypePrePaid, // 专用宿主机上的实例, 宿主机包年包月
+}
+
+var diskCategory = map[string]string{
+	"CLOUD_BASIC":   constants.DiskCategoryBasic,
+	"CLOUD_PREMIUM": constants.DiskCategoryEfficiency,
+	"CLOUD_SSD":     constants.DiskCategorySSD,
+	"CLOUD_HSSD":    constants.DiskCategoryESSD,
+	"CLOUD_TSSD":    constants.DiskCategoryESSD,
+	"LOCAL_BASIC":   constants.DiskCategoryLocal,
+	"LOCAL_SSD":     constants.DiskCategoryLocal,
+}
+
+// UnifyInstance 设置实例的统一状态和付费方式
+func UnifyInstance(instance *navite.Instance) {
+	instance.UnifiedStatus = navite.UnifiedValue(instanceStatus, instance.Status)
+	instance.UnifiedChargeType = navite.UnifiedValue(chargeType, instance.ChargeType)
+}
+
+// UnifyDisk 设置磁盘的统一状态、付费方式和类型
+func UnifyDisk(disk *navite.Disk) {
+	disk.UnifiedStatus = navite.UnifiedValue(diskStatus, disk.Status)
+	disk.UnifiedChargeType = navite.UnifiedValue(chargeType, disk.ChargeType)
+	disk.UnifiedDiskType = navite.UnifiedValue(diskCategory, disk.DiskType)
+}
+
+// unifyEip 设置弹性公网IP的统一状态
+//
+// * 弹性公网IP的计费方式在账号维度, 接口不返回付费方式
+func unifyEip(eip *navite.Eip) {
+	eip.UnifiedStatus = navite.UnifiedValue(eipStatus, eip.AddressStatus)
+}
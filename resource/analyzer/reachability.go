@@ -0,0 +1,304 @@
+package analyzer
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/constants"
+	"ark-common/resource/manage"
+	"ark-common/resource/navite"
+	"ark-common/utils/network"
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Endpoint 可达性分析的一端, 为实例或网段
+type Endpoint struct {
+	Instance   *navite.Instance
+	CidrIP     string                                 // Instance为空时使用
+	VPCCidr    string                                 // 实例所在VPC的网段
+	SubnetID   string                                 // 实例内网IP所在的子网, 为空代表没有同步到子网
+	SubnetCidr string                                 // 实例所在子网的网段
+	GroupRules map[string][]*navite.SecurityGroupRule // 实例绑定的安全组规则, key为安全组ID
+}
+
+// Name 返回端点的标识
+func (e *Endpoint) Name() string {
+	if e.Instance != nil {
+		return e.Instance.InstanceID
+	}
+	return e.CidrIP
+}
+
+// address 返回端点在通信中使用的地址
+func (e *Endpoint) address(public bool) string {
+	if e.Instance == nil {
+		return e.CidrIP
+	}
+	if public {
+		return e.Instance.EipAddress
+	}
+	return e.Instance.InnerIPAddress
+}
+
+// inVPC 网段是否在端点所在的VPC内
+func (e *Endpoint) inVPC(cidr string) bool {
+	return e.VPCCidr != "" && network.Contains(e.VPCCidr, cidr)
+}
+
+// evaluateSubnet 检查实例的内网IP是否在所属子网内, 且子网在实例的VPC内
+//
+// * 没有同步到子网时返回nil, 不做检查
+func (e *Endpoint) evaluateSubnet(peer string) *navite.ReachabilityStep {
+	if e.Instance == nil || e.SubnetCidr == "" {
+		return nil
+	}
+	step := &navite.ReachabilityStep{Stage: navite.StageSubnet}
+	switch {
+	case !network.ContainsIP(e.SubnetCidr, e.Instance.InnerIPAddress):
+		step.Reason = fmt.Sprintf("实例 %s 的内网IP %s 不在子网 %s(%s) 内", e.Instance.InstanceID, e.Instance.InnerIPAddress, e.SubnetID, e.SubnetCidr)
+	case e.VPCCidr != "" && !network.Contains(e.VPCCidr, e.SubnetCidr):
+		step.Reason = fmt.Sprintf("实例 %s 的子网 %s(%s) 不在VPC网段 %s 内", e.Instance.InstanceID, e.SubnetID, e.SubnetCidr, e.VPCCidr)
+	default:
+		step.Allowed = true
+		step.Reason = fmt.Sprintf("实例 %s 在子网 %s(%s) 内", e.Instance.InstanceID, e.SubnetID, e.SubnetCidr)
+		if peer != "" && network.Contains(e.SubnetCidr, peer) {
+			step.Reason += fmt.Sprintf(", %s 在同一子网内", peer)
+		}
+	}
+	return step
+}
+
+// matchRule 规则是否匹配与peer之间的流量
+//
+// * peer为网段时要求规则的网段完全包含peer
+func matchRule(c *navite.CanonicalRule, direction string, protocol navite.RuleProtocol, port int, peer string) bool {
+	if c.Direction != direction {
+		return false
+	}
+	if c.Protocol != navite.ProtocolAll && c.Protocol != protocol {
+		return false
+	}
+	if (protocol == navite.ProtocolTCP || protocol == navite.ProtocolUDP) && !c.MatchPort(port) {
+		return false
+	}
+	return network.Contains(c.CidrIP, peer)
+}
+
+// evaluateSecurityGroups 按生效顺序匹配实例的安全组规则, 返回决定结果的检查阶段
+//
+// * 阿里云将实例所有安全组的规则合并后按优先级匹配
+// * 腾讯云按实例绑定安全组的顺序逐个匹配, 组内按规则序号匹配
+// * 没有匹配的规则时拒绝, 阿里云安全组默认允许所有出方向的流量, 出方向没有匹配的规则时允许
+func evaluateSecurityGroups(instance *navite.Instance, groupRules map[string][]*navite.SecurityGroupRule, direction string, protocol navite.RuleProtocol, port int, peer string) *navite.ReachabilityStep {
+	step := &navite.ReachabilityStep{Stage: direction}
+	merge := instance.CloudName != constants.Tencent
+	var batches [][]*parsedRule
+	var merged []*parsedRule
+	for _, groupID := range instance.SecurityGroupList {
+		parsed, _ := parseRules(groupRules[groupID])
+		if merge {
+			merged = append(merged, parsed...)
+		} else {
+			batches = append(batches, parsed)
+		}
+	}
+	if merge {
+		sortRules(merged)
+		batches = [][]*parsedRule{merged}
+	}
+
+	for _, batch := range batches {
+		for _, p := range batch {
+			if !matchRule(p.canonical, direction, protocol, port, peer) {
+				continue
+			}
+			step.Allowed = p.canonical.Action == navite.ActionAccept
+			step.GroupID = p.raw.GroupID
+			step.Rule = p.raw
+			verb := "拒绝"
+			if step.Allowed {
+				verb = "允许"
+			}
+			step.Reason = fmt.Sprintf("安全组 %s 的规则 %s %s", p.raw.GroupID, p.canonical.Key(), verb)
+			return step
+		}
+	}
+	if merge && direction == constants.FlowEgress {
+		step.Allowed = true
+		step.Reason = fmt.Sprintf("实例 %s 的安全组没有匹配 %s 的出方向规则, 默认允许", instance.InstanceID, peer)
+		return step
+	}
+	step.Reason = fmt.Sprintf("实例 %s 的安全组没有匹配 %s 的规则, 默认拒绝", instance.InstanceID, peer)
+	return step
+}
+
+// EvaluateReachability 判断src到dst指定协议和端口的流量是否可达
+//
+// * 两端在同一VPC内时走内网, 否则走公网, 走公网时实例需要绑定弹性公网IP
+// * 依次检查网络路径、走内网时两端实例所在的子网、源实例安全组出站和目标实例安全组入站, 任一阶段拒绝即不可达
+// * NAT网关、路由表和网络ACL不在检查范围内
+func EvaluateReachability(src, dst *Endpoint, protocol navite.RuleProtocol, port int) (result *navite.ReachabilityResult) {
+	result = &navite.ReachabilityResult{
+		Source:      src.Name(),
+		Destination: dst.Name(),
+		Protocol:    protocol,
+		Port:        port,
+	}
+	pathStep := &navite.ReachabilityStep{Stage: navite.StageNetwork, Allowed: true}
+	result.Steps = append(result.Steps, pathStep)
+
+	switch {
+	case src.Instance != nil && dst.Instance != nil:
+		if src.Instance.VPCID != "" && src.Instance.VPCID == dst.Instance.VPCID {
+			pathStep.Reason = fmt.Sprintf("两端在同一VPC %s 内, 走内网", src.Instance.VPCID)
+		} else {
+			result.Public = true
+		}
+	case src.Instance != nil:
+		if src.inVPC(dst.CidrIP) {
+			pathStep.Reason = fmt.Sprintf("%s 在源实例的VPC %s 内, 走内网", dst.CidrIP, src.VPCCidr)
+		} else {
+			result.Public = true
+		}
+	case dst.Instance != nil:
+		if dst.inVPC(src.CidrIP) {
+			pathStep.Reason = fmt.Sprintf("%s 在目标实例的VPC %s 内, 走内网", src.CidrIP, dst.VPCCidr)
+		} else {
+			result.Public = true
+		}
+	default:
+		pathStep.Allowed = false
+		pathStep.Reason = "至少有一端需要是实例"
+		return result
+	}
+	if result.Public {
+		pathStep.Reason = "两端不在同一VPC内, 走公网"
+		for _, e := range []*Endpoint{src, dst} {
+			if e.Instance != nil && e.Instance.EipAddress == "" {
+				pathStep.Allowed = false
+				pathStep.Reason = fmt.Sprintf("两端不在同一VPC内, 实例 %s 没有公网IP", e.Instance.InstanceID)
+				return result
+			}
+		}
+	}
+
+	if !result.Public {
+		for _, pair := range [][2]*Endpoint{{src, dst}, {dst, src}} {
+			step := pair[0].evaluateSubnet(pair[1].address(false))
+			if step == nil {
+				continue
+			}
+			result.Steps = append(result.Steps, step)
+			if !step.Allowed {
+				return result
+			}
+		}
+	}
+
+	if src.Instance != nil {
+		step := evaluateSecurityGroups(src.Instance, src.GroupRules, constants.FlowEgress, protocol, port, dst.address(result.Public))
+		result.Steps = append(result.Steps, step)
+		if !step.Allowed {
+			return result
+		}
+	}
+	if dst.Instance != nil {
+		step := evaluateSecurityGroups(dst.Instance, dst.GroupRules, constants.FlowIngress, protocol, port, src.address(result.Public))
+		result.Steps = append(result.Steps, step)
+		if !step.Allowed {
+			return result
+		}
+	}
+	result.Reachable = true
+	return result
+}
+
+// LoadInstanceEndpoint 从mongo中加载实例及其VPC网段、子网和安全组规则
+//
+// * 同步时已标记为删除的资源视为不存在
+func LoadInstanceEndpoint(rbd *mgo.Client, instanceID string) (endpoint *Endpoint, err error) {
+	instance := &navite.Instance{}
+	filter := bson.M{
+		"instanceId": instanceID,
+		"deleted":    bson.M{"$ne": true},
+	}
+	if err = rbd.Table(navite.InstanceTable).QueryOne(filter, instance, nil); err != nil {
+		log.Errorf("filter [%v] instance failed: %v", filter, err)
+		return nil, err
+	}
+	endpoint = &Endpoint{
+		Instance:   instance,
+		GroupRules: map[string][]*navite.SecurityGroupRule{},
+	}
+	if instance.VPCID != "" {
+		vpc := &navite.VPC{}
+		filter = bson.M{
+			"accountId": instance.AccountID,
+			"vpcId":     instance.VPCID,
+			"deleted":   bson.M{"$ne": true},
+		}
+		if e := rbd.Table(navite.VPCTable).QueryOne(filter, vpc, nil); e != nil {
+			log.Warnf("filter [%v] vpc failed: %v", filter, e)
+		} else {
+			endpoint.VPCCidr = vpc.CidrBlock
+		}
+		if subnet := findInstanceSubnet(rbd, instance); subnet != nil {
+			endpoint.SubnetID = subnet.SubnetID
+			endpoint.SubnetCidr = subnet.CidrBlock
+		}
+	}
+	for _, groupID := range instance.SecurityGroupList {
+		endpoint.GroupRules[groupID] = manage.ListSecurityGroupRules(rbd, groupID)
+	}
+	return endpoint, nil
+}
+
+// findInstanceSubnet 在实例的VPC中查找包含实例内网IP的子网
+//
+// * 实例没有记录子网ID, 按内网IP匹配, 找不到时返回nil
+func findInstanceSubnet(rbd *mgo.Client, instance *navite.Instance) *navite.Subnet {
+	filter := bson.M{
+		"accountId": instance.AccountID,
+		"vpcId":     instance.VPCID,
+		"deleted":   bson.M{"$ne": true},
+	}
+	subnetList := []*navite.Subnet{}
+	mctx := context.Background()
+	cur, err := rbd.Table(navite.SubnetTable).Query(filter, 0, 0, nil)
+	if err != nil {
+		log.Warnf("filter [%v] subnets failed: %v", filter, err)
+		return nil
+	}
+	defer cur.Close(mctx)
+	if err = cur.All(mctx, &subnetList); err != nil {
+		log.Errorf("decord mgo document failed: %v", err)
+		return nil
+	}
+	for _, subnet := range subnetList {
+		if network.ContainsIP(subnet.CidrBlock, instance.InnerIPAddress) {
+			return subnet
+		}
+	}
+	return nil
+}
+
+// CheckReachability 使用mongo中同步的数据判断流量是否可达
+//
+// * srcInstanceID/dstInstanceID 为空时分别使用srcCidr/dstCidr
+func CheckReachability(rbd *mgo.Client, srcInstanceID, srcCidr, dstInstanceID, dstCidr string, protocol navite.RuleProtocol, port int) (result *navite.ReachabilityResult, err error) {
+	src := &Endpoint{CidrIP: srcCidr}
+	dst := &Endpoint{CidrIP: dstCidr}
+	if srcInstanceID != "" {
+		if src, err = LoadInstanceEndpoint(rbd, srcInstanceID); err != nil {
+			return nil, err
+		}
+	}
+	if dstInstanceID != "" {
+		if dst, err = LoadInstanceEndpoint(rbd, dstInstanceID); err != nil {
+			return nil, err
+		}
+	}
+	return EvaluateReachability(src, dst, protocol, port), nil
+}
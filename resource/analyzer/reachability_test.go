@@ -0,0 +1,106 @@
+package analyzer_test
+
+import (
+	"ark-common/constants"
+	"ark-common/resource/analyzer"
+	"ark-common/resource/navite"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEvaluateReachability(t *testing.T) {
+	egress := &navite.SecurityGroupRule{
+		CloudName:  constants.Aliyun,
+		GroupID:    "sg-a",
+		Direction:  constants.FlowEgress,
+		DestCidrIP: "0.0.0.0/0",
+		PortRange:  "-1/-1",
+		Protocol:   "ALL",
+		Priority:   "1",
+		Action:     "accept",
+	}
+	src := &analyzer.Endpoint{
+		Instance: &navite.Instance{
+			CloudName:         constants.Aliyun,
+			InstanceID:        "i-a",
+			VPCID:             "vpc-1",
+			InnerIPAddress:    "10.0.1.5",
+			SecurityGroupList: []string{"sg-a"},
+		},
+		VPCCidr:    "10.0.0.0/16",
+		GroupRules: map[string][]*navite.SecurityGroupRule{"sg-a": {egress}},
+	}
+	allow := newRule("10.0.0.0/16", "80/80", "TCP", "10", "accept")
+	allow.GroupID = "sg-b"
+	deny := newRule("10.0.1.0/24", "80/80", "TCP", "5", "drop")
+	deny.GroupID = "sg-c"
+	dst := &analyzer.Endpoint{
+		Instance: &navite.Instance{
+			CloudName:         constants.Aliyun,
+			InstanceID:        "i-b",
+			VPCID:             "vpc-1",
+			InnerIPAddress:    "10.0.2.9",
+			SecurityGroupList: []string{"sg-b"},
+		},
+		VPCCidr:    "10.0.0.0/16",
+		GroupRules: map[string][]*navite.SecurityGroupRule{"sg-b": {allow}, "sg-c": {deny}},
+	}
+
+	Convey("判断实例之间的流量是否可达", t, func() {
+		Convey("同一VPC内入站规则允许", func() {
+			result := analyzer.EvaluateReachability(src, dst, navite.ProtocolTCP, 80)
+			So(result.Reachable, ShouldBeTrue)
+			So(result.Public, ShouldBeFalse)
+			So(result.Steps[len(result.Steps)-1].Rule, ShouldEqual, allow)
+		})
+		Convey("没有匹配的入站规则", func() {
+			result := analyzer.EvaluateReachability(src, dst, navite.ProtocolTCP, 443)
+			So(result.Reachable, ShouldBeFalse)
+			So(result.Steps[len(result.Steps)-1].Rule, ShouldBeNil)
+		})
+		Convey("优先级更高的拒绝规则生效", func() {
+			dst.Instance.SecurityGroupList = []string{"sg-b", "sg-c"}
+			result := analyzer.EvaluateReachability(src, dst, navite.ProtocolTCP, 80)
+			dst.Instance.SecurityGroupList = []string{"sg-b"}
+			So(result.Reachable, ShouldBeFalse)
+			So(result.Steps[len(result.Steps)-1].GroupID, ShouldEqual, "sg-c")
+		})
+		Convey("两端所在的子网", func() {
+			src.SubnetID, src.SubnetCidr = "vsw-1", "10.0.1.0/24"
+			dst.SubnetID, dst.SubnetCidr = "vsw-2", "10.0.2.0/24"
+			result := analyzer.EvaluateReachability(src, dst, navite.ProtocolTCP, 80)
+			So(result.Reachable, ShouldBeTrue)
+			So(result.Steps[1].Stage, ShouldEqual, navite.StageSubnet)
+			So(result.Steps[2].Stage, ShouldEqual, navite.StageSubnet)
+
+			dst.SubnetCidr = "10.0.3.0/24"
+			result = analyzer.EvaluateReachability(src, dst, navite.ProtocolTCP, 80)
+			So(result.Reachable, ShouldBeFalse)
+			So(result.Steps[len(result.Steps)-1].Stage, ShouldEqual, navite.StageSubnet)
+			src.SubnetID, src.SubnetCidr = "", ""
+			dst.SubnetID, dst.SubnetCidr = "", ""
+		})
+		Convey("阿里云安全组没有出方向规则时默认允许", func() {
+			src.GroupRules = map[string][]*navite.SecurityGroupRule{"sg-a": {}}
+			result := analyzer.EvaluateReachability(src, dst, navite.ProtocolTCP, 80)
+			So(result.Reachable, ShouldBeTrue)
+			So(result.Steps[1].Stage, ShouldEqual, constants.FlowEgress)
+			So(result.Steps[1].Allowed, ShouldBeTrue)
+			So(result.Steps[1].Rule, ShouldBeNil)
+
+			drop := *egress
+			drop.Action = "drop"
+			src.GroupRules = map[string][]*navite.SecurityGroupRule{"sg-a": {&drop}}
+			result = analyzer.EvaluateReachability(src, dst, navite.ProtocolTCP, 80)
+			So(result.Reachable, ShouldBeFalse)
+			So(result.Steps[1].Rule, ShouldEqual, &drop)
+			src.GroupRules = map[string][]*navite.SecurityGroupRule{"sg-a": {egress}}
+		})
+		Convey("公网访问没有公网IP的实例", func() {
+			result := analyzer.EvaluateReachability(&analyzer.Endpoint{CidrIP: "8.8.8.8"}, dst, navite.ProtocolTCP, 80)
+			So(result.Reachable, ShouldBeFalse)
+			So(result.Steps[0].Stage, ShouldEqual, navite.StageNetwork)
+		})
+	})
+}
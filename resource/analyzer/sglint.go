@@ -0,0 +1,199 @@
+package analyzer
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/constants"
+	"ark-common/plugin"
+	"ark-common/resource/manage"
+	"ark-common/resource/navite"
+	"ark-common/utils/network"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// WidePortRange 入站放行的端口数超过该值视为端口范围过大
+const WidePortRange = 1000
+
+// adminPorts 不应该对全网开放的管理/数据库端口
+var adminPorts = []struct {
+	port int
+	name string
+}{
+	{22, "SSH"},
+	{3389, "RDP"},
+	{3306, "MySQL"},
+	{6379, "Redis"},
+}
+
+// parsedRule 原始规则和转换后的统一规则
+type parsedRule struct {
+	raw       *navite.SecurityGroupRule
+	canonical *navite.CanonicalRule
+}
+
+// parseRules 将规则转换为统一的规则并按生效顺序排序, 返回无法转换的规则
+func parseRules(rules []*navite.SecurityGroupRule) (parsed []*parsedRule, failed map[*navite.SecurityGroupRule]error) {
+	failed = map[*navite.SecurityGroupRule]error{}
+	for _, rule := range rules {
+		c, err := plugin.ToCanonicalRule(rule)
+		if err != nil {
+			failed[rule] = err
+			continue
+		}
+		parsed = append(parsed, &parsedRule{raw: rule, canonical: c})
+	}
+	sortRules(parsed)
+	return parsed, failed
+}
+
+// sortRules 按生效顺序排序
+func sortRules(parsed []*parsedRule) {
+	sort.SliceStable(parsed, func(i, j int) bool {
+		return parsed[i].canonical.Before(parsed[j].canonical)
+	})
+}
+
+// covers 规则a匹配的流量是否完全包含规则b匹配的流量
+func covers(a, b *navite.CanonicalRule) bool {
+	if a.Direction != b.Direction {
+		return false
+	}
+	if a.Protocol != navite.ProtocolAll && a.Protocol != b.Protocol {
+		return false
+	}
+	if !a.AllPorts() && (b.AllPorts() || b.PortFrom < a.PortFrom || b.PortTo > a.PortTo) {
+		return false
+	}
+	return network.Contains(a.CidrIP, b.CidrIP)
+}
+
+// portCount 规则包含的端口数
+func portCount(c *navite.CanonicalRule) int {
+	if c.AllPorts() {
+		return 65535
+	}
+	return c.PortTo - c.PortFrom + 1
+}
+
+// LintSecurityGroupRules 检查安全组规则的风险
+//
+// * rules 为安全组下的全部规则, 用于判断重复、覆盖和是否缺少出站规则
+func LintSecurityGroupRules(sg *navite.SecurityGroup, rules []*navite.SecurityGroupRule) (findings []*navite.SecurityGroupFinding) {
+	now := time.Now()
+	report := func(category, severity string, rule *navite.SecurityGroupRule, format string, args ...interface{}) {
+		findings = append(findings, &navite.SecurityGroupFinding{
+			CloudName:   sg.CloudName,
+			AccountID:   sg.AccountID,
+			RegionID:    sg.RegionID,
+			GroupID:     sg.GroupID,
+			GroupName:   sg.GroupName,
+			Category:    category,
+			Severity:    severity,
+			Message:     fmt.Sprintf(format, args...),
+			Rule:        rule,
+			CreatedTime: now,
+		})
+	}
+
+	parsed, failed := parseRules(rules)
+	for _, rule := range rules {
+		if err, ok := failed[rule]; ok {
+			report(constants.LintUnparsableRule, constants.SeverityLow, rule, "规则无法解析: %v", err)
+		}
+	}
+
+	hasEgress := false
+	seen := map[string]bool{}
+	for idx, p := range parsed {
+		c := p.canonical
+		if c.Direction == constants.FlowEgress {
+			hasEgress = true
+		}
+
+		key := c.Key()
+		if seen[key] {
+			report(constants.LintDuplicateRule, constants.SeverityLow, p.raw, "与其他规则重复: %s", key)
+			continue
+		}
+		seen[key] = true
+
+		for _, prev := range parsed[:idx] {
+			if !covers(prev.canonical, c) {
+				continue
+			}
+			if prev.canonical.Action != c.Action {
+				report(constants.LintShadowedRule, constants.SeverityMedium, p.raw,
+					"规则不会生效, 被更优先的规则 %s 覆盖", prev.canonical.Key())
+			} else {
+				report(constants.LintRedundantRule, constants.SeverityLow, p.raw,
+					"规则是冗余的, 已被更优先的规则 %s 包含", prev.canonical.Key())
+			}
+			break
+		}
+
+		if c.Direction != constants.FlowIngress || c.Action != navite.ActionAccept {
+			continue
+		}
+		if c.Protocol == navite.ProtocolAll {
+			severity := constants.SeverityMedium
+			if network.IsAny(c.CidrIP) {
+				severity = constants.SeverityHigh
+			}
+			report(constants.LintAllProtocol, severity, p.raw, "入站放行 %s 的全部协议", c.CidrIP)
+			continue
+		}
+		if c.Protocol != navite.ProtocolTCP && c.Protocol != navite.ProtocolUDP {
+			continue
+		}
+		if network.IsAny(c.CidrIP) && c.Protocol == navite.ProtocolTCP {
+			for _, admin := range adminPorts {
+				if c.MatchPort(admin.port) {
+					report(constants.LintOpenAdminPort, constants.SeverityHigh, p.raw,
+						"%s 端口 %d 对全网开放", admin.name, admin.port)
+				}
+			}
+		}
+		if portCount(c) > WidePortRange {
+			report(constants.LintWidePortRange, constants.SeverityMedium, p.raw,
+				"入站放行的端口范围 %s 过大", c.PortRange())
+		}
+	}
+
+	if !hasEgress {
+		report(constants.LintNoEgressRule, constants.SeverityLow, nil, "安全组没有出站规则")
+	}
+	return findings
+}
+
+// LintSecurityGroups 检查账号在地域下所有安全组的规则, 并用结果替换mongo中已有的风险项
+func LintSecurityGroups(rbd *mgo.Client, accountID, regionID string) (findings []*navite.SecurityGroupFinding, err error) {
+	_, sgList := manage.ListSecurityGroups(rbd, "", accountID, regionID, 0, 0)
+	for _, sg := range sgList {
+		rules := manage.ListSecurityGroupRules(rbd, sg.GroupID)
+		findings = append(findings, LintSecurityGroupRules(sg, rules)...)
+	}
+
+	filter := bson.M{
+		"accountId": accountID,
+		"regionId":  regionID,
+	}
+	if _, err = rbd.Table(navite.SecurityGroupFindingTable).DeleteMany(filter); err != nil {
+		log.Errorf("delete [%v] securityGroupFindings failed: %v", filter, err)
+		return findings, err
+	}
+	if len(findings) == 0 {
+		return findings, nil
+	}
+	documents := make([]interface{}, 0, len(findings))
+	for _, finding := range findings {
+		documents = append(documents, finding)
+	}
+	if _, err = rbd.Table(navite.SecurityGroupFindingTable).InsertMany(documents, nil); err != nil {
+		log.Errorf("insert [%v] securityGroupFindings failed: %v", filter, err)
+	}
+	return findings, err
+}
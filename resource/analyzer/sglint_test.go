@@ -0,0 +1,83 @@
+package analyzer_test
+
+import (
+	"ark-common/constants"
+	"ark-common/resource/analyzer"
+	"ark-common/resource/navite"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newRule(cidr, portRange, protocol, priority, action string) *navite.SecurityGroupRule {
+	return &navite.SecurityGroupRule{
+		CloudName:    constants.Aliyun,
+		GroupID:      "sg-2zefpzvxfxkw5nkflw3k",
+		Direction:    constants.FlowIngress,
+		SourceCidrIP: cidr,
+		PortRange:    portRange,
+		Protocol:     protocol,
+		Priority:     priority,
+		Action:       action,
+	}
+}
+
+func categories(findings []*navite.SecurityGroupFinding) map[string]string {
+	result := map[string]string{}
+	for _, f := range findings {
+		result[f.Category] = f.Severity
+	}
+	return result
+}
+
+func TestLintSecurityGroupRules(t *testing.T) {
+	sg := &navite.SecurityGroup{
+		CloudName: constants.Aliyun,
+		GroupID:   "sg-2zefpzvxfxkw5nkflw3k",
+	}
+	egress := &navite.SecurityGroupRule{
+		CloudName:  constants.Aliyun,
+		GroupID:    "sg-2zefpzvxfxkw5nkflw3k",
+		Direction:  constants.FlowEgress,
+		DestCidrIP: "0.0.0.0/0",
+		PortRange:  "-1/-1",
+		Protocol:   "ALL",
+		Priority:   "1",
+		Action:     "accept",
+	}
+
+	Convey("检查安全组规则的风险", t, func() {
+		Convey("对全网开放SSH端口", func() {
+			rules := []*navite.SecurityGroupRule{egress, newRule("0.0.0.0/0", "22/22", "TCP", "1", "accept")}
+			found := categories(analyzer.LintSecurityGroupRules(sg, rules))
+			So(found[constants.LintOpenAdminPort], ShouldEqual, constants.SeverityHigh)
+			So(found, ShouldNotContainKey, constants.LintNoEgressRule)
+		})
+		Convey("重复的规则", func() {
+			rules := []*navite.SecurityGroupRule{
+				egress,
+				newRule("10.0.0.0/8", "80/80", "TCP", "1", "accept"),
+				newRule("10.0.0.0/8", "80/80", "tcp", "5", "ACCEPT"),
+			}
+			So(categories(analyzer.LintSecurityGroupRules(sg, rules)), ShouldContainKey, constants.LintDuplicateRule)
+		})
+		Convey("被更优先的拒绝规则覆盖", func() {
+			rules := []*navite.SecurityGroupRule{
+				egress,
+				newRule("10.1.0.0/16", "80/80", "TCP", "10", "accept"),
+				newRule("10.0.0.0/8", "1/65535", "TCP", "1", "drop"),
+			}
+			So(categories(analyzer.LintSecurityGroupRules(sg, rules)), ShouldContainKey, constants.LintShadowedRule)
+		})
+		Convey("没有出站规则并且端口范围过大", func() {
+			rules := []*navite.SecurityGroupRule{newRule("10.0.0.0/8", "1000/9000", "UDP", "1", "accept")}
+			found := categories(analyzer.LintSecurityGroupRules(sg, rules))
+			So(found, ShouldContainKey, constants.LintNoEgressRule)
+			So(found, ShouldContainKey, constants.LintWidePortRange)
+		})
+		Convey("无法解析的规则", func() {
+			rules := []*navite.SecurityGroupRule{egress, newRule("0.0.0.0/0", "80,443", "TCP", "1", "accept")}
+			So(categories(analyzer.LintSecurityGroupRules(sg, rules)), ShouldContainKey, constants.LintUnparsableRule)
+		})
+	})
+}
@@ -0,0 +1,223 @@
+package catalog
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/constants"
+	"ark-common/param"
+	"ark-common/resource/navite"
+	"context"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// distributions 发行版的匹配规则, 按顺序匹配, 更具体的名称在前
+var distributions = []struct {
+	pattern      *regexp.Regexp
+	distribution string
+	family       string
+}{
+	{regexp.MustCompile(`alibaba cloud linux|aliyun linux|alinux`), "alinux", "linux"},
+	{regexp.MustCompile(`tencentos|tlinux`), "tencentos", "linux"},
+	{regexp.MustCompile(`centos stream`), "centos-stream", "linux"},
+	{regexp.MustCompile(`centos`), "centos", "linux"},
+	{regexp.MustCompile(`rocky`), "rocky", "linux"},
+	{regexp.MustCompile(`almalinux`), "almalinux", "linux"},
+	{regexp.MustCompile(`ubuntu`), "ubuntu", "linux"},
+	{regexp.MustCompile(`debian`), "debian", "linux"},
+	{regexp.MustCompile(`opensuse`), "opensuse", "linux"},
+	{regexp.MustCompile(`suse|sles`), "suse", "linux"},
+	{regexp.MustCompile(`red ?hat|rhel`), "rhel", "linux"},
+	{regexp.MustCompile(`fedora`), "fedora", "linux"},
+	{regexp.MustCompile(`anolis`), "anolis", "linux"},
+	{regexp.MustCompile(`opencloudos`), "opencloudos", "linux"},
+	{regexp.MustCompile(`freebsd`), "freebsd", "bsd"},
+	{regexp.MustCompile(`windows`), "windows", "windows"},
+}
+
+var (
+	underscoreVersion = regexp.MustCompile(`(\d)_(\d)`)
+	windowsVersion    = regexp.MustCompile(`(20\d\d)( r2)?`)
+	linuxVersion      = regexp.MustCompile(`\d+(\.\d+)*`)
+	armArch           = regexp.MustCompile(`arm64|aarch64|\barm\b`)
+	i386Arch          = regexp.MustCompile(`i386|i686|32位|32 ?bit`)
+)
+
+// ParseOS 从镜像的系统名称、镜像名称和版本中解析操作系统信息, 无法识别发行版时返回nil
+//
+// * 优先使用系统名称, 识别不到时再使用镜像名称, 镜像名称中的 22_04 视为 22.04
+// * 架构从系统名称和镜像名称中识别, 没有标明架构的视为x86_64
+func ParseOS(osName, imageName, imageVersion string) *navite.OSInfo {
+	for _, text := range []string{osName, imageName + " " + imageVersion} {
+		text = strings.ToLower(underscoreVersion.ReplaceAllString(text, "$1.$2"))
+		text = strings.NewReplacer("_", " ", "-", " ").Replace(text)
+		for _, d := range distributions {
+			loc := d.pattern.FindStringIndex(text)
+			if loc == nil {
+				continue
+			}
+			info := &navite.OSInfo{
+				Family:       d.family,
+				Distribution: d.distribution,
+				Arch:         "x86_64",
+			}
+			rest := text[loc[1]:]
+			if d.family == "windows" {
+				if m := windowsVersion.FindStringSubmatch(rest); m != nil {
+					info.Version = m[1] + strings.TrimSpace(m[2])
+				}
+			} else {
+				info.Version = linuxVersion.FindString(rest)
+			}
+			all := strings.ToLower(osName + " " + imageName)
+			switch {
+			case armArch.MatchString(all):
+				info.Arch = "arm64"
+			case i386Arch.MatchString(all):
+				info.Arch = "i386"
+			}
+			return info
+		}
+	}
+	return nil
+}
+
+// BuildCatalog 将镜像按操作系统分组
+func BuildCatalog(imageList []*navite.Image) (catalog []*navite.ImageCatalogEntry) {
+	entries := map[string]*navite.ImageCatalogEntry{}
+	for _, image := range imageList {
+		info := ParseOS(image.OSName, image.ImageName, image.ImageVersion)
+		if info == nil {
+			continue
+		}
+		entry, ok := entries[info.Key()]
+		if !ok {
+			entry = &navite.ImageCatalogEntry{OS: info}
+			entries[info.Key()] = entry
+			catalog = append(catalog, entry)
+		}
+		entry.Images = append(entry.Images, image)
+	}
+	sort.Slice(catalog, func(i, j int) bool {
+		return catalog[i].OS.Key() < catalog[j].OS.Key()
+	})
+	return catalog
+}
+
+// matchVersion version是否满足want, want为 7 时匹配 7.9, 不匹配 70
+func matchVersion(version, want string) bool {
+	return want == "" || version == want || strings.HasPrefix(version, want+".")
+}
+
+// compareVersion 按数字逐段比较版本号
+func compareVersion(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// publicImageOwners 公共镜像的所有者, 阿里云为ImageOwnerAlias, 腾讯云为ImageType
+var publicImageOwners = map[string]bool{
+	"system":       true,
+	"PUBLIC_IMAGE": true,
+}
+
+// IsPublicImage 镜像是否为云商提供的公共镜像
+//
+// * 自定义镜像和共享镜像会沿用源镜像的系统名称, 不能按操作系统选择
+func IsPublicImage(image *navite.Image) bool {
+	return publicImageOwners[image.Owner]
+}
+
+// SelectImage 从镜像中选出与want匹配的公共镜像
+//
+// * 版本按前缀匹配, 匹配到多个时选择版本最高的, 版本相同时选择最新创建的
+func SelectImage(imageList []*navite.Image, want *navite.OSInfo) (image *navite.Image) {
+	var best *navite.OSInfo
+	for _, candidate := range imageList {
+		if !IsPublicImage(candidate) {
+			continue
+		}
+		info := ParseOS(candidate.OSName, candidate.ImageName, candidate.ImageVersion)
+		if info == nil || info.Distribution != want.Distribution || !matchVersion(info.Version, want.Version) {
+			continue
+		}
+		if want.Arch != "" && info.Arch != want.Arch {
+			continue
+		}
+		if image != nil {
+			cmp := compareVersion(info.Version, best.Version)
+			if cmp < 0 || (cmp == 0 && !candidate.CreatedTime.After(image.CreatedTime)) {
+				continue
+			}
+		}
+		image, best = candidate, info
+	}
+	return image
+}
+
+// LookupImage 在账号的地域下查找操作系统对应的公共镜像
+func LookupImage(rbd *mgo.Client, accountID, regionID string, want *navite.OSInfo) (image *navite.Image) {
+	owners := make([]string, 0, len(publicImageOwners))
+	for owner := range publicImageOwners {
+		owners = append(owners, owner)
+	}
+	filter := bson.M{
+		"accountId": accountID,
+		"regionId":  regionID,
+		"owner":     bson.M{"$in": owners},
+	}
+	imageList := []*navite.Image{}
+	mctx := context.Background()
+	cur, err := rbd.Table(navite.ImageTable).Query(filter, 0, 0, nil)
+	if err != nil {
+		log.Warnf("filter [%v] images failed: %v", filter, err)
+		return nil
+	}
+	defer cur.Close(mctx)
+	if err = cur.All(mctx, &imageList); err != nil {
+		log.Errorf("decord mgo document failed: %v", err)
+		return nil
+	}
+	return SelectImage(imageList, want)
+}
+
+// ResolveImage 没有指定ImageID时按操作系统查找镜像并填入p.ImageID
+func ResolveImage(rbd *mgo.Client, p *param.RunInstanceParam) (errCode int) {
+	if p.ImageID != "" {
+		return constants.Success
+	}
+	if p.OSDistribution == "" {
+		return constants.InvalidParam
+	}
+	want := &navite.OSInfo{
+		Distribution: strings.ToLower(p.OSDistribution),
+		Version:      p.OSVersion,
+		Arch:         p.OSArch,
+	}
+	image := LookupImage(rbd, p.AccountID, p.RegionID, want)
+	if image == nil {
+		log.Warnf("no image [%s] in account [%s] region [%s]", want.Key(), p.AccountID, p.RegionID)
+		return constants.InvalidResourceID
+	}
+	p.ImageID = image.ImageID
+	return constants.Success
+}
@@ -0,0 +1,70 @@
+package catalog_test
+
+import (
+	"ark-common/resource/catalog"
+	"ark-common/resource/navite"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseOS(t *testing.T) {
+	Convey("从镜像名称中解析操作系统", t, func() {
+		Convey("阿里云和腾讯云的Ubuntu镜像解析结果相同", func() {
+			ali := catalog.ParseOS("Ubuntu  22.04 64位", "ubuntu_22_04_x64_20G_alibase_20230815.vhd", "")
+			ten := catalog.ParseOS("Ubuntu Server 22.04 LTS 64bit", "Ubuntu Server 22.04 LTS 64位", "")
+			So(ali.Key(), ShouldEqual, "ubuntu-22.04-x86_64")
+			So(ten.Key(), ShouldEqual, ali.Key())
+		})
+		Convey("系统名称为空时使用镜像名称", func() {
+			info := catalog.ParseOS("", "ubuntu_22_04_arm64_20G_alibase_20230815.vhd", "")
+			So(info.Key(), ShouldEqual, "ubuntu-22.04-arm64")
+		})
+		Convey("区分CentOS和CentOS Stream", func() {
+			So(catalog.ParseOS("CentOS  7.9 64位", "", "").Key(), ShouldEqual, "centos-7.9-x86_64")
+			So(catalog.ParseOS("CentOS Stream 9 64位", "", "").Distribution, ShouldEqual, "centos-stream")
+		})
+		Convey("Windows版本", func() {
+			info := catalog.ParseOS("Windows Server 2012 R2 数据中心版 64位中文版", "", "")
+			So(info.Family, ShouldEqual, "windows")
+			So(info.Version, ShouldEqual, "2012r2")
+		})
+		Convey("无法识别的镜像", func() {
+			So(catalog.ParseOS("Unknown OS", "custom-image", ""), ShouldBeNil)
+		})
+	})
+}
+
+func TestSelectImage(t *testing.T) {
+	now := time.Now()
+	imageList := []*navite.Image{
+		{ImageID: "centos-7.6", OSName: "CentOS 7.6 64位", Owner: "system", CreatedTime: now},
+		{ImageID: "centos-7.9-old", OSName: "CentOS 7.9 64位", Owner: "system", CreatedTime: now.Add(-time.Hour)},
+		{ImageID: "centos-7.9", OSName: "CentOS 7.9 64位", Owner: "system", CreatedTime: now},
+		{ImageID: "centos-8.2", OSName: "CentOS 8.2 64位", Owner: "system", CreatedTime: now},
+		// 基于公共镜像创建的自定义镜像, 系统名称与源镜像相同
+		{ImageID: "m-app-centos-7.9", OSName: "CentOS 7.9 64位", Owner: "self", CreatedTime: now.Add(time.Hour)},
+		{ImageID: "img-shared-centos-8.2", OSName: "CentOS 8.2 64位", Owner: "SHARED_IMAGE", CreatedTime: now.Add(time.Hour)},
+	}
+
+	Convey("按操作系统选择镜像", t, func() {
+		Convey("版本按前缀匹配并选择最高版本的最新镜像", func() {
+			image := catalog.SelectImage(imageList, &navite.OSInfo{Distribution: "centos", Version: "7", Arch: "x86_64"})
+			So(image.ImageID, ShouldEqual, "centos-7.9")
+		})
+		Convey("不选择更新的自定义镜像和共享镜像", func() {
+			image := catalog.SelectImage(imageList, &navite.OSInfo{Distribution: "centos", Version: "7.9"})
+			So(image.ImageID, ShouldEqual, "centos-7.9")
+			image = catalog.SelectImage(imageList, &navite.OSInfo{Distribution: "centos", Version: "8"})
+			So(image.ImageID, ShouldEqual, "centos-8.2")
+		})
+		Convey("腾讯云公共镜像", func() {
+			tencent := []*navite.Image{{ImageID: "img-l8og963d", OSName: "TencentOS Server 3.1", Owner: "PUBLIC_IMAGE"}}
+			So(catalog.SelectImage(tencent, &navite.OSInfo{Distribution: "tencentos"}).ImageID, ShouldEqual, "img-l8og963d")
+		})
+		Convey("没有匹配的镜像", func() {
+			So(catalog.SelectImage(imageList, &navite.OSInfo{Distribution: "ubuntu"}), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,370 @@
+package ipam
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/constants"
+	"ark-common/resource/navite"
+	"ark-common/utils/network"
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// allocateLock 串行化同一进程内的分配, 减少进程内的版本冲突
+//
+// * 多个进程之间通过IPAMVersion的版本检测并发的分配, 见reserveAt
+var allocateLock sync.Mutex
+
+// maxAllocateAttempts 版本冲突时重新分配的次数
+const maxAllocateAttempts = 5
+
+// vpcScope VPC网段在所有账号之间检查重叠, 使用同一个版本
+const vpcScope = "vpc"
+
+func subnetScope(vpcID string) string {
+	return "subnet:" + vpcID
+}
+
+var (
+	versionIndexMu sync.Mutex
+	versionIndexed bool
+)
+
+// ensureVersionIndex 创建scope的唯一索引, 版本的条件更新依赖该索引检测冲突
+func ensureVersionIndex(rbd *mgo.Client) error {
+	versionIndexMu.Lock()
+	defer versionIndexMu.Unlock()
+	if versionIndexed {
+		return nil
+	}
+	if err := rbd.Table(navite.IPAMVersionTable).CreateUniqueIndex("scope"); err != nil {
+		log.Errorf("create ipamVersion index failed: %v", err)
+		return err
+	}
+	versionIndexed = true
+	return nil
+}
+
+// loadVersion 读取scope当前的版本, 还没有分配过时为0
+func loadVersion(rbd *mgo.Client, scope string) (version int64, err error) {
+	if err = ensureVersionIndex(rbd); err != nil {
+		return 0, err
+	}
+	doc := &navite.IPAMVersion{}
+	filter := bson.M{
+		"scope": scope,
+	}
+	err = rbd.Table(navite.IPAMVersionTable).QueryOne(filter, doc, nil)
+	if mgo.IsNotFoundError(err) {
+		return 0, nil
+	}
+	if err != nil {
+		log.Errorf("query ipamVersion [%s] failed: %v", scope, err)
+		return 0, err
+	}
+	return doc.Version, nil
+}
+
+// bumpVersion scope的版本仍为version时加1, 已被其他进程修改时返回唯一索引冲突的错误
+func bumpVersion(rbd *mgo.Client, scope string, version int64) error {
+	filter := bson.M{
+		"scope":   scope,
+		"version": version,
+	}
+	update := bson.M{
+		"$inc": bson.M{"version": 1},
+	}
+	return rbd.Table(navite.IPAMVersionTable).Update(filter, update, options.Update().SetUpsert(true))
+}
+
+// reserveAt 在scope的版本为version时预留网段
+//
+// * 先写入预留再递增版本, 版本已变化说明有其他进程在读取已用网段之后完成了分配, 删除本次预留并返回lost, 由调用方重新分配
+func reserveAt(rbd *mgo.Client, scope string, version int64, reservation *navite.IPReservation) (errCode int, lost bool) {
+	reservation.CreatedTime = time.Now()
+	result, err := rbd.Table(navite.IPReservationTable).Insert(reservation)
+	if err != nil {
+		log.Errorf("insert ipReservation [%+v] failed: %v", reservation, err)
+		return constants.ServerError, false
+	}
+	if err = bumpVersion(rbd, scope, version); err == nil {
+		return constants.Success, false
+	}
+	if _, e := rbd.Table(navite.IPReservationTable).DeleteMany(bson.M{"_id": result.InsertedID}); e != nil {
+		log.Errorf("rollback ipReservation [%+v] failed: %v", reservation, e)
+	}
+	if mgo.IsDuplicateKeyError(err) {
+		log.Warnf("ipam scope [%s] changed since version %d, retry", scope, version)
+		return constants.ServerError, true
+	}
+	log.Errorf("update ipamVersion [%s] failed: %v", scope, err)
+	return constants.ServerError, false
+}
+
+// SetIPPool 设置环境的地址池
+func SetIPPool(rbd *mgo.Client, pool *navite.IPPool) (errCode int) {
+	if _, err := network.ParseCIDR(pool.Supernet); err != nil {
+		return constants.InvalidParam
+	}
+	if pool.CreatedTime.IsZero() {
+		pool.CreatedTime = time.Now()
+	}
+	filter := bson.M{
+		"environment": pool.Environment,
+	}
+	if err := rbd.Table(navite.IPPoolTable).Upsert(filter, pool); err != nil {
+		log.Errorf("upsert ipPool [%+v] failed: %v", pool, err)
+		return constants.ServerError
+	}
+	return constants.Success
+}
+
+// loadIPPool 读取环境的地址池
+func loadIPPool(rbd *mgo.Client, environment string) (pool *navite.IPPool, err error) {
+	pool = &navite.IPPool{}
+	filter := bson.M{
+		"environment": environment,
+	}
+	if err = rbd.Table(navite.IPPoolTable).QueryOne(filter, pool, nil); err != nil {
+		log.Warnf("filter [%v] ipPool failed: %v", filter, err)
+		return nil, err
+	}
+	return pool, nil
+}
+
+// loadCidrs 读取表中文档的cidrBlock字段
+func loadCidrs(rbd *mgo.Client, table string, filter bson.M) (cidrList []string, err error) {
+	documents := []struct {
+		CidrBlock string `bson:"cidrBlock"`
+	}{}
+	mctx := context.Background()
+	cur, err := rbd.Table(table).Query(filter, 0, 0, nil)
+	if err != nil {
+		log.Errorf("filter [%v] %s failed: %v", filter, table, err)
+		return nil, err
+	}
+	defer cur.Close(mctx)
+	if err = cur.All(mctx, &documents); err != nil {
+		log.Errorf("decord mgo document failed: %v", err)
+		return nil, err
+	}
+	for _, doc := range documents {
+		if doc.CidrBlock != "" {
+			cidrList = append(cidrList, doc.CidrBlock)
+		}
+	}
+	return cidrList, nil
+}
+
+// usedVPCCidrs 所有账号已同步的VPC网段和预留的VPC网段, 不包含已标记为删除的VPC
+func usedVPCCidrs(rbd *mgo.Client) (cidrList []string, err error) {
+	if cidrList, err = loadCidrs(rbd, navite.VPCTable, bson.M{"deleted": bson.M{"$ne": true}}); err != nil {
+		return nil, err
+	}
+	reserved, err := loadCidrs(rbd, navite.IPReservationTable, bson.M{"vpcId": ""})
+	if err != nil {
+		return nil, err
+	}
+	return append(cidrList, reserved...), nil
+}
+
+// usedSubnetCidrs VPC下已同步的子网网段和预留的子网网段, 不包含已标记为删除的子网
+func usedSubnetCidrs(rbd *mgo.Client, vpcID string) (cidrList []string, err error) {
+	filter := bson.M{
+		"vpcId": vpcID,
+	}
+	synced := bson.M{
+		"vpcId":   vpcID,
+		"deleted": bson.M{"$ne": true},
+	}
+	if cidrList, err = loadCidrs(rbd, navite.SubnetTable, synced); err != nil {
+		return nil, err
+	}
+	reserved, err := loadCidrs(rbd, navite.IPReservationTable, filter)
+	if err != nil {
+		return nil, err
+	}
+	return append(cidrList, reserved...), nil
+}
+
+// overlaps 返回used中与cidr重叠的网段
+func overlaps(cidr string, used []string) (conflicts []string) {
+	for _, u := range used {
+		if network.Overlaps(cidr, u) {
+			conflicts = append(conflicts, u)
+		}
+	}
+	return conflicts
+}
+
+// AllocateVPCCidr 从环境的地址池中分配掩码长度为prefixLen的VPC网段
+//
+// * 跳过所有账号已有的VPC网段和已预留的网段, 分配的网段会被预留
+// * 与其他进程并发分配时重新读取已用网段后再分配, 不会分配到重叠的网段
+func AllocateVPCCidr(rbd *mgo.Client, environment string, prefixLen int, owner string) (cidr string, errCode int) {
+	allocateLock.Lock()
+	defer allocateLock.Unlock()
+
+	pool, err := loadIPPool(rbd, environment)
+	if err != nil {
+		return "", constants.InvalidParam
+	}
+	for attempt := 0; attempt < maxAllocateAttempts; attempt++ {
+		version, err := loadVersion(rbd, vpcScope)
+		if err != nil {
+			return "", constants.ServerError
+		}
+		used, err := usedVPCCidrs(rbd)
+		if err != nil {
+			return "", constants.ServerError
+		}
+		if cidr, err = network.NextFreeCidr(pool.Supernet, prefixLen, used); err != nil {
+			log.Warnf("allocate /%d from ipPool [%s] failed: %v", prefixLen, environment, err)
+			return "", constants.CidrExhausted
+		}
+		code, lost := reserveAt(rbd, vpcScope, version, &navite.IPReservation{
+			Environment: environment,
+			CidrBlock:   cidr,
+			Owner:       owner,
+		})
+		if lost {
+			continue
+		}
+		if code != constants.Success {
+			return "", code
+		}
+		return cidr, constants.Success
+	}
+	log.Errorf("allocate /%d from ipPool [%s] failed after %d attempts", prefixLen, environment, maxAllocateAttempts)
+	return "", constants.ServerError
+}
+
+// AllocateSubnetCidr 从VPC网段中分配掩码长度为prefixLen的子网网段
+func AllocateSubnetCidr(rbd *mgo.Client, vpc *navite.VPC, prefixLen int, owner string) (cidr string, errCode int) {
+	allocateLock.Lock()
+	defer allocateLock.Unlock()
+
+	scope := subnetScope(vpc.VPCID)
+	for attempt := 0; attempt < maxAllocateAttempts; attempt++ {
+		version, err := loadVersion(rbd, scope)
+		if err != nil {
+			return "", constants.ServerError
+		}
+		used, err := usedSubnetCidrs(rbd, vpc.VPCID)
+		if err != nil {
+			return "", constants.ServerError
+		}
+		if cidr, err = network.NextFreeCidr(vpc.CidrBlock, prefixLen, used); err != nil {
+			log.Warnf("allocate /%d from vpc [%s] failed: %v", prefixLen, vpc.VPCID, err)
+			return "", constants.CidrExhausted
+		}
+		code, lost := reserveAt(rbd, scope, version, &navite.IPReservation{
+			VPCID:     vpc.VPCID,
+			CidrBlock: cidr,
+			Owner:     owner,
+		})
+		if lost {
+			continue
+		}
+		if code != constants.Success {
+			return "", code
+		}
+		return cidr, constants.Success
+	}
+	log.Errorf("allocate /%d from vpc [%s] failed after %d attempts", prefixLen, vpc.VPCID, maxAllocateAttempts)
+	return "", constants.ServerError
+}
+
+// ReserveVPCCidr 预留调用方指定的VPC网段, 网段需要在环境的地址池内, 与已有网段重叠时拒绝
+func ReserveVPCCidr(rbd *mgo.Client, environment, cidr, owner string) (errCode int) {
+	if _, err := network.ParseCIDR(cidr); err != nil {
+		return constants.InvalidParam
+	}
+	allocateLock.Lock()
+	defer allocateLock.Unlock()
+
+	pool, err := loadIPPool(rbd, environment)
+	if err != nil {
+		return constants.InvalidParam
+	}
+	if !network.Contains(pool.Supernet, cidr) {
+		log.Warnf("vpc cidr [%s] is not in ipPool [%s] supernet %s", cidr, environment, pool.Supernet)
+		return constants.InvalidParam
+	}
+	for attempt := 0; attempt < maxAllocateAttempts; attempt++ {
+		version, err := loadVersion(rbd, vpcScope)
+		if err != nil {
+			return constants.ServerError
+		}
+		used, err := usedVPCCidrs(rbd)
+		if err != nil {
+			return constants.ServerError
+		}
+		if conflicts := overlaps(cidr, used); len(conflicts) > 0 {
+			log.Warnf("vpc cidr [%s] overlaps with %v", cidr, conflicts)
+			return constants.CidrOverlap
+		}
+		code, lost := reserveAt(rbd, vpcScope, version, &navite.IPReservation{
+			Environment: environment,
+			CidrBlock:   cidr,
+			Owner:       owner,
+		})
+		if !lost {
+			return code
+		}
+	}
+	return constants.ServerError
+}
+
+// ReserveSubnetCidr 预留调用方指定的子网网段, 网段需要在VPC内并且不与其他子网重叠
+func ReserveSubnetCidr(rbd *mgo.Client, vpc *navite.VPC, cidr, owner string) (errCode int) {
+	if !network.Contains(vpc.CidrBlock, cidr) {
+		return constants.InvalidParam
+	}
+	allocateLock.Lock()
+	defer allocateLock.Unlock()
+
+	scope := subnetScope(vpc.VPCID)
+	for attempt := 0; attempt < maxAllocateAttempts; attempt++ {
+		version, err := loadVersion(rbd, scope)
+		if err != nil {
+			return constants.ServerError
+		}
+		used, err := usedSubnetCidrs(rbd, vpc.VPCID)
+		if err != nil {
+			return constants.ServerError
+		}
+		if conflicts := overlaps(cidr, used); len(conflicts) > 0 {
+			log.Warnf("subnet cidr [%s] overlaps with %v", cidr, conflicts)
+			return constants.CidrOverlap
+		}
+		code, lost := reserveAt(rbd, scope, version, &navite.IPReservation{
+			VPCID:     vpc.VPCID,
+			CidrBlock: cidr,
+			Owner:     owner,
+		})
+		if !lost {
+			return code
+		}
+	}
+	return constants.ServerError
+}
+
+// ReleaseCidr 释放预留的网段, VPC网段的vpcID为空
+//
+// * VPC/子网创建并同步后即可释放预留, 之后由同步的数据占用网段
+func ReleaseCidr(rbd *mgo.Client, vpcID, cidr string) (errCode int) {
+	filter := bson.M{
+		"vpcId":     vpcID,
+		"cidrBlock": cidr,
+	}
+	if _, err := rbd.Table(navite.IPReservationTable).DeleteMany(filter); err != nil {
+		log.Errorf("delete [%v] ipReservations failed: %v", filter, err)
+		return constants.ServerError
+	}
+	return constants.Success
+}
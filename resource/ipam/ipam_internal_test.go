@@ -0,0 +1,61 @@
+package ipam
+
+import (
+	"ark-common/constants"
+	"ark-common/resource/navite"
+	"ark-common/utils/testenv"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestOverlaps(t *testing.T) {
+	Convey("返回与网段重叠的已用网段", t, func() {
+		used := []string{"10.0.0.0/16", "10.1.0.0/16", "10.0.128.0/24"}
+		So(overlaps("10.0.0.0/17", used), ShouldResemble, []string{"10.0.0.0/16"})
+		So(overlaps("10.0.0.0/8", used), ShouldResemble, used)
+		So(overlaps("10.2.0.0/16", used), ShouldBeEmpty)
+	})
+}
+
+func TestReserveAt(t *testing.T) {
+	rbd := testenv.Mongo(t)
+	vpcID := fmt.Sprintf("vpc-ipam-%d", time.Now().UnixNano())
+	scope := subnetScope(vpcID)
+	defer func() {
+		rbd.Table(navite.IPReservationTable).DeleteMany(bson.M{"vpcId": vpcID})
+		rbd.Table(navite.IPAMVersionTable).DeleteMany(bson.M{"scope": scope})
+	}()
+
+	Convey("版本未变化时预留成功并递增版本", t, func() {
+		version, err := loadVersion(rbd, scope)
+		So(err, ShouldBeNil)
+		So(version, ShouldEqual, 0)
+
+		code, lost := reserveAt(rbd, scope, version, &navite.IPReservation{VPCID: vpcID, CidrBlock: "10.0.0.0/24"})
+		So(code, ShouldEqual, constants.Success)
+		So(lost, ShouldBeFalse)
+		version, _ = loadVersion(rbd, scope)
+		So(version, ShouldEqual, 1)
+
+		Convey("其他进程已预留时版本冲突, 回滚本次预留", func() {
+			// 两个进程读取到同一个版本, 先提交的进程预留成功
+			stale := version
+			code, lost = reserveAt(rbd, scope, stale, &navite.IPReservation{VPCID: vpcID, CidrBlock: "10.0.1.0/24"})
+			So(code, ShouldEqual, constants.Success)
+
+			code, lost = reserveAt(rbd, scope, stale, &navite.IPReservation{VPCID: vpcID, CidrBlock: "10.0.1.0/24"})
+			So(lost, ShouldBeTrue)
+			So(code, ShouldEqual, constants.ServerError)
+
+			count, err := rbd.Table(navite.IPReservationTable).Count(bson.M{"vpcId": vpcID, "cidrBlock": "10.0.1.0/24"}, nil)
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 1)
+			version, _ = loadVersion(rbd, scope)
+			So(version, ShouldEqual, 2)
+		})
+	})
+}
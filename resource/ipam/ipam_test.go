@@ -0,0 +1,75 @@
+package ipam_test
+
+import (
+	"ark-common/constants"
+	"ark-common/resource/ipam"
+	"ark-common/resource/navite"
+	"ark-common/utils/testenv"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestAllocateVPCCidr(t *testing.T) {
+	rbd := testenv.Mongo(t)
+	environment := fmt.Sprintf("ipam-test-%d", time.Now().UnixNano())
+	defer func() {
+		rbd.Table(navite.IPPoolTable).DeleteMany(bson.M{"environment": environment})
+		rbd.Table(navite.IPReservationTable).DeleteMany(bson.M{"environment": environment})
+	}()
+
+	Convey("从地址池分配和预留VPC网段", t, func() {
+		_, code := ipam.AllocateVPCCidr(rbd, environment, 24, "test")
+		So(code, ShouldEqual, constants.InvalidParam)
+
+		code = ipam.SetIPPool(rbd, &navite.IPPool{Environment: environment, Supernet: "100.64.0.0/22"})
+		So(code, ShouldEqual, constants.Success)
+
+		first, code := ipam.AllocateVPCCidr(rbd, environment, 24, "test")
+		So(code, ShouldEqual, constants.Success)
+		second, code := ipam.AllocateVPCCidr(rbd, environment, 24, "test")
+		So(code, ShouldEqual, constants.Success)
+		So(second, ShouldNotEqual, first)
+
+		// 预留与已分配网段重叠或不在地址池内时拒绝
+		So(ipam.ReserveVPCCidr(rbd, environment, first, "test"), ShouldEqual, constants.CidrOverlap)
+		So(ipam.ReserveVPCCidr(rbd, environment, "100.64.0.0/22", "test"), ShouldEqual, constants.CidrOverlap)
+		So(ipam.ReserveVPCCidr(rbd, environment, "100.65.0.0/24", "test"), ShouldEqual, constants.InvalidParam)
+
+		// 地址池用尽后分配失败, 释放后可以重新分配
+		So(ipam.ReserveVPCCidr(rbd, environment, "100.64.3.0/24", "test"), ShouldEqual, constants.Success)
+		third, code := ipam.AllocateVPCCidr(rbd, environment, 24, "test")
+		So(code, ShouldEqual, constants.Success)
+		_, code = ipam.AllocateVPCCidr(rbd, environment, 24, "test")
+		So(code, ShouldEqual, constants.CidrExhausted)
+
+		So(ipam.ReleaseCidr(rbd, "", third), ShouldEqual, constants.Success)
+		again, code := ipam.AllocateVPCCidr(rbd, environment, 24, "test")
+		So(code, ShouldEqual, constants.Success)
+		So(again, ShouldEqual, third)
+	})
+}
+
+func TestAllocateSubnetCidr(t *testing.T) {
+	rbd := testenv.Mongo(t)
+	vpc := &navite.VPC{
+		VPCID:     fmt.Sprintf("vpc-ipam-%d", time.Now().UnixNano()),
+		CidrBlock: "172.16.0.0/23",
+	}
+	defer rbd.Table(navite.IPReservationTable).DeleteMany(bson.M{"vpcId": vpc.VPCID})
+
+	Convey("从VPC网段分配和预留子网网段", t, func() {
+		So(ipam.ReserveSubnetCidr(rbd, vpc, "172.16.0.0/24", "test"), ShouldEqual, constants.Success)
+		So(ipam.ReserveSubnetCidr(rbd, vpc, "172.16.0.128/25", "test"), ShouldEqual, constants.CidrOverlap)
+		So(ipam.ReserveSubnetCidr(rbd, vpc, "172.17.0.0/24", "test"), ShouldEqual, constants.InvalidParam)
+
+		cidr, code := ipam.AllocateSubnetCidr(rbd, vpc, 24, "test")
+		So(code, ShouldEqual, constants.Success)
+		So(cidr, ShouldEqual, "172.16.1.0/24")
+		_, code = ipam.AllocateSubnetCidr(rbd, vpc, 24, "test")
+		So(code, ShouldEqual, constants.CidrExhausted)
+	})
+}
@@ -0,0 +1,152 @@
+package keystore
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/constants"
+	"ark-common/resource/navite"
+	"ark-common/utils/tool"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Keystore 加密保存私钥的私钥库, 所有操作都会记录审计
+type Keystore struct {
+	rbd    *mgo.Client
+	secret string
+}
+
+// NewKeystore 返回私钥库, secret为空时读取环境变量 ARK_KEYSTORE_KEY
+func NewKeystore(rbd *mgo.Client, secret string) (*Keystore, error) {
+	if secret == "" {
+		secret = os.Getenv("ARK_KEYSTORE_KEY")
+		if secret == "" {
+			return nil, errors.New("please set ENV: ARK_KEYSTORE_KEY")
+		}
+	}
+	return &Keystore{rbd: rbd, secret: secret}, nil
+}
+
+func (ks *Keystore) audit(operator, action, fingerprint string, allowed bool, reason string) {
+	record := &navite.KeystoreAudit{
+		FingerprintSHA256: fingerprint,
+		Operator:          operator,
+		Action:            action,
+		Allowed:           allowed,
+		Reason:            reason,
+		CreatedTime:       time.Now(),
+	}
+	if _, err := ks.rbd.Table(navite.KeystoreAuditTable).Insert(record); err != nil {
+		log.Errorf("insert keystoreAudit [%+v] failed: %v", record, err)
+	}
+}
+
+// Store 加密保存密钥对的私钥, 以SHA256指纹作为唯一标识, operator为私钥的所有者
+//
+// * 私钥推导出的公钥需要与密钥对的公钥一致
+// * 指纹已存在时只有原所有者可以覆盖, 其他人存入相同公钥的私钥会被拒绝
+func (ks *Keystore) Store(operator string, keypair *navite.Keypair, privateKey []byte) (err error) {
+	if keypair.FingerprintSHA256 == "" {
+		if err = keypair.SetFingerprint(); err != nil {
+			return err
+		}
+	}
+	if keypair.FingerprintSHA256 == "" {
+		return errors.New("keypair without public key")
+	}
+	derived, err := tool.PrivateKeyFingerprint(privateKey)
+	if err != nil {
+		ks.audit(operator, constants.KeystoreStore, keypair.FingerprintSHA256, false, "invalid private key")
+		return fmt.Errorf("parse private key failed: %v", err)
+	}
+	if derived != keypair.FingerprintSHA256 {
+		ks.audit(operator, constants.KeystoreStore, keypair.FingerprintSHA256, false, "private key mismatch")
+		return fmt.Errorf("private key %s does not match public key %s", derived, keypair.FingerprintSHA256)
+	}
+
+	filter := bson.M{
+		"fingerprintSha256": keypair.FingerprintSHA256,
+	}
+	existing := &navite.KeystoreEntry{}
+	err = ks.rbd.Table(navite.KeystoreTable).QueryOne(filter, existing, nil)
+	if err != nil && !mgo.IsNotFoundError(err) {
+		log.Errorf("query keystore [%s] failed: %v", keypair.FingerprintSHA256, err)
+		return err
+	}
+	if err == nil && existing.Owner != operator {
+		ks.audit(operator, constants.KeystoreStore, keypair.FingerprintSHA256, false, "owned by "+existing.Owner)
+		return fmt.Errorf("%s is not allowed to overwrite private key %s", operator, keypair.FingerprintSHA256)
+	}
+
+	sealed, err := tool.Seal(ks.secret, privateKey)
+	if err != nil {
+		return err
+	}
+	entry := &navite.KeystoreEntry{
+		AccountID:         keypair.AccountID,
+		KeypairName:       keypair.KeypairName,
+		KeyType:           keypair.KeyType,
+		FingerprintMD5:    keypair.FingerprintMD5,
+		FingerprintSHA256: keypair.FingerprintSHA256,
+		PrivateKey:        sealed,
+		Owner:             operator,
+		CreatedTime:       time.Now(),
+	}
+	if err = ks.rbd.Table(navite.KeystoreTable).Upsert(filter, entry); err != nil {
+		log.Errorf("upsert keystore [%s] failed: %v", entry.FingerprintSHA256, err)
+		return err
+	}
+	ks.audit(operator, constants.KeystoreStore, entry.FingerprintSHA256, true, "")
+	return nil
+}
+
+// Retrieve 取出私钥
+//
+// * 只有私钥的所有者和系统用户可以取出, 并且必须说明原因
+// * 无论是否允许都会记录审计
+func (ks *Keystore) Retrieve(operator, fingerprint, reason string) (privateKey []byte, err error) {
+	entry := &navite.KeystoreEntry{}
+	filter := bson.M{
+		"fingerprintSha256": fingerprint,
+	}
+	if err = ks.rbd.Table(navite.KeystoreTable).QueryOne(filter, entry, nil); err != nil {
+		ks.audit(operator, constants.KeystoreRetrieve, fingerprint, false, reason)
+		return nil, fmt.Errorf("private key %s not found", fingerprint)
+	}
+	if reason == "" || (operator != entry.Owner && operator != constants.SYSTEMUSER) {
+		ks.audit(operator, constants.KeystoreRetrieve, fingerprint, false, reason)
+		return nil, fmt.Errorf("%s is not allowed to retrieve private key %s", operator, fingerprint)
+	}
+	if privateKey, err = tool.Open(ks.secret, entry.PrivateKey); err != nil {
+		log.Errorf("decrypt private key [%s] failed: %v", fingerprint, err)
+		ks.audit(operator, constants.KeystoreRetrieve, fingerprint, false, reason)
+		return nil, err
+	}
+	ks.audit(operator, constants.KeystoreRetrieve, fingerprint, true, reason)
+	return privateKey, nil
+}
+
+// Delete 删除私钥, 只有私钥的所有者和系统用户可以删除
+func (ks *Keystore) Delete(operator, fingerprint string) (err error) {
+	entry := &navite.KeystoreEntry{}
+	filter := bson.M{
+		"fingerprintSha256": fingerprint,
+	}
+	if err = ks.rbd.Table(navite.KeystoreTable).QueryOne(filter, entry, nil); err != nil {
+		return fmt.Errorf("private key %s not found", fingerprint)
+	}
+	if operator != entry.Owner && operator != constants.SYSTEMUSER {
+		ks.audit(operator, constants.KeystoreDelete, fingerprint, false, "")
+		return fmt.Errorf("%s is not allowed to delete private key %s", operator, fingerprint)
+	}
+	if _, err = ks.rbd.Table(navite.KeystoreTable).DeleteMany(filter); err != nil {
+		log.Errorf("delete [%v] keystore failed: %v", filter, err)
+		return err
+	}
+	ks.audit(operator, constants.KeystoreDelete, fingerprint, true, "")
+	return nil
+}
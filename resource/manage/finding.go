@@ -0,0 +1,42 @@
+package manage
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/resource/navite"
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ListSecurityGroupFindings 安全组风险列表, severity为空时返回全部等级
+func ListSecurityGroupFindings(rbd *mgo.Client, accountID, regionID, severity string, pageSize, currentPage int) (count int, findingList []*navite.SecurityGroupFinding) {
+	filter := bson.M{}
+	if accountID != "" {
+		filter["accountId"] = accountID
+	}
+	if regionID != "" {
+		filter["regionId"] = regionID
+	}
+	if severity != "" {
+		filter["severity"] = severity
+	}
+	findingList = []*navite.SecurityGroupFinding{}
+	total, err := rbd.Table(navite.SecurityGroupFindingTable).Count(filter, nil)
+	if err != nil {
+		log.Warnf("list [%v] securityGroupFindings failed: %v", filter, err)
+		return 0, findingList
+	}
+	mctx := context.Background()
+	cur, err := rbd.Table(navite.SecurityGroupFindingTable).Query(filter, pageSize, currentPage, nil)
+	if err != nil {
+		log.Warnf("list [%v] securityGroupFindings failed: %v", filter, err)
+		return 0, findingList
+	}
+	defer cur.Close(mctx)
+	err = cur.All(mctx, &findingList)
+	if err != nil {
+		log.Errorf("decord mgo document failed: %v", err)
+	}
+	return int(total), findingList
+}
@@ -65,8 +65,24 @@ func ListImages(rbd *mgo.Client, regionID, osType string, pageSize, currentPage
 }
 
 // ListInstances 列出实例
+//
+// * 不返回同步时已标记为删除的资源
 func ListInstances(rbd *mgo.Client, pageSize, currentPage int) (count int, instanceList []*navite.Instance) {
+	return ListInstancesByStatus(rbd, "", "", pageSize, currentPage)
+}
+
+// ListInstancesByStatus 按云和统一的状态列出实例, 如 constants.InstanceStatusRunning, 参数为空时不过滤
+//
+// * 不返回同步时已标记为删除的资源
+func ListInstancesByStatus(rbd *mgo.Client, cloudName, unifiedStatus string, pageSize, currentPage int) (count int, instanceList []*navite.Instance) {
 	filter := bson.M{}
+	if cloudName != "" {
+		filter["cloudName"] = cloudName
+	}
+	if unifiedStatus != "" {
+		filter["unifiedStatus"] = unifiedStatus
+	}
+	filter["deleted"] = bson.M{"$ne": true}
 	instanceList = []*navite.Instance{}
 	total, err := rbd.Table(navite.InstanceTable).Count(filter, nil)
 	if err != nil {
@@ -120,8 +136,45 @@ func ListSecurityGroups(rbd *mgo.Client, cloudName, accountID, regionID string,
 	return int(total), sgList
 }
 
+// ListSecurityGroupRules 安全组的全部规则
+func ListSecurityGroupRules(rbd *mgo.Client, groupID string) (ruleList []*navite.SecurityGroupRule) {
+	filter := bson.M{
+		"groupId": groupID,
+	}
+	ruleList = []*navite.SecurityGroupRule{}
+	mctx := context.Background()
+	cur, err := rbd.Table(navite.SecurityGroupRuleTable).Query(filter, 0, 0, nil)
+	if err != nil {
+		log.Warnf("list [%v] securityGroupRules failed: %v", filter, err)
+		return ruleList
+	}
+	defer cur.Close(mctx)
+	err = cur.All(mctx, &ruleList)
+	if err != nil {
+		log.Errorf("decord mgo document failed: %v", err)
+	}
+	return ruleList
+}
+
+// ListDisks 列出磁盘
+//
+// * 不返回同步时已标记为删除的资源
 func ListDisks(rbd *mgo.Client, pageSize, currentPage int) (count int, diskList []*navite.Disk) {
+	return ListDisksByStatus(rbd, "", "", pageSize, currentPage)
+}
+
+// ListDisksByStatus 按云和统一的状态列出磁盘, 参数为空时不过滤
+//
+// * 不返回同步时已标记为删除的资源
+func ListDisksByStatus(rbd *mgo.Client, cloudName, unifiedStatus string, pageSize, currentPage int) (count int, diskList []*navite.Disk) {
 	filter := bson.M{}
+	if cloudName != "" {
+		filter["cloudName"] = cloudName
+	}
+	if unifiedStatus != "" {
+		filter["unifiedStatus"] = unifiedStatus
+	}
+	filter["deleted"] = bson.M{"$ne": true}
 	diskList = []*navite.Disk{}
 	total, err := rbd.Table(navite.DiskTable).Count(filter, nil)
 	if err != nil {
@@ -163,3 +216,35 @@ func ListKeypairs(rbd *mgo.Client, pageSize, currentPage int) (count int, keypai
 	}
 	return int(total), keypairList
 }
+
+// ListEips 列出弹性公网IP, unifiedStatus非空时按统一的状态过滤
+//
+// * 不返回同步时已标记为删除的资源
+func ListEips(rbd *mgo.Client, cloudName, unifiedStatus string, pageSize, currentPage int) (count int, eipList []*navite.Eip) {
+	filter := bson.M{}
+	if cloudName != "" {
+		filter["cloudName"] = cloudName
+	}
+	if unifiedStatus != "" {
+		filter["unifiedStatus"] = unifiedStatus
+	}
+	filter["deleted"] = bson.M{"$ne": true}
+	eipList = []*navite.Eip{}
+	total, err := rbd.Table(navite.EIPTable).Count(filter, nil)
+	if err != nil {
+		log.Warnf("list [%v] eips failed: %v", filter, err)
+		return 0, eipList
+	}
+	mctx := context.Background()
+	cur, err := rbd.Table(navite.EIPTable).Query(filter, pageSize, currentPage, nil)
+	if err != nil {
+		log.Warnf("list [%v] eips failed: %v", filter, err)
+		return 0, eipList
+	}
+	defer cur.Close(mctx)
+	err = cur.All(mctx, &eipList)
+	if err != nil {
+		log.Errorf("decord mgo document failed: %v", err)
+	}
+	return int(total), eipList
+}
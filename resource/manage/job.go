@@ -0,0 +1,78 @@
+package manage
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/constants"
+	"ark-common/resource/navite"
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ListDeadJobs 重试耗尽后投递到死信队列的作业, accountID为空时返回全部账号
+func ListDeadJobs(rbd *mgo.Client, accountID string, pageSize, currentPage int) (count int, jobList []*navite.Job) {
+	filter := bson.M{
+		"deadLettered": true,
+	}
+	if accountID != "" {
+		filter["accountId"] = accountID
+	}
+	jobList = []*navite.Job{}
+	total, err := rbd.Table(navite.JobTable).Count(filter, nil)
+	if err != nil {
+		log.Warnf("list [%v] jobs failed: %v", filter, err)
+		return 0, jobList
+	}
+	mctx := context.Background()
+	cur, err := rbd.Table(navite.JobTable).Query(filter, pageSize, currentPage, nil)
+	if err != nil {
+		log.Warnf("list [%v] jobs failed: %v", filter, err)
+		return 0, jobList
+	}
+	defer cur.Close(mctx)
+	err = cur.All(mctx, &jobList)
+	if err != nil {
+		log.Errorf("decord mgo document failed: %v", err)
+	}
+	return int(total), jobList
+}
+
+// maxJobTreeDepth 作业树的最大深度, 防止错误的parentId形成环
+const maxJobTreeDepth = 8
+
+// GetJobTree 返回作业及其所有子作业
+func GetJobTree(rbd *mgo.Client, jobID string) (tree *navite.JobTree, errCode int) {
+	job := &navite.Job{}
+	filter := bson.M{
+		"jobId": jobID,
+	}
+	if err := rbd.Table(navite.JobTable).QueryOne(filter, job, nil); err != nil {
+		log.Errorf("query job [%s] failed: %v", jobID, err)
+		return nil, constants.InvalidResourceID
+	}
+	tree = &navite.JobTree{Job: job}
+	if err := buildJobTree(rbd, tree, 1); err != nil {
+		return nil, constants.ServerError
+	}
+	return tree, constants.Success
+}
+
+func buildJobTree(rbd *mgo.Client, tree *navite.JobTree, depth int) error {
+	tree.Children = []*navite.JobTree{}
+	if depth >= maxJobTreeDepth {
+		return nil
+	}
+	children, err := navite.ListChildJobs(rbd, tree.Job.JobID)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		node := &navite.JobTree{Job: child}
+		if err = buildJobTree(rbd, node, depth+1); err != nil {
+			return err
+		}
+		tree.Children = append(tree.Children, node)
+	}
+	return nil
+}
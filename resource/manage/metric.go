@@ -0,0 +1,38 @@
+package manage
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/resource/navite"
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetInstanceMetric 获取实例在时间段内的监控数据
+func GetInstanceMetric(rbd *mgo.Client, instanceID, metricName string, startTime, endTime time.Time) (points []*navite.MetricPoint) {
+	filter := bson.M{
+		"instanceId": instanceID,
+		"metricName": metricName,
+		"timestamp": bson.M{
+			"$gte": startTime,
+			"$lte": endTime,
+		},
+	}
+	points = []*navite.MetricPoint{}
+	opt := options.Find().SetSort(bson.M{"timestamp": 1})
+	mctx := context.Background()
+	cur, err := rbd.Table(navite.InstanceMetricTable).Query(filter, 0, 0, opt)
+	if err != nil {
+		log.Warnf("list [%v] metrics failed: %v", filter, err)
+		return points
+	}
+	defer cur.Close(mctx)
+	err = cur.All(mctx, &points)
+	if err != nil {
+		log.Errorf("decord mgo document failed: %v", err)
+	}
+	return points
+}
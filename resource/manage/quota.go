@@ -0,0 +1,80 @@
+package manage
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/constants"
+	"ark-common/param"
+	"ark-common/resource/navite"
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ListQuotas 列出账号在地域下的配额
+func ListQuotas(rbd *mgo.Client, accountID, regionID string) (quotaList []*navite.Quota) {
+	filter := bson.M{
+		"accountId": accountID,
+	}
+	if regionID != "" {
+		filter["regionId"] = regionID
+	}
+	quotaList = []*navite.Quota{}
+	mctx := context.Background()
+	cur, err := rbd.Table(navite.QuotaTable).Query(filter, 0, 0, nil)
+	if err != nil {
+		log.Warnf("list [%v] quotas failed: %v", filter, err)
+		return quotaList
+	}
+	defer cur.Close(mctx)
+	err = cur.All(mctx, &quotaList)
+	if err != nil {
+		log.Errorf("decord mgo document failed: %v", err)
+	}
+	return quotaList
+}
+
+// CheckRunInstanceQuota 创建实例前检查剩余配额是否足够
+//
+// * 没有同步到的配额视为不限制
+func CheckRunInstanceQuota(rbd *mgo.Client, p *param.RunInstanceParam) (errCode int) {
+	numbers := int64(p.Numbers)
+	if numbers < 1 {
+		numbers = 1
+	}
+	var cpu int64
+	spec := &navite.InstanceSpec{}
+	filter := bson.M{
+		"accountId":      p.AccountID,
+		"regionId":       p.RegionID,
+		"instanceSpecId": p.InstanceType,
+	}
+	if err := rbd.Table(navite.InstanceSpecTable).QueryOne(filter, spec, nil); err != nil {
+		log.Warnf("filter [%v] instanceSpec failed, skip vcpu quota check: %v", filter, err)
+	} else {
+		cpu = int64(spec.CPU)
+	}
+
+	for _, quota := range ListQuotas(rbd, p.AccountID, p.RegionID) {
+		if quota.ZoneID != "" && quota.ZoneID != p.ZoneID {
+			continue
+		}
+		var need int64
+		switch quota.QuotaName {
+		case constants.QuotaInstanceVCPU:
+			need = cpu * numbers
+		case constants.QuotaInstanceCount:
+			need = numbers
+		case constants.QuotaDiskCapacity:
+			need = int64(p.DiskSize) * numbers
+		default:
+			continue
+		}
+		if need > quota.Remaining() {
+			log.Warnf("account [%s] region [%s] quota [%s] exceeded, need %d remaining %d",
+				p.AccountID, p.RegionID, quota.QuotaName, need, quota.Remaining())
+			return constants.QuotaExceeded
+		}
+	}
+	return constants.Success
+}
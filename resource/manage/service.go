@@ -0,0 +1,45 @@
+package manage
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/constants"
+	"ark-common/resource/navite"
+	"ark-common/resource/registry"
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ListServices 服务实例列表, serviceName为空时返回全部服务
+//
+// * liveOnly为true时只返回DeadAfter内有心跳的实例, 不依赖是否已被标记为dead
+func ListServices(rbd *mgo.Client, serviceName string, liveOnly bool, pageSize, currentPage int) (count int, serviceList []*navite.Service) {
+	filter := bson.M{}
+	if serviceName != "" {
+		filter["serviceName"] = serviceName
+	}
+	if liveOnly {
+		filter["status"] = constants.ServiceAlive
+		filter["lastUpdateTime"] = bson.M{"$gte": time.Now().Add(-registry.DeadAfter)}
+	}
+	serviceList = []*navite.Service{}
+	total, err := rbd.Table(navite.ServiceTable).Count(filter, nil)
+	if err != nil {
+		log.Warnf("list [%v] services failed: %v", filter, err)
+		return 0, serviceList
+	}
+	mctx := context.Background()
+	cur, err := rbd.Table(navite.ServiceTable).Query(filter, pageSize, currentPage, nil)
+	if err != nil {
+		log.Warnf("list [%v] services failed: %v", filter, err)
+		return 0, serviceList
+	}
+	defer cur.Close(mctx)
+	err = cur.All(mctx, &serviceList)
+	if err != nil {
+		log.Errorf("decord mgo document failed: %v", err)
+	}
+	return int(total), serviceList
+}
@@ -0,0 +1,137 @@
+package manage
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/param"
+	"ark-common/resource/navite"
+	"context"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// specSoldOut 售罄的规格状态
+var specSoldOut = map[string]bool{
+	"SOLD_OUT": true,
+	"SoldOut":  true,
+}
+
+// MatchInstanceSpecs 在所有账号和云商已同步的规格中匹配满足需求的规格
+func MatchInstanceSpecs(rbd *mgo.Client, p *param.MatchInstanceSpecParam) (candidates []*navite.SpecCandidate) {
+	filter := bson.M{
+		"regionId": bson.M{"$in": p.RegionIDs},
+		"cpu":      bson.M{"$gte": p.CPU},
+		"memory":   bson.M{"$gte": p.Memory},
+	}
+	if p.CloudName != "" {
+		filter["cloudName"] = p.CloudName
+	}
+	specList := []*navite.InstanceSpec{}
+	mctx := context.Background()
+	cur, err := rbd.Table(navite.InstanceSpecTable).Query(filter, 0, 0, nil)
+	if err != nil {
+		log.Warnf("filter [%v] instanceSpecs failed: %v", filter, err)
+		return []*navite.SpecCandidate{}
+	}
+	defer cur.Close(mctx)
+	if err = cur.All(mctx, &specList); err != nil {
+		log.Errorf("decord mgo document failed: %v", err)
+	}
+	return RankInstanceSpecs(specList, p)
+}
+
+// RankInstanceSpecs 过滤并排序规格
+//
+// * 同一账号地域下相同的规格按可用区合并
+// * 优先返回有可售可用区的规格, 其次按超出需求的比例从小到大, 再按价格从低到高, 没有价格的排在后面
+// * 阿里云的规格不区分可用区, 没有售卖状态, 这类规格ZoneKnown为false并视为可售, 是否有货由创建实例时云端校验
+func RankInstanceSpecs(specList []*navite.InstanceSpec, p *param.MatchInstanceSpecParam) (candidates []*navite.SpecCandidate) {
+	candidates = []*navite.SpecCandidate{}
+	merged := map[string]*navite.SpecCandidate{}
+	for _, spec := range specList {
+		if !fitSpec(spec, p) {
+			continue
+		}
+		key := spec.AccountID + "|" + spec.RegionID + "|" + spec.InstanceSpecID
+		c, ok := merged[key]
+		if !ok {
+			c = &navite.SpecCandidate{
+				CloudName:      spec.CloudName,
+				AccountID:      spec.AccountID,
+				RegionID:       spec.RegionID,
+				InstanceSpecID: spec.InstanceSpecID,
+				InstanceFamily: spec.InstanceFamily,
+				CPU:            spec.CPU,
+				Memory:         spec.Memory,
+				GPU:            spec.GPU,
+				HourlyPrice:    spec.HourlyPrice,
+				Zones:          []*navite.ZoneAvailability{},
+				Waste:          specWaste(spec, p),
+			}
+			merged[key] = c
+			candidates = append(candidates, c)
+		}
+		available := !specSoldOut[spec.Status]
+		c.Available = c.Available || available
+		if spec.ZoneID != "" {
+			c.ZoneKnown = true
+			c.Zones = append(c.Zones, &navite.ZoneAvailability{
+				ZoneID:    spec.ZoneID,
+				Status:    spec.Status,
+				Available: available,
+			})
+		}
+		if spec.HourlyPrice > 0 && (c.HourlyPrice == 0 || spec.HourlyPrice < c.HourlyPrice) {
+			c.HourlyPrice = spec.HourlyPrice
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Available != b.Available {
+			return a.Available
+		}
+		if a.Waste != b.Waste {
+			return a.Waste < b.Waste
+		}
+		if (a.HourlyPrice > 0) != (b.HourlyPrice > 0) {
+			return a.HourlyPrice > 0
+		}
+		return a.HourlyPrice < b.HourlyPrice
+	})
+	if p.Limit > 0 && len(candidates) > p.Limit {
+		candidates = candidates[:p.Limit]
+	}
+	return candidates
+}
+
+func fitSpec(spec *navite.InstanceSpec, p *param.MatchInstanceSpecParam) bool {
+	if spec.CPU < p.CPU || spec.Memory < p.Memory {
+		return false
+	}
+	if (p.GPU == 0 && spec.GPU > 0) || spec.GPU < p.GPU {
+		return false
+	}
+	if len(p.Families) == 0 {
+		return true
+	}
+	for _, family := range p.Families {
+		if strings.HasPrefix(spec.InstanceFamily, family) || strings.HasPrefix(spec.InstanceSpecID, family) {
+			return true
+		}
+	}
+	return false
+}
+
+// specWaste 规格的CPU和内存超出需求的比例之和
+func specWaste(spec *navite.InstanceSpec, p *param.MatchInstanceSpecParam) (waste float64) {
+	if p.CPU > 0 {
+		waste += float64(spec.CPU-p.CPU) / float64(p.CPU)
+	}
+	if p.Memory > 0 {
+		waste += (spec.Memory - p.Memory) / p.Memory
+	}
+	return waste
+}
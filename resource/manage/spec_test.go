@@ -0,0 +1,73 @@
+package manage_test
+
+import (
+	"ark-common/constants"
+	"ark-common/param"
+	"ark-common/resource/manage"
+	"ark-common/resource/navite"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRankInstanceSpecs(t *testing.T) {
+	spec := func(cloudName, zoneID, specID string, cpu int, memory float64, status string, price float64) *navite.InstanceSpec {
+		return &navite.InstanceSpec{
+			CloudName:      cloudName,
+			AccountID:      cloudName + "-account",
+			RegionID:       "region",
+			ZoneID:         zoneID,
+			InstanceSpecID: specID,
+			InstanceFamily: specID[:2],
+			CPU:            cpu,
+			Memory:         memory,
+			Status:         status,
+			HourlyPrice:    price,
+		}
+	}
+	specList := []*navite.InstanceSpec{
+		spec(constants.Tencent, "zone-1", "S5.LARGE8", 2, 8, "SELL", 0.5),
+		spec(constants.Tencent, "zone-2", "S5.LARGE8", 2, 8, "SOLD_OUT", 0.4),
+		spec(constants.Tencent, "zone-1", "S5.XLARGE16", 4, 16, "SELL", 1),
+		spec(constants.Tencent, "zone-1", "SA.LARGE4", 2, 4, "SOLD_OUT", 0.3),
+		spec(constants.Tencent, "zone-1", "GN.LARGE8", 2, 8, "SELL", 3),
+		spec(constants.Aliyun, "", "ecs.g6.large", 2, 8, "", 0),
+	}
+	specList[4].GPU = 1
+
+	Convey("过滤并排序实例规格", t, func() {
+		Convey("合并可用区并按贴合程度和价格排序", func() {
+			candidates := manage.RankInstanceSpecs(specList, &param.MatchInstanceSpecParam{CPU: 2, Memory: 8})
+			So(len(candidates), ShouldEqual, 3)
+			So(candidates[0].InstanceSpecID, ShouldEqual, "S5.LARGE8")
+			So(candidates[0].HourlyPrice, ShouldEqual, 0.4)
+			So(len(candidates[0].Zones), ShouldEqual, 2)
+			So(candidates[0].ZoneKnown, ShouldBeTrue)
+			So(candidates[0].Available, ShouldBeTrue)
+			So(candidates[1].InstanceSpecID, ShouldEqual, "ecs.g6.large")
+			So(candidates[2].InstanceSpecID, ShouldEqual, "S5.XLARGE16")
+		})
+		Convey("阿里云规格没有可用区数据", func() {
+			candidates := manage.RankInstanceSpecs(specList, &param.MatchInstanceSpecParam{CPU: 2, Memory: 8, CloudName: constants.Aliyun, Families: []string{"ecs.g6"}})
+			So(len(candidates), ShouldEqual, 1)
+			So(candidates[0].ZoneKnown, ShouldBeFalse)
+			So(candidates[0].Zones, ShouldBeEmpty)
+			So(candidates[0].Available, ShouldBeTrue)
+		})
+		Convey("售罄的规格排在后面", func() {
+			candidates := manage.RankInstanceSpecs(specList, &param.MatchInstanceSpecParam{CPU: 2, Memory: 4, Families: []string{"SA", "S5"}})
+			So(len(candidates), ShouldEqual, 3)
+			So(candidates[2].InstanceSpecID, ShouldEqual, "SA.LARGE4")
+			So(candidates[2].Available, ShouldBeFalse)
+		})
+		Convey("只在指定GPU时返回GPU规格", func() {
+			candidates := manage.RankInstanceSpecs(specList, &param.MatchInstanceSpecParam{CPU: 2, Memory: 8, GPU: 1})
+			So(len(candidates), ShouldEqual, 1)
+			So(candidates[0].InstanceSpecID, ShouldEqual, "GN.LARGE8")
+		})
+		Convey("按Limit截断", func() {
+			candidates := manage.RankInstanceSpecs(specList, &param.MatchInstanceSpecParam{CPU: 1, Memory: 1, Limit: 2})
+			So(len(candidates), ShouldEqual, 2)
+		})
+	})
+}
@@ -0,0 +1,22 @@
+package navite
+
+import "strings"
+
+// OSInfo 从镜像名称中解析出的操作系统信息
+type OSInfo struct {
+	Family       string `bson:"family" json:"family"`             // linux/windows/bsd
+	Distribution string `bson:"distribution" json:"distribution"` // ubuntu/centos/windows...
+	Version      string `bson:"version" json:"version"`           // 22.04/7.9/2019
+	Arch         string `bson:"arch" json:"arch"`                 // x86_64/arm64/i386
+}
+
+// Key 返回操作系统的唯一标识, 如 ubuntu-22.04-x86_64
+func (o *OSInfo) Key() string {
+	return strings.Join([]string{o.Distribution, o.Version, o.Arch}, "-")
+}
+
+// ImageCatalogEntry 不同云商和地域下相同操作系统的镜像
+type ImageCatalogEntry struct {
+	OS     *OSInfo  `json:"os"`
+	Images []*Image `json:"images"`
+}
@@ -0,0 +1,91 @@
+package navite
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// FieldChange 变化的字段, Field为bson字段名
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// ResourceEvent 资源变更事件
+type ResourceEvent struct {
+	EventID      string         `json:"eventId"`
+	Event        string         `json:"event"` // constants.EventCreated 等
+	CloudName    string         `json:"cloudName"`
+	AccountID    string         `json:"accountId"`
+	RegionID     string         `json:"regionId"`
+	ResourceType string         `json:"resourceType"` // constants.ResourceInstance 等
+	ResourceID   string         `json:"resourceId"`
+	Changes      []*FieldChange `json:"changes,omitempty"`
+	Resource     interface{}    `json:"resource,omitempty"` // 删除事件中为删除前的资源
+	Time         time.Time      `json:"time"`
+}
+
+// RoutingKey 返回事件的routingKey, 如 resource.aliyun.instance.updated
+func (e *ResourceEvent) RoutingKey() string {
+	return fmt.Sprintf("resource.%s.%s.%s", e.CloudName, e.ResourceType, e.Event)
+}
+
+// diffIgnoredFields 每次同步都会变化的字段, 不计入变更
+var diffIgnoredFields = map[string]bool{
+	"syncedTime":  true,
+	"deletedTime": true,
+}
+
+// DiffFields 比较同一类型的两个资源, 返回发生变化的字段
+//
+// * old和new需为同一结构体类型的指针
+// * 时间按毫秒比较, mongo只保存到毫秒
+// * 空切片和nil视为相同
+func DiffFields(old, new interface{}) (changes []*FieldChange) {
+	ov := reflect.Indirect(reflect.ValueOf(old))
+	nv := reflect.Indirect(reflect.ValueOf(new))
+	if ov.Kind() != reflect.Struct || ov.Type() != nv.Type() {
+		return nil
+	}
+	t := ov.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("bson"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if diffIgnoredFields[name] {
+			continue
+		}
+		a, b := ov.Field(i), nv.Field(i)
+		if fieldEqual(a, b) {
+			continue
+		}
+		changes = append(changes, &FieldChange{
+			Field: name,
+			Old:   a.Interface(),
+			New:   b.Interface(),
+		})
+	}
+	return changes
+}
+
+func fieldEqual(a, b reflect.Value) bool {
+	if ta, ok := a.Interface().(time.Time); ok {
+		tb := b.Interface().(time.Time)
+		return ta.Truncate(time.Millisecond).Equal(tb.Truncate(time.Millisecond))
+	}
+	if a.Kind() == reflect.Slice && a.Len() == 0 && b.Len() == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
@@ -0,0 +1,63 @@
+package navite_test
+
+import (
+	"ark-common/resource/navite"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDiffFields(t *testing.T) {
+	now := time.Date(2020, 1, 1, 10, 0, 0, 123456789, time.UTC)
+	newInstance := func() *navite.Instance {
+		return &navite.Instance{
+			InstanceID:  "i-test",
+			Status:      "Running",
+			CreatedTime: now,
+			SyncedTime:  now,
+		}
+	}
+	Convey("比较资源的字段变化", t, func() {
+		Convey("相同的资源没有变化", func() {
+			So(navite.DiffFields(newInstance(), newInstance()), ShouldBeEmpty)
+		})
+		Convey("返回变化字段的bson名和新旧值", func() {
+			old, cur := newInstance(), newInstance()
+			cur.Status = "Stopped"
+			changes := navite.DiffFields(old, cur)
+			So(len(changes), ShouldEqual, 1)
+			So(changes[0].Field, ShouldEqual, "status")
+			So(changes[0].Old, ShouldEqual, "Running")
+			So(changes[0].New, ShouldEqual, "Stopped")
+		})
+		Convey("时间按毫秒比较", func() {
+			old, cur := newInstance(), newInstance()
+			old.CreatedTime = now.Truncate(time.Millisecond)
+			So(navite.DiffFields(old, cur), ShouldBeEmpty)
+			cur.CreatedTime = now.Add(time.Second)
+			changes := navite.DiffFields(old, cur)
+			So(len(changes), ShouldEqual, 1)
+			So(changes[0].Field, ShouldEqual, "createdTime")
+		})
+		Convey("nil和空切片视为相同", func() {
+			old, cur := newInstance(), newInstance()
+			old.SecurityGroupList = nil
+			cur.SecurityGroupList = []string{}
+			So(navite.DiffFields(old, cur), ShouldBeEmpty)
+			cur.SecurityGroupList = []string{"sg-1"}
+			changes := navite.DiffFields(old, cur)
+			So(len(changes), ShouldEqual, 1)
+			So(changes[0].Field, ShouldEqual, "securityGroupList")
+		})
+		Convey("忽略每次同步都会变化的字段", func() {
+			old, cur := newInstance(), newInstance()
+			cur.SyncedTime = now.Add(time.Hour)
+			cur.DeletedTime = now.Add(time.Hour)
+			So(navite.DiffFields(old, cur), ShouldBeEmpty)
+		})
+		Convey("类型不同时不比较", func() {
+			So(navite.DiffFields(newInstance(), &navite.Disk{}), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,20 @@
+package navite
+
+import "time"
+
+// SecurityGroupFindingTable 安全组风险表
+const SecurityGroupFindingTable = "securityGroupFindings"
+
+// SecurityGroupFinding 安全组规则的风险项
+type SecurityGroupFinding struct {
+	CloudName   string             `bson:"cloudName" json:"cloudName"`
+	AccountID   string             `bson:"accountId" json:"accountId"`
+	RegionID    string             `bson:"regionId" json:"regionId"`
+	GroupID     string             `bson:"groupId" json:"groupId"`
+	GroupName   string             `bson:"groupName" json:"groupName"`
+	Category    string             `bson:"category" json:"category"`
+	Severity    string             `bson:"severity" json:"severity"`
+	Message     string             `bson:"message" json:"message"`
+	Rule        *SecurityGroupRule `bson:"rule" json:"rule"` // 没有出站规则等安全组级别的风险为空
+	CreatedTime time.Time          `bson:"createdTime" json:"createdTime"`
+}
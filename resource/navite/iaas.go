@@ -46,6 +46,9 @@ type InstanceSpec struct {
 	InstanceFamily   string    `bson:"instanceFamily" json:"instanceFamily"`
 	CPU              int       `bson:"cpu" json:"cpu"`
 	Memory           float64   `bson:"memory" json:"memory"`
+	GPU              int       `bson:"gpu" json:"gpu"`
+	GPUSpec          string    `bson:"gpuSpec" json:"gpuSpec"`
+	HourlyPrice      float64   `bson:"hourlyPrice" json:"hourlyPrice"` // 按量付费的小时单价, 0代表没有价格数据
 	Status           string    `bson:"status" json:"status"`
 	SyncedTime       time.Time `bson:"syncedTime" json:"syncedTime"`
 }
@@ -76,6 +79,14 @@ type Instance struct {
 	SecurityGroupList []string  `bson:"securityGroupList" json:"securityGroupList"`
 	CreatedTime       time.Time `bson:"createdTime" json:"createdTime"`
 	SyncedTime        time.Time `bson:"syncedTime" json:"syncedTime"`
+
+	// 与云商无关的统一取值, 原始值保留在上面的字段中
+	UnifiedStatus     string `bson:"unifiedStatus" json:"unifiedStatus"`
+	UnifiedChargeType string `bson:"unifiedChargeType" json:"unifiedChargeType"`
+
+	// 云端已删除时由同步作业标记, 资源重新出现时被同步覆盖
+	Deleted     bool      `bson:"deleted" json:"deleted"`
+	DeletedTime time.Time `bson:"deletedTime" json:"deletedTime"`
 }
 
 // SecurityGroup 安全组
@@ -95,6 +106,8 @@ type SecurityGroup struct {
 // SecurityGroupRule 安全组规则
 type SecurityGroupRule struct {
 	CloudName    string    `bson:"cloudName" json:"cloudName"`
+	AccountID    string    `bson:"accountId" json:"accountId"`
+	RegionID     string    `bson:"regionId" json:"regionId"`
 	GroupID      string    `bson:"groupId" json:"grouopId"`
 	GroupName    string    `bson:"groupName" json:"groupName"`
 	DestCidrIP   string    `bson:"destCidrIp" json:"destCidrIp"`
@@ -130,19 +143,31 @@ type Disk struct {
 	Description      string    `bson:"description" json:"description"`
 	CreatedTime      time.Time `bson:"createdTime" json:"createdTime"`
 	SyncedTime       time.Time `bson:"syncedTime" json:"syncedTime"`
+
+	// 与云商无关的统一取值, 原始值保留在上面的字段中
+	UnifiedStatus     string `bson:"unifiedStatus" json:"unifiedStatus"`
+	UnifiedChargeType string `bson:"unifiedChargeType" json:"unifiedChargeType"`
+	UnifiedDiskType   string `bson:"unifiedDiskType" json:"unifiedDiskType"`
+
+	// 云端已删除时由同步作业标记, 资源重新出现时被同步覆盖
+	Deleted     bool      `bson:"deleted" json:"deleted"`
+	DeletedTime time.Time `bson:"deletedTime" json:"deletedTime"`
 }
 
 // Keypair 密钥对
 type Keypair struct {
-	CloudName   string    `bson:"cloudName" json:"cloudName"`
-	RegionID    string    `bson:"regionId" json:"regionId"`
-	AccountID   string    `bson:"accountId" json:"accountId"`
-	KeypairID   string    `bson:"keypairId" json:"keypairId"`
-	KeypairName string    `bson:"keypairName" json:"keypairName"`
-	PublicKey   string    `bson:"publicKey" json:"publicKey"`
-	Description string    `bson:"description" json:"description"`
-	CreatedTime time.Time `bson:"createdTime" json:"createdTime"`
-	SyncedTime  time.Time `bson:"syncedTime" json:"syncedTime"`
+	CloudName         string    `bson:"cloudName" json:"cloudName"`
+	RegionID          string    `bson:"regionId" json:"regionId"`
+	AccountID         string    `bson:"accountId" json:"accountId"`
+	KeypairID         string    `bson:"keypairId" json:"keypairId"`
+	KeypairName       string    `bson:"keypairName" json:"keypairName"`
+	KeyType           string    `bson:"keyType" json:"keyType"`
+	PublicKey         string    `bson:"publicKey" json:"publicKey"`
+	FingerprintMD5    string    `bson:"fingerprintMd5" json:"fingerprintMd5"`
+	FingerprintSHA256 string    `bson:"fingerprintSha256" json:"fingerprintSha256"` // 云商只返回MD5指纹时为空
+	Description       string    `bson:"description" json:"description"`
+	CreatedTime       time.Time `bson:"createdTime" json:"createdTime"`
+	SyncedTime        time.Time `bson:"syncedTime" json:"syncedTime"`
 }
 
 // VPC vpc私有网络
@@ -200,4 +225,19 @@ type Eip struct {
 	Description         string    `bson:"description" json:"description"`
 	CreatedTime         time.Time `bson:"createdTime" json:"createdTime"`
 	SyncedTime          time.Time `bson:"syncedTime" json:"syncedTime"`
+
+	// 与云商无关的统一取值, 原始值保留在上面的字段中
+	UnifiedStatus     string `bson:"unifiedStatus" json:"unifiedStatus"`
+	UnifiedChargeType string `bson:"unifiedChargeType" json:"unifiedChargeType"`
+
+	// 云端已删除时由同步作业标记, 资源重新出现时被同步覆盖
+	Deleted     bool      `bson:"deleted" json:"deleted"`
+	DeletedTime time.Time `bson:"deletedTime" json:"deletedTime"`
+}
+
+// VncConsole 实例的管理终端
+type VncConsole struct {
+	InstanceID  string    `json:"instanceId"`
+	URL         string    `json:"url"`
+	ExpiredTime time.Time `json:"expiredTime"` // 过期后需要重新获取
 }
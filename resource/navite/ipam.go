@@ -0,0 +1,37 @@
+package navite
+
+import "time"
+
+// 地址管理表
+const (
+	IPPoolTable        = "ipPools"
+	IPReservationTable = "ipReservations"
+	IPAMVersionTable   = "ipamVersions"
+)
+
+// IPPool 环境的地址池, VPC网段从Supernet中分配
+type IPPool struct {
+	Environment string    `bson:"environment" json:"environment"`
+	Supernet    string    `bson:"supernet" json:"supernet"`
+	Description string    `bson:"description" json:"description"`
+	CreatedTime time.Time `bson:"createdTime" json:"createdTime"`
+}
+
+// IPReservation 已分配但可能还没有同步下来的网段
+//
+// * VPCID为空代表VPC网段, 否则为该VPC下的子网网段
+type IPReservation struct {
+	Environment string    `bson:"environment" json:"environment"`
+	VPCID       string    `bson:"vpcId" json:"vpcId"`
+	CidrBlock   string    `bson:"cidrBlock" json:"cidrBlock"`
+	Owner       string    `bson:"owner" json:"owner"`
+	CreatedTime time.Time `bson:"createdTime" json:"createdTime"`
+}
+
+// IPAMVersion 分配范围的版本, 每次预留网段后递增, 用于多个进程之间检测并发的分配
+//
+// * Scope为 vpc 或 subnet:VPCID, VPC网段在所有环境之间检查重叠, 共用一个版本
+type IPAMVersion struct {
+	Scope   string `bson:"scope" json:"scope"`
+	Version int64  `bson:"version" json:"version"`
+}
@@ -3,6 +3,9 @@ package navite
 import (
 	"ark-common/clients/mgo"
 	"ark-common/constants"
+	"errors"
+	"fmt"
+	"math/rand"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -13,6 +16,56 @@ import (
 // JobTable 作业
 const JobTable = "jobs"
 
+var (
+	// ErrInvalidTransition 状态机不允许的状态变更
+	ErrInvalidTransition = errors.New("invalid job status transition")
+	// ErrLostTransition 作业的状态或所属worker已被其他节点修改
+	ErrLostTransition = errors.New("job status changed by others")
+)
+
+// jobTransitions 作业状态机, pending→working→success/failed/cancel, pending的作业也可以直接取消
+//
+// * 可重试的失败进入retrying, 延时后重新进入working, 重新发布失败时直接置为failed
+var jobTransitions = map[string][]string{
+	constants.PENDINGJOB: {constants.WORKINGJOB, constants.CANCELJOB},
+	constants.WORKINGJOB: {constants.SUCCESSJOB, constants.FAILEDJOB, constants.CANCELJOB, constants.RETRYJOB},
+	constants.RETRYJOB:   {constants.WORKINGJOB, constants.CANCELJOB, constants.FAILEDJOB},
+}
+
+const (
+	// DefaultMaxAttempts 作业未指定MaxAttempts时的最大执行次数
+	DefaultMaxAttempts = 3
+	// DefaultBackoff 作业未指定Backoff时首次重试的等待时间
+	DefaultBackoff = 10 * time.Second
+	// DefaultMaxBackoff 作业未指定MaxBackoff时重试等待时间的上限
+	DefaultMaxBackoff = 10 * time.Minute
+	// BackoffJitter 重试等待时间的随机抖动比例, 避免同时失败的作业同时重试
+	BackoffJitter = 0.2
+	// BackoffBuckets 抖动后的等待时间取值的个数, 每个等待时间对应一个延时队列, 取值需要有限
+	BackoffBuckets = 5
+)
+
+// JobAttempt 作业的一次执行
+type JobAttempt struct {
+	Attempt    int       `bson:"attempt" json:"attempt"`
+	WorkerID   string    `bson:"workerId" json:"workerId"`
+	StartTime  time.Time `bson:"startTime" json:"startTime"`
+	EndTime    time.Time `bson:"endTime" json:"endTime"`
+	Status     string    `bson:"status" json:"status"`
+	ErrorClass string    `bson:"errorClass" json:"errorClass"`
+	Reason     string    `bson:"reason" json:"reason"`
+}
+
+// CanTransition 返回作业能否从from状态变更为to状态
+func CanTransition(from, to string) bool {
+	for _, status := range jobTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
 // Job 资源同步作业
 type Job struct {
 	Action      string      `bson:"action" json:"action"`
@@ -26,63 +79,258 @@ type Job struct {
 	Reason      string      `bson:"reason" json:"reason"`
 	CreatedTime time.Time   `bson:"createdTime" json:"createdTime"`
 	StatusTime  time.Time   `bson:"statusTime" json:"statusTime"`
+
+	// 执行作业的worker, 进入working时写入
+	WorkerID string `bson:"workerId" json:"workerId"`
+	// 最近一次状态变更或进度上报的时间, 用于判断作业是否已失效
+	HeartbeatTime time.Time `bson:"heartbeatTime" json:"heartbeatTime"`
+
+	// 重试策略, 为0或为空时使用默认值; MaxAttempts为1时不重试
+	MaxAttempts int           `bson:"maxAttempts" json:"maxAttempts"`
+	Backoff     time.Duration `bson:"backoff" json:"backoff"` // 首次重试的等待时间, 之后每次翻倍
+	MaxBackoff  time.Duration `bson:"maxBackoff" json:"maxBackoff"`
+	RetryOn     []string      `bson:"retryOn" json:"retryOn"` // 可重试的错误类型, 如 constants.ErrorThrottling
+
+	// 执行历史
+	Attempt      int           `bson:"attempt" json:"attempt"`
+	ErrorClass   string        `bson:"errorClass" json:"errorClass"` // 最近一次失败的错误类型
+	Attempts     []*JobAttempt `bson:"attempts" json:"attempts"`
+	DeadLettered bool          `bson:"deadLettered" json:"deadLettered"` // 重试耗尽后已投递到死信队列
+
+	// 父子作业和进度, 父作业的StepsTotal为子作业总数
+	ParentID   string `bson:"parentId" json:"parentId"`
+	Progress   int    `bson:"progress" json:"progress"` // 完成百分比
+	Step       string `bson:"step" json:"step"`         // 当前步骤
+	StepsDone  int    `bson:"stepsDone" json:"stepsDone"`
+	StepsTotal int    `bson:"stepsTotal" json:"stepsTotal"`
+}
+
+// Retryable 返回作业以errorClass失败后能否再次执行
+func (j *Job) Retryable(errorClass string) bool {
+	maxAttempts := j.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	if j.Attempt >= maxAttempts {
+		return false
+	}
+	retryOn := j.RetryOn
+	if len(retryOn) == 0 {
+		retryOn = constants.DefaultRetryableErrors
+	}
+	for _, class := range retryOn {
+		if class == errorClass {
+			return true
+		}
+	}
+	return false
+}
+
+// NextBackoff 返回第Attempt次执行失败后的等待时间, Backoff * 2^(Attempt-1), 不超过MaxBackoff
+//
+// * 在等待时间上增加±BackoffJitter比例的随机抖动, 与调度器的NextRunTime一致
+// * 抖动只取BackoffBuckets个均匀分布的值, 避免PushDelay为每个不同的等待时间创建延时队列
+func (j *Job) NextBackoff() time.Duration {
+	backoff, maxBackoff := j.baseBackoff()
+	bucket := rand.Intn(BackoffBuckets)
+	factor := 1 - BackoffJitter + 2*BackoffJitter*float64(bucket)/float64(BackoffBuckets-1)
+	if delay := time.Duration(float64(backoff) * factor); delay < maxBackoff {
+		return delay
+	}
+	return maxBackoff
+}
+
+// baseBackoff 返回未增加抖动的等待时间和等待时间的上限
+func (j *Job) baseBackoff() (backoff, maxBackoff time.Duration) {
+	backoff, maxBackoff = j.Backoff, j.MaxBackoff
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+	for i := 1; i < j.Attempt; i++ {
+		backoff *= 2
+		if backoff >= maxBackoff {
+			return maxBackoff, maxBackoff
+		}
+	}
+	if backoff > maxBackoff {
+		return maxBackoff, maxBackoff
+	}
+	return backoff, maxBackoff
 }
 
-func (j *Job) SetPending(rbd *mgo.Client) {
+// Stale 作业超过staleAfter没有状态变更或进度上报
+func (j *Job) Stale(staleAfter time.Duration, now time.Time) bool {
+	return !j.HeartbeatTime.After(now.Add(-staleAfter))
+}
+
+// SetPending 新建pending状态的作业
+func (j *Job) SetPending(rbd *mgo.Client) (err error) {
 	j.Status = constants.PENDINGJOB
 	j.CreatedTime = time.Now()
-	j.StatusTime = time.Now()
-	_, err := rbd.Table(JobTable).Insert(j)
+	j.StatusTime = j.CreatedTime
+	j.HeartbeatTime = j.CreatedTime
+	_, err = rbd.Table(JobTable).Insert(j)
 	if err != nil {
 		log.Errorf("insert into [%+v] failed: %v", j, err)
 	}
+	return
+}
+
+// SetWorking 由workerID领取pending的作业, 多个worker竞争时只有一个成功
+func (j *Job) SetWorking(rbd *mgo.Client, workerID string) (err error) {
+	return j.transition(rbd, constants.WORKINGJOB, workerID)
 }
 
-func (j *Job) SetWorking(rbd *mgo.Client) {
-	j.Status = constants.WORKINGJOB
-	j.StatusTime = time.Now()
+// SetFailed 作业执行失败, 失败原因为j.Reason
+func (j *Job) SetFailed(rbd *mgo.Client) (err error) {
+	return j.transition(rbd, constants.FAILEDJOB, j.WorkerID)
+}
+
+// SetCancel 取消作业
+func (j *Job) SetCancel(rbd *mgo.Client, reason string) (err error) {
+	j.Reason = reason
+	return j.transition(rbd, constants.CANCELJOB, j.WorkerID)
+}
+
+// SetRetrying 作业以可重试的错误失败, 失败原因为j.Reason, 错误类型为j.ErrorClass
+func (j *Job) SetRetrying(rbd *mgo.Client) (err error) {
+	return j.transition(rbd, constants.RETRYJOB, j.WorkerID)
+}
+
+// SetDeadLettered 标记作业已投递到死信队列
+func (j *Job) SetDeadLettered(rbd *mgo.Client) (err error) {
 	filter := bson.M{
 		"jobId": j.JobID,
 	}
-	err := rbd.Table(JobTable).Replace(filter, j, nil)
-	if err != nil {
-		log.Errorf("replace job [%+v] failed: %v", j, err)
+	update := bson.M{
+		"$set": bson.M{
+			"deadLettered": true,
+		},
 	}
+	if err = rbd.Table(JobTable).Update(filter, update, nil); err != nil {
+		log.Errorf("mark job [%s] dead lettered failed: %v", j.JobID, err)
+		return err
+	}
+	j.DeadLettered = true
+	return nil
 }
 
-func (j *Job) SetFailed(rbd *mgo.Client) {
-	j.Status = constants.FAILEDJOB
-	j.StatusTime = time.Now()
+// SetProgress 更新working作业的进度并刷新HeartbeatTime, 作业已被其他worker接管或已结束时返回ErrLostTransition
+func (j *Job) SetProgress(rbd *mgo.Client, done, total int, step string) (err error) {
+	now := time.Now()
+	progress := 0
+	if total > 0 {
+		progress = done * 100 / total
+	}
 	filter := bson.M{
-		"jobId": j.JobID,
+		"jobId":    j.JobID,
+		"status":   constants.WORKINGJOB,
+		"workerId": j.WorkerID,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"progress":      progress,
+			"step":          step,
+			"stepsDone":     done,
+			"stepsTotal":    total,
+			"heartbeatTime": now,
+		},
+	}
+	err = rbd.Table(JobTable).QueryAndUpdate(filter, update, nil).Err()
+	if mgo.IsNotFoundError(err) {
+		log.Warnf("job [%s] lost progress update", j.JobID)
+		return ErrLostTransition
 	}
-	err := rbd.Table(JobTable).Replace(filter, j, nil)
 	if err != nil {
-		log.Errorf("replace job [%+v] failed: %v", j, err)
+		log.Errorf("update job [%s] progress failed: %v", j.JobID, err)
+		return err
 	}
+	j.Progress, j.Step, j.StepsDone, j.StepsTotal = progress, step, done, total
+	j.HeartbeatTime = now
+	return nil
 }
 
-func (j *Job) SetCancel(rbd *mgo.Client, reason string) {
-	j.Status = constants.CANCELJOB
-	j.StatusTime = time.Now()
-	j.Reason = reason
+// SetSuccess 作业执行成功
+func (j *Job) SetSuccess(rbd *mgo.Client) (err error) {
+	return j.transition(rbd, constants.SUCCESSJOB, j.WorkerID)
+}
+
+// transition 按状态机变更作业状态
+//
+// * 以当前状态为条件更新, working之后还要求workerId一致, 条件不满足时返回ErrLostTransition
+func (j *Job) transition(rbd *mgo.Client, to, workerID string) (err error) {
+	from := j.Status
+	if !CanTransition(from, to) {
+		log.Errorf("job [%s] can not transit from [%s] to [%s]", j.JobID, from, to)
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+	}
 	filter := bson.M{
-		"jobId": j.JobID,
+		"jobId":  j.JobID,
+		"status": from,
+	}
+	if from == constants.WORKINGJOB {
+		filter["workerId"] = j.WorkerID
+	}
+	now := time.Now()
+	attempt, attempts := j.Attempt, j.recordAttempt(to, workerID, now)
+	if to == constants.WORKINGJOB {
+		attempt++
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":        to,
+			"statusTime":    now,
+			"heartbeatTime": now,
+			"reason":        j.Reason,
+			"workerId":      workerID,
+			"errorClass":    j.ErrorClass,
+			"attempt":       attempt,
+			"attempts":      attempts,
+		},
+	}
+	err = rbd.Table(JobTable).QueryAndUpdate(filter, update, nil).Err()
+	if mgo.IsNotFoundError(err) {
+		log.Warnf("job [%s] lost transition from [%s] to [%s]", j.JobID, from, to)
+		return fmt.Errorf("%w: %s -> %s", ErrLostTransition, from, to)
 	}
-	err := rbd.Table(JobTable).Replace(filter, j, nil)
 	if err != nil {
-		log.Errorf("replace job [%+v] failed: %v", j, err)
+		log.Errorf("update job [%s] status to [%s] failed: %v", j.JobID, to, err)
+		return err
 	}
+	j.Status = to
+	j.StatusTime = now
+	j.HeartbeatTime = now
+	j.WorkerID = workerID
+	j.Attempt = attempt
+	j.Attempts = attempts
+	return nil
 }
 
-func (j *Job) SetSuccess(rbd *mgo.Client) {
-	j.Status = constants.SUCCESSJOB
-	j.StatusTime = time.Now()
-	filter := bson.M{
-		"jobId": j.JobID,
+// recordAttempt 返回变更到to状态后的执行历史, 进入working时新增一次执行, 离开working时结束当前执行
+func (j *Job) recordAttempt(to, workerID string, now time.Time) []*JobAttempt {
+	attempts := make([]*JobAttempt, len(j.Attempts))
+	copy(attempts, j.Attempts)
+	if to == constants.WORKINGJOB {
+		return append(attempts, &JobAttempt{
+			Attempt:   j.Attempt + 1,
+			WorkerID:  workerID,
+			StartTime: now,
+			Status:    to,
+		})
 	}
-	err := rbd.Table(JobTable).Replace(filter, j, nil)
-	if err != nil {
-		log.Errorf("replace job [%+v] failed: %v", j, err)
+	if j.Status != constants.WORKINGJOB || len(attempts) == 0 {
+		return attempts
+	}
+	last := *attempts[len(attempts)-1]
+	last.EndTime = now
+	last.Status = to
+	if to != constants.SUCCESSJOB {
+		last.ErrorClass = j.ErrorClass
+		last.Reason = j.Reason
 	}
+	attempts[len(attempts)-1] = &last
+	return attempts
 }
@@ -0,0 +1,80 @@
+package navite
+
+import (
+	"ark-common/constants"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRecordAttempt(t *testing.T) {
+	start := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Minute)
+	Convey("记录作业的执行历史", t, func() {
+		job := &Job{Status: constants.PENDINGJOB}
+
+		Convey("进入working时新增一次执行", func() {
+			attempts := job.recordAttempt(constants.WORKINGJOB, "worker-1", start)
+			So(len(attempts), ShouldEqual, 1)
+			So(*attempts[0], ShouldResemble, JobAttempt{
+				Attempt:   1,
+				WorkerID:  "worker-1",
+				StartTime: start,
+				Status:    constants.WORKINGJOB,
+			})
+			So(job.Attempts, ShouldBeEmpty)
+		})
+
+		Convey("离开working时结束当前执行", func() {
+			job.Status, job.Attempt = constants.WORKINGJOB, 1
+			job.Attempts = job.recordAttempt(constants.WORKINGJOB, "worker-1", start)
+			job.Reason, job.ErrorClass = "Throttling.User", constants.ErrorThrottling
+
+			attempts := job.recordAttempt(constants.RETRYJOB, "worker-1", end)
+			So(len(attempts), ShouldEqual, 1)
+			So(attempts[0].EndTime, ShouldEqual, end)
+			So(attempts[0].Status, ShouldEqual, constants.RETRYJOB)
+			So(attempts[0].ErrorClass, ShouldEqual, constants.ErrorThrottling)
+			So(attempts[0].Reason, ShouldEqual, "Throttling.User")
+			// 不修改作业已有的执行历史, 状态变更失败时保持原样
+			So(job.Attempts[0].Status, ShouldEqual, constants.WORKINGJOB)
+			So(job.Attempts[0].EndTime.IsZero(), ShouldBeTrue)
+
+			Convey("成功时不记录失败原因", func() {
+				attempts := job.recordAttempt(constants.SUCCESSJOB, "worker-1", end)
+				So(attempts[0].Status, ShouldEqual, constants.SUCCESSJOB)
+				So(attempts[0].Reason, ShouldBeEmpty)
+			})
+		})
+
+		Convey("重试时追加新的执行", func() {
+			job.Status, job.Attempt = constants.RETRYJOB, 1
+			job.Attempts = []*JobAttempt{{Attempt: 1, Status: constants.RETRYJOB}}
+			attempts := job.recordAttempt(constants.WORKINGJOB, "worker-2", end)
+			So(len(attempts), ShouldEqual, 2)
+			So(attempts[1].Attempt, ShouldEqual, 2)
+			So(attempts[1].WorkerID, ShouldEqual, "worker-2")
+
+			Convey("retrying直接置为failed时不修改执行历史", func() {
+				attempts := job.recordAttempt(constants.FAILEDJOB, "worker-1", end)
+				So(attempts, ShouldResemble, job.Attempts)
+			})
+		})
+	})
+}
+
+func TestBaseBackoff(t *testing.T) {
+	Convey("未抖动的等待时间每次翻倍, 不超过上限", t, func() {
+		job := &Job{Attempt: 0, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+		for _, c := range []struct {
+			attempt int
+			backoff time.Duration
+		}{{0, time.Second}, {2, 2 * time.Second}, {3, 4 * time.Second}, {4, 5 * time.Second}} {
+			job.Attempt = c.attempt
+			backoff, maxBackoff := job.baseBackoff()
+			So(backoff, ShouldEqual, c.backoff)
+			So(maxBackoff, ShouldEqual, 5*time.Second)
+		}
+	})
+}
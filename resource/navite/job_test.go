@@ -0,0 +1,187 @@
+package navite_test
+
+import (
+	"ark-common/constants"
+	"ark-common/resource/navite"
+	"ark-common/utils/testenv"
+	"ark-common/utils/tool"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCanTransition(t *testing.T) {
+	cases := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{constants.PENDINGJOB, constants.WORKINGJOB, true},
+		{constants.PENDINGJOB, constants.CANCELJOB, true},
+		{constants.WORKINGJOB, constants.SUCCESSJOB, true},
+		{constants.WORKINGJOB, constants.FAILEDJOB, true},
+		{constants.WORKINGJOB, constants.CANCELJOB, true},
+		{constants.WORKINGJOB, constants.RETRYJOB, true},
+		{constants.RETRYJOB, constants.WORKINGJOB, true},
+		{constants.RETRYJOB, constants.CANCELJOB, true},
+		{constants.RETRYJOB, constants.FAILEDJOB, true},
+
+		{constants.PENDINGJOB, constants.SUCCESSJOB, false},
+		{constants.PENDINGJOB, constants.FAILEDJOB, false},
+		{constants.PENDINGJOB, constants.RETRYJOB, false},
+		{constants.WORKINGJOB, constants.WORKINGJOB, false},
+		{constants.WORKINGJOB, constants.PENDINGJOB, false},
+		{constants.RETRYJOB, constants.SUCCESSJOB, false},
+		{constants.SUCCESSJOB, constants.WORKINGJOB, false},
+		{constants.SUCCESSJOB, constants.FAILEDJOB, false},
+		{constants.FAILEDJOB, constants.WORKINGJOB, false},
+		{constants.FAILEDJOB, constants.RETRYJOB, false},
+		{constants.CANCELJOB, constants.WORKINGJOB, false},
+		{"", constants.WORKINGJOB, false},
+	}
+	Convey("作业状态机", t, func() {
+		for _, c := range cases {
+			So(navite.CanTransition(c.from, c.to), ShouldEqual, c.allowed)
+		}
+	})
+}
+
+func TestJobRetryable(t *testing.T) {
+	Convey("作业失败后能否重试", t, func() {
+		job := &navite.Job{Attempt: 1}
+		Convey("默认重试限流、网络和服务端错误", func() {
+			So(job.Retryable(constants.ErrorThrottling), ShouldBeTrue)
+			So(job.Retryable(constants.ErrorNetwork), ShouldBeTrue)
+			So(job.Retryable(constants.ErrorServer), ShouldBeTrue)
+			So(job.Retryable(constants.ErrorUnknown), ShouldBeFalse)
+			So(job.Retryable(""), ShouldBeFalse)
+		})
+		Convey("只重试RetryOn中的错误", func() {
+			job.RetryOn = []string{constants.ErrorUnknown}
+			So(job.Retryable(constants.ErrorUnknown), ShouldBeTrue)
+			So(job.Retryable(constants.ErrorThrottling), ShouldBeFalse)
+		})
+		Convey("执行次数达到上限后不重试", func() {
+			job.Attempt = navite.DefaultMaxAttempts
+			So(job.Retryable(constants.ErrorThrottling), ShouldBeFalse)
+
+			job.MaxAttempts = navite.DefaultMaxAttempts + 1
+			So(job.Retryable(constants.ErrorThrottling), ShouldBeTrue)
+
+			job.MaxAttempts, job.Attempt = 1, 1
+			So(job.Retryable(constants.ErrorThrottling), ShouldBeFalse)
+		})
+	})
+}
+
+// backoffBuckets 返回基础等待时间base抖动后所有可能的取值
+func backoffBuckets(base, maxBackoff time.Duration) (buckets []time.Duration) {
+	for i := 0; i < navite.BackoffBuckets; i++ {
+		factor := 1 - navite.BackoffJitter + 2*navite.BackoffJitter*float64(i)/float64(navite.BackoffBuckets-1)
+		delay := time.Duration(float64(base) * factor)
+		if delay > maxBackoff {
+			delay = maxBackoff
+		}
+		buckets = append(buckets, delay)
+	}
+	return buckets
+}
+
+func TestJobNextBackoff(t *testing.T) {
+	Convey("重试等待时间每次翻倍, 并在有限的几个值中抖动", t, func() {
+		Convey("使用默认值", func() {
+			job := &navite.Job{Attempt: 1}
+			So(job.NextBackoff(), ShouldBeIn, backoffBuckets(navite.DefaultBackoff, navite.DefaultMaxBackoff))
+			job.Attempt = 3
+			So(job.NextBackoff(), ShouldBeIn, backoffBuckets(4*navite.DefaultBackoff, navite.DefaultMaxBackoff))
+			job.Attempt = 100
+			So(job.NextBackoff(), ShouldBeIn, backoffBuckets(navite.DefaultMaxBackoff, navite.DefaultMaxBackoff))
+		})
+		Convey("使用作业的配置", func() {
+			job := &navite.Job{Attempt: 0, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+			So(job.NextBackoff(), ShouldBeIn, backoffBuckets(time.Second, 5*time.Second))
+			job.Attempt = 3
+			So(job.NextBackoff(), ShouldBeIn, backoffBuckets(4*time.Second, 5*time.Second))
+			job.Attempt = 4
+			So(job.NextBackoff(), ShouldBeIn, backoffBuckets(5*time.Second, 5*time.Second))
+		})
+		Convey("抖动后不超过上限", func() {
+			job := &navite.Job{Attempt: 1, Backoff: time.Minute, MaxBackoff: time.Second}
+			So(job.NextBackoff(), ShouldBeIn, backoffBuckets(time.Second, time.Second))
+			So(job.NextBackoff(), ShouldBeLessThanOrEqualTo, time.Second)
+		})
+		Convey("多次重试的等待时间不完全相同", func() {
+			job := &navite.Job{Attempt: 2}
+			seen := map[time.Duration]bool{}
+			for i := 0; i < 100; i++ {
+				seen[job.NextBackoff()] = true
+			}
+			So(len(seen), ShouldBeGreaterThan, 1)
+			So(len(seen), ShouldBeLessThanOrEqualTo, navite.BackoffBuckets)
+		})
+	})
+}
+
+func TestJobStale(t *testing.T) {
+	Convey("按心跳判断作业是否失效", t, func() {
+		now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+		job := &navite.Job{StatusTime: now.Add(-2 * time.Hour), HeartbeatTime: now.Add(-time.Minute)}
+		So(job.Stale(time.Hour, now), ShouldBeFalse)
+		job.HeartbeatTime = now.Add(-time.Hour)
+		So(job.Stale(time.Hour, now), ShouldBeTrue)
+	})
+}
+
+func TestSetWorking(t *testing.T) {
+	rbd := testenv.Mongo(t)
+	Convey("多个worker竞争同一个pending作业", t, func() {
+		job := &navite.Job{
+			Action:    constants.HandleSyncInstance,
+			CloudName: constants.Aliyun,
+			JobID:     tool.UUID(),
+			Owner:     constants.SYSTEMUSER,
+		}
+		So(job.SetPending(rbd), ShouldBeNil)
+		defer rbd.Table(navite.JobTable).DeleteMany(bson.M{"jobId": job.JobID})
+
+		// 两个worker各自持有从队列中收到的pending作业
+		first, second := *job, *job
+		So(first.SetWorking(rbd, "worker-1"), ShouldBeNil)
+		So(first.Status, ShouldEqual, constants.WORKINGJOB)
+		So(first.Attempt, ShouldEqual, 1)
+
+		err := second.SetWorking(rbd, "worker-2")
+		So(errors.Is(err, navite.ErrLostTransition), ShouldBeTrue)
+		So(second.Status, ShouldEqual, constants.PENDINGJOB)
+
+		stored := &navite.Job{}
+		So(rbd.Table(navite.JobTable).QueryOne(bson.M{"jobId": job.JobID}, stored, nil), ShouldBeNil)
+		So(stored.Status, ShouldEqual, constants.WORKINGJOB)
+		So(stored.WorkerID, ShouldEqual, "worker-1")
+		So(len(stored.Attempts), ShouldEqual, 1)
+
+		Convey("上报进度刷新心跳", func() {
+			before := first.HeartbeatTime
+			time.Sleep(10 * time.Millisecond)
+			So(first.SetProgress(rbd, 1, 2, "page 1"), ShouldBeNil)
+			stored := &navite.Job{}
+			So(rbd.Table(navite.JobTable).QueryOne(bson.M{"jobId": job.JobID}, stored, nil), ShouldBeNil)
+			So(stored.HeartbeatTime.After(before), ShouldBeTrue)
+			So(stored.StatusTime.Before(stored.HeartbeatTime), ShouldBeTrue)
+		})
+
+		Convey("非法的状态变更不写入", func() {
+			err := first.SetWorking(rbd, "worker-1")
+			So(errors.Is(err, navite.ErrInvalidTransition), ShouldBeTrue)
+		})
+
+		Convey("被其他worker接管后无法结束作业", func() {
+			second.Status, second.WorkerID = constants.WORKINGJOB, "worker-2"
+			err := second.SetSuccess(rbd)
+			So(errors.Is(err, navite.ErrLostTransition), ShouldBeTrue)
+			So(first.SetSuccess(rbd), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,189 @@
+package navite
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/constants"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// JobTree 作业及其子作业
+type JobTree struct {
+	Job      *Job       `json:"job"`
+	Children []*JobTree `json:"children"`
+}
+
+// ListChildJobs 返回父作业的直接子作业
+func ListChildJobs(rbd *mgo.Client, parentID string) (jobList []*Job, err error) {
+	filter := bson.M{
+		"parentId": parentID,
+	}
+	jobList = []*Job{}
+	mctx := context.Background()
+	cur, err := rbd.Table(JobTable).Query(filter, 0, 0, nil)
+	if err != nil {
+		log.Errorf("filter [%v] jobs failed: %v", filter, err)
+		return jobList, err
+	}
+	defer cur.Close(mctx)
+	if err = cur.All(mctx, &jobList); err != nil {
+		log.Errorf("decord mgo document failed: %v", err)
+	}
+	return jobList, err
+}
+
+// AggregateParentJob 按子作业的状态更新父作业的进度, 子作业全部结束后结束父作业
+//
+// * 所有子作业成功时父作业成功, 否则父作业失败
+// * 父作业的StepsTotal为0时子作业还未全部创建, 只更新不结束
+// * 多个子作业同时结束时只有一个能结束父作业, 其余的ErrLostTransition被忽略
+// * 并发汇总时读到的子作业状态可能较旧, 进度只增不减
+func AggregateParentJob(rbd *mgo.Client, parentID string) (err error) {
+	parent := &Job{}
+	filter := bson.M{
+		"jobId": parentID,
+	}
+	if err = rbd.Table(JobTable).QueryOne(filter, parent, nil); err != nil {
+		log.Errorf("query parent job [%s] failed: %v", parentID, err)
+		return err
+	}
+	if parent.Status != constants.WORKINGJOB || parent.StepsTotal == 0 {
+		return nil
+	}
+	children, err := ListChildJobs(rbd, parentID)
+	if err != nil {
+		return err
+	}
+
+	done, failed := 0, 0
+	for _, child := range children {
+		switch child.Status {
+		case constants.SUCCESSJOB:
+			done++
+		case constants.FAILEDJOB, constants.CANCELJOB:
+			done++
+			failed++
+		}
+	}
+	if err = parent.raiseProgress(rbd, done); err != nil {
+		return ignoreLost(err)
+	}
+	if done < parent.StepsTotal {
+		return nil
+	}
+	if failed == 0 {
+		return ignoreLost(parent.SetSuccess(rbd))
+	}
+	parent.Reason = fmt.Sprintf("%d of %d child jobs failed", failed, parent.StepsTotal)
+	return ignoreLost(parent.SetFailed(rbd))
+}
+
+// raiseProgress 父作业的已完成子作业数增加到done, 小于已记录的值时不变
+func (j *Job) raiseProgress(rbd *mgo.Client, done int) (err error) {
+	filter := bson.M{
+		"jobId":    j.JobID,
+		"status":   constants.WORKINGJOB,
+		"workerId": j.WorkerID,
+	}
+	update := bson.M{
+		"$max": bson.M{
+			"stepsDone": done,
+			"progress":  done * 100 / j.StepsTotal,
+		},
+	}
+	err = rbd.Table(JobTable).QueryAndUpdate(filter, update, nil).Err()
+	if mgo.IsNotFoundError(err) {
+		log.Warnf("job [%s] lost progress update", j.JobID)
+		return ErrLostTransition
+	}
+	if err != nil {
+		log.Errorf("update job [%s] progress failed: %v", j.JobID, err)
+		return err
+	}
+	if done > j.StepsDone {
+		j.StepsDone, j.Progress = done, done*100/j.StepsTotal
+	}
+	return nil
+}
+
+// cancelStaleChildJobs 取消超过staleAfter没有心跳的未结束子作业, 返回取消的数量
+//
+// * 执行子作业的worker崩溃或消息丢失时子作业不会结束, 父作业会一直停留在working
+func cancelStaleChildJobs(rbd *mgo.Client, parentID string, staleAfter time.Duration, now time.Time) (cancelled int, err error) {
+	children, err := ListChildJobs(rbd, parentID)
+	if err != nil {
+		return 0, err
+	}
+	for _, child := range children {
+		switch child.Status {
+		case constants.PENDINGJOB, constants.WORKINGJOB, constants.RETRYJOB:
+		default:
+			continue
+		}
+		if !child.Stale(staleAfter, now) {
+			continue
+		}
+		reason := fmt.Sprintf("no heartbeat since %s", child.HeartbeatTime.Format(time.RFC3339))
+		if e := ignoreLost(child.SetCancel(rbd, reason)); e != nil {
+			err = e
+			continue
+		}
+		cancelled++
+	}
+	return cancelled, err
+}
+
+// SweepParentJobs 巡检working的父作业, 取消已失效的子作业后重新汇总
+//
+// * 超过staleAfter仍未记录子作业总数的父作业视为创建中断, 直接取消
+// * 父作业由NewParentJob以constants.SYSTEMUSER置为working, 以此区分worker执行的作业
+func SweepParentJobs(rbd *mgo.Client, staleAfter time.Duration, now time.Time) (err error) {
+	filter := bson.M{
+		"status":   constants.WORKINGJOB,
+		"workerId": constants.SYSTEMUSER,
+	}
+	parentList := []*Job{}
+	mctx := context.Background()
+	cur, err := rbd.Table(JobTable).Query(filter, 0, 0, nil)
+	if err != nil {
+		log.Errorf("filter [%v] jobs failed: %v", filter, err)
+		return err
+	}
+	defer cur.Close(mctx)
+	if err = cur.All(mctx, &parentList); err != nil {
+		log.Errorf("decord mgo document failed: %v", err)
+		return err
+	}
+
+	for _, parent := range parentList {
+		if parent.StepsTotal == 0 {
+			if parent.Stale(staleAfter, now) {
+				ignoreLost(parent.SetCancel(rbd, "child jobs not sealed"))
+			}
+			continue
+		}
+		cancelled, e := cancelStaleChildJobs(rbd, parent.JobID, staleAfter, now)
+		if e != nil {
+			err = e
+		}
+		if cancelled > 0 {
+			log.Warnf("cancel %d stale child jobs of [%s]", cancelled, parent.JobID)
+		}
+		if e = AggregateParentJob(rbd, parent.JobID); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func ignoreLost(err error) error {
+	if errors.Is(err, ErrLostTransition) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,159 @@
+package navite_test
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/constants"
+	"ark-common/misc"
+	"ark-common/resource/manage"
+	"ark-common/resource/navite"
+	"ark-common/utils/testenv"
+	"ark-common/utils/tool"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const testAccountID = "jobtree-test"
+
+// newChildJob 创建父作业下已被worker领取的子作业
+func newChildJob(rbd *mgo.Client, parent *navite.Job) *navite.Job {
+	child := &navite.Job{
+		Action:    constants.HandleSyncInstance,
+		CloudName: parent.CloudName,
+		AccountID: parent.AccountID,
+		JobID:     tool.UUID(),
+		Owner:     parent.Owner,
+		ParentID:  parent.JobID,
+	}
+	So(child.SetPending(rbd), ShouldBeNil)
+	So(child.SetWorking(rbd, "worker-1"), ShouldBeNil)
+	return child
+}
+
+func loadJob(rbd *mgo.Client, jobID string) *navite.Job {
+	job := &navite.Job{}
+	So(rbd.Table(navite.JobTable).QueryOne(bson.M{"jobId": jobID}, job, nil), ShouldBeNil)
+	return job
+}
+
+func TestAggregateParentJob(t *testing.T) {
+	rbd := testenv.Mongo(t)
+	Convey("子作业结束后汇总父作业", t, func() {
+		defer rbd.Table(navite.JobTable).DeleteMany(bson.M{"accountId": testAccountID})
+		parent, err := misc.NewParentJob(rbd, constants.HandleSyncAccount, testAccountID, constants.Aliyun, constants.SYSTEMUSER)
+		So(err, ShouldBeNil)
+		first, second := newChildJob(rbd, parent), newChildJob(rbd, parent)
+
+		Convey("记录总数前结束的子作业不结束父作业", func() {
+			So(first.SetSuccess(rbd), ShouldBeNil)
+			So(navite.AggregateParentJob(rbd, parent.JobID), ShouldBeNil)
+			So(loadJob(rbd, parent.JobID).Status, ShouldEqual, constants.WORKINGJOB)
+
+			So(misc.SealParentJob(rbd, parent, 2), ShouldBeNil)
+			stored := loadJob(rbd, parent.JobID)
+			So(stored.Status, ShouldEqual, constants.WORKINGJOB)
+			So(stored.StepsDone, ShouldEqual, 1)
+			So(stored.StepsTotal, ShouldEqual, 2)
+			So(stored.Progress, ShouldEqual, 50)
+		})
+
+		Convey("子作业全部成功时父作业成功", func() {
+			So(misc.SealParentJob(rbd, parent, 2), ShouldBeNil)
+			So(first.SetSuccess(rbd), ShouldBeNil)
+			So(navite.AggregateParentJob(rbd, parent.JobID), ShouldBeNil)
+			So(second.SetSuccess(rbd), ShouldBeNil)
+			So(navite.AggregateParentJob(rbd, parent.JobID), ShouldBeNil)
+			// 重复汇总已结束的父作业不报错
+			So(navite.AggregateParentJob(rbd, parent.JobID), ShouldBeNil)
+
+			tree, errCode := manage.GetJobTree(rbd, parent.JobID)
+			So(errCode, ShouldEqual, constants.Success)
+			So(tree.Job.Status, ShouldEqual, constants.SUCCESSJOB)
+			So(tree.Job.StepsDone, ShouldEqual, 2)
+			So(tree.Job.Progress, ShouldEqual, 100)
+			So(len(tree.Children), ShouldEqual, 2)
+			for _, child := range tree.Children {
+				So(child.Job.Status, ShouldEqual, constants.SUCCESSJOB)
+				So(child.Children, ShouldBeEmpty)
+			}
+		})
+
+		Convey("有子作业失败时父作业失败", func() {
+			So(misc.SealParentJob(rbd, parent, 2), ShouldBeNil)
+			So(first.SetSuccess(rbd), ShouldBeNil)
+			So(navite.AggregateParentJob(rbd, parent.JobID), ShouldBeNil)
+			second.Reason = "Forbidden.RAM"
+			So(second.SetFailed(rbd), ShouldBeNil)
+			So(navite.AggregateParentJob(rbd, parent.JobID), ShouldBeNil)
+
+			tree, errCode := manage.GetJobTree(rbd, parent.JobID)
+			So(errCode, ShouldEqual, constants.Success)
+			So(tree.Job.Status, ShouldEqual, constants.FAILEDJOB)
+			So(tree.Job.Reason, ShouldEqual, "1 of 2 child jobs failed")
+			So(len(tree.Children), ShouldEqual, 2)
+		})
+
+		Convey("取消的子作业视为失败", func() {
+			So(misc.SealParentJob(rbd, parent, 2), ShouldBeNil)
+			So(first.SetCancel(rbd, "canceled by user"), ShouldBeNil)
+			So(second.SetSuccess(rbd), ShouldBeNil)
+			So(navite.AggregateParentJob(rbd, parent.JobID), ShouldBeNil)
+			So(loadJob(rbd, parent.JobID).Status, ShouldEqual, constants.FAILEDJOB)
+		})
+
+		Convey("进度只增不减", func() {
+			So(misc.SealParentJob(rbd, parent, 2), ShouldBeNil)
+			// 模拟其他节点已汇总到更大的进度
+			rbd.Table(navite.JobTable).Update(bson.M{"jobId": parent.JobID}, bson.M{"$set": bson.M{"stepsDone": 1, "progress": 50}}, nil)
+			So(navite.AggregateParentJob(rbd, parent.JobID), ShouldBeNil)
+			stored := loadJob(rbd, parent.JobID)
+			So(stored.StepsDone, ShouldEqual, 1)
+			So(stored.Progress, ShouldEqual, 50)
+		})
+	})
+
+	Convey("没有子作业时取消父作业", t, func() {
+		defer rbd.Table(navite.JobTable).DeleteMany(bson.M{"accountId": testAccountID})
+		parent, err := misc.NewParentJob(rbd, constants.HandleSyncAccount, testAccountID, constants.Aliyun, constants.SYSTEMUSER)
+		So(err, ShouldBeNil)
+		So(misc.SealParentJob(rbd, parent, 0), ShouldBeNil)
+
+		tree, errCode := manage.GetJobTree(rbd, parent.JobID)
+		So(errCode, ShouldEqual, constants.Success)
+		So(tree.Job.Status, ShouldEqual, constants.CANCELJOB)
+		So(tree.Children, ShouldBeEmpty)
+	})
+}
+
+func TestSweepParentJobs(t *testing.T) {
+	rbd := testenv.Mongo(t)
+	Convey("巡检停留在working的父作业", t, func() {
+		defer rbd.Table(navite.JobTable).DeleteMany(bson.M{"accountId": testAccountID})
+		parent, err := misc.NewParentJob(rbd, constants.HandleSyncAccount, testAccountID, constants.Aliyun, constants.SYSTEMUSER)
+		So(err, ShouldBeNil)
+		first, second := newChildJob(rbd, parent), newChildJob(rbd, parent)
+
+		Convey("取消失效的子作业并结束父作业", func() {
+			So(misc.SealParentJob(rbd, parent, 2), ShouldBeNil)
+			So(first.SetSuccess(rbd), ShouldBeNil)
+			So(navite.AggregateParentJob(rbd, parent.JobID), ShouldBeNil)
+
+			// 未失效时不取消
+			So(navite.SweepParentJobs(rbd, time.Hour, time.Now()), ShouldBeNil)
+			So(loadJob(rbd, second.JobID).Status, ShouldEqual, constants.WORKINGJOB)
+
+			So(navite.SweepParentJobs(rbd, time.Hour, time.Now().Add(2*time.Hour)), ShouldBeNil)
+			So(loadJob(rbd, second.JobID).Status, ShouldEqual, constants.CANCELJOB)
+			stored := loadJob(rbd, parent.JobID)
+			So(stored.Status, ShouldEqual, constants.FAILEDJOB)
+			So(stored.StepsDone, ShouldEqual, 2)
+		})
+
+		Convey("取消未记录子作业总数的父作业", func() {
+			So(navite.SweepParentJobs(rbd, time.Hour, time.Now().Add(2*time.Hour)), ShouldBeNil)
+			So(loadJob(rbd, parent.JobID).Status, ShouldEqual, constants.CANCELJOB)
+		})
+	})
+}
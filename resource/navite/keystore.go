@@ -0,0 +1,57 @@
+package navite
+
+import (
+	"ark-common/constants"
+	"ark-common/utils/tool"
+	"strings"
+	"time"
+)
+
+// 私钥库表
+const (
+	KeystoreTable      = "keystore"
+	KeystoreAuditTable = "keystoreAudits"
+)
+
+// KeystoreEntry 私钥库中加密保存的私钥
+type KeystoreEntry struct {
+	AccountID         string    `bson:"accountId" json:"accountId"`
+	KeypairName       string    `bson:"keypairName" json:"keypairName"`
+	KeyType           string    `bson:"keyType" json:"keyType"`
+	FingerprintMD5    string    `bson:"fingerprintMd5" json:"fingerprintMd5"`
+	FingerprintSHA256 string    `bson:"fingerprintSha256" json:"fingerprintSha256"`
+	PrivateKey        string    `bson:"privateKey" json:"-"` // 加密后的私钥
+	Owner             string    `bson:"owner" json:"owner"`
+	CreatedTime       time.Time `bson:"createdTime" json:"createdTime"`
+}
+
+// KeystoreAudit 私钥库的操作记录
+type KeystoreAudit struct {
+	FingerprintSHA256 string    `bson:"fingerprintSha256" json:"fingerprintSha256"`
+	Operator          string    `bson:"operator" json:"operator"`
+	Action            string    `bson:"action" json:"action"` // store/retrieve/delete
+	Allowed           bool      `bson:"allowed" json:"allowed"`
+	Reason            string    `bson:"reason" json:"reason"`
+	CreatedTime       time.Time `bson:"createdTime" json:"createdTime"`
+}
+
+// SetFingerprint 根据公钥计算密钥类型和指纹
+func (k *Keypair) SetFingerprint() (err error) {
+	if k.PublicKey == "" {
+		return nil
+	}
+	if k.FingerprintMD5, k.FingerprintSHA256, err = tool.Fingerprint(k.PublicKey); err != nil {
+		return err
+	}
+	switch prefix := strings.Fields(k.PublicKey)[0]; {
+	case prefix == "ssh-ed25519":
+		k.KeyType = constants.KeyTypeEd25519
+	case strings.HasPrefix(prefix, "ecdsa-"):
+		k.KeyType = constants.KeyTypeECDSA
+	case prefix == "ssh-rsa":
+		k.KeyType = constants.KeyTypeRSA
+	default:
+		k.KeyType = prefix
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package navite
+
+import (
+	"sort"
+	"time"
+)
+
+// InstanceMetricTable 实例监控数据表
+const InstanceMetricTable = "instanceMetrics"
+
+const (
+	// MetricInterval 降采样后的数据粒度
+	MetricInterval = 5 * time.Minute
+	// MetricRetention 监控数据的保留时长
+	MetricRetention = 30 * 24 * time.Hour
+)
+
+// MetricPoint 监控数据点
+type MetricPoint struct {
+	CloudName  string    `bson:"cloudName" json:"cloudName"`
+	AccountID  string    `bson:"accountId" json:"accountId"`
+	RegionID   string    `bson:"regionId" json:"regionId"`
+	InstanceID string    `bson:"instanceId" json:"instanceId"`
+	MetricName string    `bson:"metricName" json:"metricName"`
+	Timestamp  time.Time `bson:"timestamp" json:"timestamp"`
+	Average    float64   `bson:"average" json:"average"`
+	Maximum    float64   `bson:"maximum" json:"maximum"`
+	Minimum    float64   `bson:"minimum" json:"minimum"`
+}
+
+// DownsampleMetric 将监控数据按interval聚合, 每个时间窗口取平均值、最大值和最小值
+func DownsampleMetric(points []*MetricPoint, interval time.Duration) (sampled []*MetricPoint) {
+	buckets := map[int64]*MetricPoint{}
+	counts := map[int64]int{}
+	for _, p := range points {
+		ts := p.Timestamp.Truncate(interval)
+		key := ts.Unix()
+		b, ok := buckets[key]
+		if !ok {
+			b = &MetricPoint{
+				CloudName:  p.CloudName,
+				AccountID:  p.AccountID,
+				RegionID:   p.RegionID,
+				InstanceID: p.InstanceID,
+				MetricName: p.MetricName,
+				Timestamp:  ts,
+				Maximum:    p.Maximum,
+				Minimum:    p.Minimum,
+			}
+			buckets[key] = b
+			sampled = append(sampled, b)
+		}
+		b.Average += p.Average
+		counts[key]++
+		if p.Maximum > b.Maximum {
+			b.Maximum = p.Maximum
+		}
+		if p.Minimum < b.Minimum {
+			b.Minimum = p.Minimum
+		}
+	}
+	for _, b := range sampled {
+		b.Average = b.Average / float64(counts[b.Timestamp.Unix()])
+	}
+	sort.Slice(sampled, func(i, j int) bool {
+		return sampled[i].Timestamp.Before(sampled[j].Timestamp)
+	})
+	return sampled
+}
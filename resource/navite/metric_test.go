@@ -0,0 +1,54 @@
+package navite_test
+
+import (
+	"ark-common/resource/navite"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDownsampleMetric(t *testing.T) {
+	base := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	point := func(offset time.Duration, avg, max, min float64) *navite.MetricPoint {
+		return &navite.MetricPoint{
+			InstanceID: "i-test",
+			MetricName: "cpu",
+			Timestamp:  base.Add(offset),
+			Average:    avg,
+			Maximum:    max,
+			Minimum:    min,
+		}
+	}
+	Convey("监控数据降采样", t, func() {
+		Convey("空数据返回空结果", func() {
+			So(navite.DownsampleMetric(nil, navite.MetricInterval), ShouldBeEmpty)
+		})
+		Convey("同一窗口内取平均值、最大值和最小值", func() {
+			sampled := navite.DownsampleMetric([]*navite.MetricPoint{
+				point(0, 10, 20, 5),
+				point(time.Minute, 20, 40, 2),
+				point(4*time.Minute, 30, 30, 8),
+			}, navite.MetricInterval)
+			So(len(sampled), ShouldEqual, 1)
+			So(sampled[0].Timestamp, ShouldEqual, base)
+			So(sampled[0].Average, ShouldEqual, 20)
+			So(sampled[0].Maximum, ShouldEqual, 40)
+			So(sampled[0].Minimum, ShouldEqual, 2)
+			So(sampled[0].InstanceID, ShouldEqual, "i-test")
+		})
+		Convey("跨窗口的数据按时间升序输出", func() {
+			sampled := navite.DownsampleMetric([]*navite.MetricPoint{
+				point(11*time.Minute, 3, 3, 3),
+				point(0, 1, 1, 1),
+				point(6*time.Minute, 2, 2, 2),
+				point(5*time.Minute, 4, 4, 4),
+			}, navite.MetricInterval)
+			So(len(sampled), ShouldEqual, 3)
+			So(sampled[0].Timestamp, ShouldEqual, base)
+			So(sampled[1].Timestamp, ShouldEqual, base.Add(5*time.Minute))
+			So(sampled[1].Average, ShouldEqual, 3)
+			So(sampled[2].Timestamp, ShouldEqual, base.Add(10*time.Minute))
+		})
+	})
+}
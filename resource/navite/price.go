@@ -0,0 +1,11 @@
+package navite
+
+// Price 资源询价结果
+type Price struct {
+	CloudName     string  `json:"cloudName"`
+	ResourceType  string  `json:"resourceType"` // instance/disk/eip
+	Currency      string  `json:"currency"`
+	PriceUnit     string  `json:"priceUnit"`     // hour/month
+	OriginalPrice float64 `json:"originalPrice"` // 原价
+	DiscountPrice float64 `json:"discountPrice"` // 折扣后的价格
+}
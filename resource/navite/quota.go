@@ -0,0 +1,24 @@
+package navite
+
+import "time"
+
+// QuotaTable 云商配额表
+const QuotaTable = "quotas"
+
+// Quota 账号在地域下的资源配额
+type Quota struct {
+	CloudName  string    `bson:"cloudName" json:"cloudName"`
+	AccountID  string    `bson:"accountId" json:"accountId"`
+	RegionID   string    `bson:"regionId" json:"regionId"`
+	ZoneID     string    `bson:"zoneId" json:"zoneId"` // 为空代表整个地域的配额
+	QuotaName  string    `bson:"quotaName" json:"quotaName"`
+	RawName    string    `bson:"rawName" json:"rawName"` // 云商返回的配额名
+	Limit      int64     `bson:"limit" json:"limit"`
+	Used       int64     `bson:"used" json:"used"`
+	SyncedTime time.Time `bson:"syncedTime" json:"syncedTime"`
+}
+
+// Remaining 返回剩余配额
+func (q *Quota) Remaining() int64 {
+	return q.Limit - q.Used
+}
@@ -0,0 +1,29 @@
+package navite
+
+// 可达性分析的检查阶段
+const (
+	StageNetwork = "network" // VPC/公网路径
+	StageSubnet  = "subnet"  // 实例内网IP所在的子网
+	StageEgress  = "egress"  // 源实例安全组出站
+	StageIngress = "ingress" // 目标实例安全组入站
+)
+
+// ReachabilityStep 可达性分析中的一个检查阶段
+type ReachabilityStep struct {
+	Stage   string             `json:"stage"`
+	Allowed bool               `json:"allowed"`
+	Reason  string             `json:"reason"`
+	GroupID string             `json:"groupId"` // 决定结果的规则所在的安全组
+	Rule    *SecurityGroupRule `json:"rule"`    // 决定结果的规则, 没有匹配的规则时为空
+}
+
+// ReachabilityResult 可达性分析的结果
+type ReachabilityResult struct {
+	Source      string              `json:"source"`
+	Destination string              `json:"destination"`
+	Protocol    RuleProtocol        `json:"protocol"`
+	Port        int                 `json:"port"`
+	Public      bool                `json:"public"` // 是否经过公网
+	Reachable   bool                `json:"reachable"`
+	Steps       []*ReachabilityStep `json:"steps"`
+}
@@ -0,0 +1,79 @@
+package navite
+
+import (
+	"strconv"
+	"strings"
+)
+
+// RuleProtocol 统一的安全组规则协议
+type RuleProtocol string
+
+// RuleAction 统一的安全组规则策略
+type RuleAction string
+
+const (
+	ProtocolTCP    RuleProtocol = "tcp"
+	ProtocolUDP    RuleProtocol = "udp"
+	ProtocolICMP   RuleProtocol = "icmp"
+	ProtocolICMPv6 RuleProtocol = "icmpv6"
+	ProtocolGRE    RuleProtocol = "gre"
+	ProtocolAll    RuleProtocol = "all"
+
+	ActionAccept RuleAction = "ACCEPT"
+	ActionDrop   RuleAction = "DROP"
+
+	// PortAll 代表全部端口
+	PortAll = -1
+)
+
+// CanonicalRule 与云商无关的安全组规则, 用于在不同云商之间比较和复制规则
+type CanonicalRule struct {
+	Direction   string       `json:"direction"` // ingress/egress
+	Protocol    RuleProtocol `json:"protocol"`
+	PortFrom    int          `json:"portFrom"` // PortAll代表全部端口
+	PortTo      int          `json:"portTo"`
+	CidrIP      string       `json:"cidrIp"` // 入站为源地址, 出站为目的地址
+	Priority    int          `json:"priority"`
+	Action      RuleAction   `json:"action"`
+	Description string       `json:"description"`
+}
+
+// AllPorts 是否包含全部端口
+func (c *CanonicalRule) AllPorts() bool {
+	return c.PortFrom == PortAll || (c.PortFrom <= 1 && c.PortTo >= 65535)
+}
+
+// MatchPort 规则是否包含指定的端口
+func (c *CanonicalRule) MatchPort(port int) bool {
+	return c.AllPorts() || (port >= c.PortFrom && port <= c.PortTo)
+}
+
+// PortRange 返回 from-to 格式的端口范围
+func (c *CanonicalRule) PortRange() string {
+	if c.AllPorts() {
+		return "all"
+	}
+	return strconv.Itoa(c.PortFrom) + "-" + strconv.Itoa(c.PortTo)
+}
+
+// Key 返回规则的唯一标识, 不同云商下相同含义的规则Key相同
+func (c *CanonicalRule) Key() string {
+	return strings.Join([]string{
+		c.Direction,
+		string(c.Protocol),
+		c.PortRange(),
+		c.CidrIP,
+		string(c.Action),
+	}, "|")
+}
+
+// Before 规则c是否比o先生效
+//
+// * 优先级数值越小越先生效, 相同优先级时拒绝优先于允许
+// * 腾讯云的优先级为规则的序号, 不会出现相同的优先级
+func (c *CanonicalRule) Before(o *CanonicalRule) bool {
+	if c.Priority != o.Priority {
+		return c.Priority < o.Priority
+	}
+	return c.Action == ActionDrop && o.Action != ActionDrop
+}
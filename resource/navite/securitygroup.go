@@ -0,0 +1,80 @@
+package navite
+
+import (
+	"ark-common/constants"
+	"strings"
+)
+
+// SecurityGroupRulePlan 安全组规则的变更计划
+type SecurityGroupRulePlan struct {
+	GroupID   string               `json:"groupId"`
+	DryRun    bool                 `json:"dryRun"`
+	ToAdd     []*SecurityGroupRule `json:"toAdd"`
+	ToRemove  []*SecurityGroupRule `json:"toRemove"`
+	Unchanged int                  `json:"unchanged"`
+}
+
+// Key 返回规则的唯一标识, 同一云商下相同含义的规则Key相同
+//
+// * 包含优先级, 只修改了优先级的规则Key不同
+func (r *SecurityGroupRule) Key() string {
+	cidr := r.SourceCidrIP
+	if strings.ToLower(r.Direction) == constants.FlowEgress {
+		cidr = r.DestCidrIP
+	}
+	return strings.Join([]string{
+		strings.ToLower(r.Direction),
+		strings.ToLower(r.Protocol),
+		strings.ToLower(r.PortRange),
+		cidr,
+		strings.ToUpper(r.Action),
+		r.Priority,
+	}, "|")
+}
+
+// DiffSecurityGroupRules 对比当前规则和期望的规则, 返回需要新增和删除的规则
+//
+// * key用于判断两条规则是否相同, 为nil时使用SecurityGroupRule.Key
+func DiffSecurityGroupRules(current, desired []*SecurityGroupRule, key func(*SecurityGroupRule) string) (toAdd, toRemove []*SecurityGroupRule, unchanged int) {
+	if key == nil {
+		key = (*SecurityGroupRule).Key
+	}
+	currentKeys := map[string]bool{}
+	for _, r := range current {
+		currentKeys[key(r)] = true
+	}
+	desiredKeys := map[string]bool{}
+	for _, r := range desired {
+		k := key(r)
+		if desiredKeys[k] {
+			continue
+		}
+		desiredKeys[k] = true
+		if currentKeys[k] {
+			unchanged++
+		} else {
+			toAdd = append(toAdd, r)
+		}
+	}
+	for _, r := range current {
+		if !desiredKeys[key(r)] {
+			toRemove = append(toRemove, r)
+		}
+	}
+	return
+}
+
+// SkippedRule 无法复制的规则及原因
+type SkippedRule struct {
+	Rule   *SecurityGroupRule `json:"rule"`
+	Reason string             `json:"reason"`
+}
+
+// SecurityGroupCopyResult 复制安全组的结果
+type SecurityGroupCopyResult struct {
+	DryRun  bool                 `json:"dryRun"`
+	Source  *SecurityGroup       `json:"source"`
+	Target  *SecurityGroup       `json:"target"`
+	Copied  []*SecurityGroupRule `json:"copied"`
+	Skipped []*SkippedRule       `json:"skipped"`
+}
@@ -0,0 +1,74 @@
+package navite_test
+
+import (
+	"ark-common/constants"
+	"ark-common/resource/navite"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDiffSecurityGroupRules(t *testing.T) {
+	rule := func(cidr, port, priority string) *navite.SecurityGroupRule {
+		return &navite.SecurityGroupRule{
+			CloudName:    constants.Aliyun,
+			GroupID:      "sg-test",
+			SourceCidrIP: cidr,
+			Direction:    constants.FlowIngress,
+			Protocol:     "tcp",
+			PortRange:    port,
+			Priority:     priority,
+			Action:       "accept",
+		}
+	}
+	Convey("对比安全组规则", t, func() {
+		current := []*navite.SecurityGroupRule{
+			rule("10.0.0.0/8", "22/22", "1"),
+			rule("10.0.0.0/8", "80/80", "1"),
+		}
+		Convey("规则相同时没有变更", func() {
+			toAdd, toRemove, unchanged := navite.DiffSecurityGroupRules(current, []*navite.SecurityGroupRule{
+				rule("10.0.0.0/8", "22/22", "1"),
+				rule("10.0.0.0/8", "80/80", "1"),
+			}, nil)
+			So(toAdd, ShouldBeEmpty)
+			So(toRemove, ShouldBeEmpty)
+			So(unchanged, ShouldEqual, 2)
+		})
+		Convey("新增和删除规则", func() {
+			toAdd, toRemove, unchanged := navite.DiffSecurityGroupRules(current, []*navite.SecurityGroupRule{
+				rule("10.0.0.0/8", "22/22", "1"),
+				rule("10.0.0.0/8", "443/443", "1"),
+			}, nil)
+			So(len(toAdd), ShouldEqual, 1)
+			So(toAdd[0].PortRange, ShouldEqual, "443/443")
+			So(len(toRemove), ShouldEqual, 1)
+			So(toRemove[0].PortRange, ShouldEqual, "80/80")
+			So(unchanged, ShouldEqual, 1)
+		})
+		Convey("只修改优先级的规则需要变更", func() {
+			toAdd, toRemove, unchanged := navite.DiffSecurityGroupRules(current, []*navite.SecurityGroupRule{
+				rule("10.0.0.0/8", "22/22", "5"),
+				rule("10.0.0.0/8", "80/80", "1"),
+			}, nil)
+			So(len(toAdd), ShouldEqual, 1)
+			So(toAdd[0].Priority, ShouldEqual, "5")
+			So(len(toRemove), ShouldEqual, 1)
+			So(toRemove[0].Priority, ShouldEqual, "1")
+			So(unchanged, ShouldEqual, 1)
+		})
+		Convey("期望中重复的规则只新增一次", func() {
+			toAdd, _, _ := navite.DiffSecurityGroupRules(nil, []*navite.SecurityGroupRule{
+				rule("10.0.0.0/8", "22/22", "1"),
+				rule("10.0.0.0/8", "22/22", "1"),
+			}, nil)
+			So(len(toAdd), ShouldEqual, 1)
+		})
+		Convey("期望为空时删除全部规则", func() {
+			toAdd, toRemove, unchanged := navite.DiffSecurityGroupRules(current, nil, nil)
+			So(toAdd, ShouldBeEmpty)
+			So(len(toRemove), ShouldEqual, 2)
+			So(unchanged, ShouldEqual, 0)
+		})
+	})
+}
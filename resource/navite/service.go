@@ -10,4 +10,12 @@ type Service struct {
 	ServiceName    string    `bson:"serviceName" json:"serviceName"`
 	Status         string    `bson:"status" json:"status"`
 	LastUpdateTime time.Time `bson:"lastUpdateTime" json:"lastUpdateTime"`
+
+	// 服务实例的注册信息, 同一服务可以有多个实例
+	InstanceID   string    `bson:"instanceId" json:"instanceId"`
+	Host         string    `bson:"host" json:"host"`
+	PID          int       `bson:"pid" json:"pid"`
+	Version      string    `bson:"version" json:"version"`
+	Capabilities []string  `bson:"capabilities" json:"capabilities"` // 如同步worker能处理的作业
+	StartedTime  time.Time `bson:"startedTime" json:"startedTime"`
 }
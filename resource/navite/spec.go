@@ -0,0 +1,25 @@
+package navite
+
+// ZoneAvailability 规格在可用区的售卖状态
+type ZoneAvailability struct {
+	ZoneID    string `json:"zoneId"`
+	Status    string `json:"status"`
+	Available bool   `json:"available"`
+}
+
+// SpecCandidate 匹配到的实例规格
+type SpecCandidate struct {
+	CloudName      string              `json:"cloudName"`
+	AccountID      string              `json:"accountId"`
+	RegionID       string              `json:"regionId"`
+	InstanceSpecID string              `json:"instanceSpecId"`
+	InstanceFamily string              `json:"instanceFamily"`
+	CPU            int                 `json:"cpu"`
+	Memory         float64             `json:"memory"`
+	GPU            int                 `json:"gpu"`
+	HourlyPrice    float64             `json:"hourlyPrice"` // 0代表没有价格数据
+	Zones          []*ZoneAvailability `json:"zones"`       // 云商不按可用区返回规格时为空
+	ZoneKnown      bool                `json:"zoneKnown"`   // 是否有可用区的售卖数据, 阿里云的规格没有可用区数据
+	Available      bool                `json:"available"`   // ZoneKnown为false时只代表没有售罄的记录, 不保证可以在指定可用区购买
+	Waste          float64             `json:"waste"`       // CPU和内存超出需求的比例之和, 越小越贴合
+}
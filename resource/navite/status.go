@@ -0,0 +1,16 @@
+package navite
+
+import "ark-common/constants"
+
+// UnifiedValue 根据云商的映射表将原始值转换为统一的取值
+//
+// * 原始值为空时返回空, 映射表中没有的原始值返回 constants.StatusUnknown
+func UnifiedValue(mapping map[string]string, raw string) string {
+	if raw == "" {
+		return ""
+	}
+	if value, ok := mapping[raw]; ok {
+		return value
+	}
+	return constants.StatusUnknown
+}
@@ -0,0 +1,93 @@
+package navite
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 拓扑节点类型
+const (
+	NodeVPC           = "vpc"
+	NodeSubnet        = "subnet"
+	NodeInstance      = "instance"
+	NodeDisk          = "disk"
+	NodeEip           = "eip"
+	NodeSecurityGroup = "securityGroup"
+)
+
+// nodeOrder 节点的输出顺序, 保证相同的拓扑输出相同, 便于对比
+var nodeOrder = map[string]int{
+	NodeVPC:           0,
+	NodeSubnet:        1,
+	NodeInstance:      2,
+	NodeDisk:          3,
+	NodeEip:           4,
+	NodeSecurityGroup: 5,
+}
+
+// TopologyNode 拓扑中的资源节点
+type TopologyNode struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Name       string            `json:"name"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// TopologyEdge 拓扑中资源之间的关系
+type TopologyEdge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Relation string `json:"relation"` // contains/attach/bind/member
+}
+
+// Topology 账号在地域下的网络拓扑
+type Topology struct {
+	AccountID string          `json:"accountId"`
+	RegionID  string          `json:"regionId"`
+	Nodes     []*TopologyNode `json:"nodes"`
+	Edges     []*TopologyEdge `json:"edges"`
+}
+
+// Sort 按节点类型和ID排序
+func (t *Topology) Sort() {
+	sort.SliceStable(t.Nodes, func(i, j int) bool {
+		a, b := t.Nodes[i], t.Nodes[j]
+		if nodeOrder[a.Type] != nodeOrder[b.Type] {
+			return nodeOrder[a.Type] < nodeOrder[b.Type]
+		}
+		return a.ID < b.ID
+	})
+	sort.SliceStable(t.Edges, func(i, j int) bool {
+		a, b := t.Edges[i], t.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+}
+
+// DOT 返回Graphviz DOT格式的拓扑
+func (t *Topology) DOT() string {
+	t.Sort()
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "digraph \"%s\" {\n", dotQuote(t.AccountID+"/"+t.RegionID))
+	b.WriteString("  rankdir=LR;\n")
+	for _, n := range t.Nodes {
+		label := dotQuote(n.Type) + "\\n" + dotQuote(n.ID)
+		if n.Name != "" && n.Name != n.ID {
+			label += "\\n" + dotQuote(n.Name)
+		}
+		fmt.Fprintf(b, "  \"%s\" [label=\"%s\", shape=box];\n", dotQuote(n.ID), label)
+	}
+	for _, e := range t.Edges {
+		fmt.Fprintf(b, "  \"%s\" -> \"%s\" [label=\"%s\"];\n", dotQuote(e.From), dotQuote(e.To), dotQuote(e.Relation))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotQuote 转义DOT字符串中的引号和反斜杠
+func dotQuote(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ").Replace(s)
+}
@@ -0,0 +1,124 @@
+package registry
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/constants"
+	"ark-common/resource/navite"
+	"ark-common/utils/system"
+	"ark-common/utils/tool"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// HeartbeatInterval 服务实例上报心跳的间隔
+	HeartbeatInterval = 10 * time.Second
+	// DeadAfter 超过该时长没有心跳的实例视为已失效, 即错过3次心跳
+	DeadAfter = 3 * HeartbeatInterval
+)
+
+// Register 注册服务实例, 未指定InstanceID时自动生成, Host和PID取当前进程
+//
+// * 实例不存在时插入, 已存在时(如心跳发现记录被删除后重新注册)覆盖
+func Register(rbd *mgo.Client, svc *navite.Service) (err error) {
+	if svc.InstanceID == "" {
+		svc.InstanceID = tool.UUID()
+	}
+	if svc.Host == "" {
+		svc.Host = system.GetHostName()
+	}
+	if svc.PID == 0 {
+		svc.PID = os.Getpid()
+	}
+	now := time.Now()
+	if svc.StartedTime.IsZero() {
+		svc.StartedTime = now
+	}
+	svc.Status = constants.ServiceAlive
+	svc.LastUpdateTime = now
+	filter := bson.M{
+		"instanceId": svc.InstanceID,
+	}
+	if err = rbd.Table(navite.ServiceTable).Upsert(filter, svc); err != nil {
+		log.Errorf("register service [%+v] failed: %v", svc, err)
+	}
+	return
+}
+
+// Heartbeat 上报服务实例的心跳, 实例不存在时返回错误, 调用方需要重新注册
+func Heartbeat(rbd *mgo.Client, instanceID string) (err error) {
+	filter := bson.M{
+		"instanceId": instanceID,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":         constants.ServiceAlive,
+			"lastUpdateTime": time.Now(),
+		},
+	}
+	err = rbd.Table(navite.ServiceTable).QueryAndUpdate(filter, update, nil).Err()
+	if err != nil && !mgo.IsNotFoundError(err) {
+		log.Errorf("heartbeat service [%s] failed: %v", instanceID, err)
+	}
+	return
+}
+
+// Deregister 注销服务实例, 记录保留并标记为dead
+func Deregister(rbd *mgo.Client, instanceID string) (err error) {
+	filter := bson.M{
+		"instanceId": instanceID,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":         constants.ServiceDead,
+			"lastUpdateTime": time.Now(),
+		},
+	}
+	if err = rbd.Table(navite.ServiceTable).Update(filter, update, nil); err != nil {
+		log.Errorf("deregister service [%s] failed: %v", instanceID, err)
+	}
+	return
+}
+
+// MarkDeadServices 把超过DeadAfter没有心跳的实例标记为dead
+func MarkDeadServices(rbd *mgo.Client, now time.Time) (err error) {
+	filter := bson.M{
+		"status":         constants.ServiceAlive,
+		"lastUpdateTime": bson.M{"$lt": now.Add(-DeadAfter)},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status": constants.ServiceDead,
+		},
+	}
+	if err = rbd.Table(navite.ServiceTable).Update(filter, update, nil); err != nil {
+		log.Errorf("mark [%v] services dead failed: %v", filter, err)
+	}
+	return
+}
+
+// Keepalive 注册服务实例并按HeartbeatInterval上报心跳, 直到stop被关闭后注销
+//
+// * 每次心跳时顺带把其他失效的实例标记为dead, 不需要单独的巡检进程
+func Keepalive(rbd *mgo.Client, svc *navite.Service, stop <-chan struct{}) {
+	if err := Register(rbd, svc); err != nil {
+		log.Errorf("register service [%s] failed, retry on next heartbeat: %v", svc.ServiceName, err)
+	}
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			Deregister(rbd, svc.InstanceID)
+			return
+		case now := <-ticker.C:
+			if err := Heartbeat(rbd, svc.InstanceID); mgo.IsNotFoundError(err) {
+				Register(rbd, svc)
+			}
+			MarkDeadServices(rbd, now)
+		}
+	}
+}
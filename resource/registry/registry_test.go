@@ -0,0 +1,99 @@
+package registry_test
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/constants"
+	"ark-common/resource/manage"
+	"ark-common/resource/navite"
+	"ark-common/resource/registry"
+	"ark-common/utils/testenv"
+	"ark-common/utils/tool"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func cleanupService(rbd *mgo.Client, serviceName string) {
+	rbd.Table(navite.ServiceTable).DeleteMany(bson.M{"serviceName": serviceName})
+}
+
+func TestRegister(t *testing.T) {
+	rbd := testenv.Mongo(t)
+	Convey("注册新的服务实例", t, func() {
+		serviceName := "registry-test-" + tool.UUID()
+		defer cleanupService(rbd, serviceName)
+
+		svc := &navite.Service{ServiceName: serviceName}
+		So(registry.Register(rbd, svc), ShouldBeNil)
+		So(svc.InstanceID, ShouldNotBeEmpty)
+
+		count, serviceList := manage.ListServices(rbd, serviceName, false, 0, 0)
+		So(count, ShouldEqual, 1)
+		So(serviceList[0].InstanceID, ShouldEqual, svc.InstanceID)
+		So(serviceList[0].Status, ShouldEqual, constants.ServiceAlive)
+
+		Convey("重复注册覆盖已有记录", func() {
+			svc.Version = "v2"
+			So(registry.Register(rbd, svc), ShouldBeNil)
+			count, serviceList = manage.ListServices(rbd, serviceName, false, 0, 0)
+			So(count, ShouldEqual, 1)
+			So(serviceList[0].Version, ShouldEqual, "v2")
+		})
+
+		Convey("注销后保留记录并标记为dead", func() {
+			So(registry.Deregister(rbd, svc.InstanceID), ShouldBeNil)
+			count, serviceList = manage.ListServices(rbd, serviceName, false, 0, 0)
+			So(count, ShouldEqual, 1)
+			So(serviceList[0].Status, ShouldEqual, constants.ServiceDead)
+		})
+	})
+}
+
+func TestMarkDeadServices(t *testing.T) {
+	rbd := testenv.Mongo(t)
+	Convey("标记超过DeadAfter没有心跳的实例", t, func() {
+		serviceName := "registry-test-" + tool.UUID()
+		defer cleanupService(rbd, serviceName)
+
+		alive := &navite.Service{ServiceName: serviceName}
+		stale := &navite.Service{ServiceName: serviceName}
+		So(registry.Register(rbd, alive), ShouldBeNil)
+		So(registry.Register(rbd, stale), ShouldBeNil)
+		rbd.Table(navite.ServiceTable).Update(
+			bson.M{"instanceId": stale.InstanceID},
+			bson.M{"$set": bson.M{"lastUpdateTime": time.Now().Add(-2 * registry.DeadAfter)}},
+			nil,
+		)
+
+		Convey("liveOnly只返回心跳未过期的实例", func() {
+			count, serviceList := manage.ListServices(rbd, serviceName, true, 0, 0)
+			So(count, ShouldEqual, 1)
+			So(serviceList[0].InstanceID, ShouldEqual, alive.InstanceID)
+
+			count, _ = manage.ListServices(rbd, serviceName, false, 0, 0)
+			So(count, ShouldEqual, 2)
+		})
+
+		Convey("过期实例被标记为dead, 心跳后恢复", func() {
+			So(registry.MarkDeadServices(rbd, time.Now()), ShouldBeNil)
+			_, serviceList := manage.ListServices(rbd, serviceName, false, 0, 0)
+			status := map[string]string{}
+			for _, svc := range serviceList {
+				status[svc.InstanceID] = svc.Status
+			}
+			So(status[alive.InstanceID], ShouldEqual, constants.ServiceAlive)
+			So(status[stale.InstanceID], ShouldEqual, constants.ServiceDead)
+
+			So(registry.Heartbeat(rbd, stale.InstanceID), ShouldBeNil)
+			count, _ := manage.ListServices(rbd, serviceName, true, 0, 0)
+			So(count, ShouldEqual, 2)
+		})
+
+		Convey("心跳不存在的实例返回NotFound", func() {
+			err := registry.Heartbeat(rbd, tool.UUID())
+			So(mgo.IsNotFoundError(err), ShouldBeTrue)
+		})
+	})
+}
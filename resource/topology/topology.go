@@ -0,0 +1,146 @@
+package topology
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/resource/navite"
+	"ark-common/utils/network"
+	"context"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Inventory 构建拓扑使用的资源
+type Inventory struct {
+	VPCs           []*navite.VPC
+	Subnets        []*navite.Subnet
+	Instances      []*navite.Instance
+	Disks          []*navite.Disk
+	Eips           []*navite.Eip
+	SecurityGroups []*navite.SecurityGroup
+}
+
+// Build 根据资源构建拓扑: VPC -> 子网 -> 实例 -> 云盘/弹性公网IP/安全组
+//
+// * 实例没有记录子网, 按内网IP所在的子网网段归属, 找不到子网时直接挂在VPC下
+// * 负载均衡和NAT网关目前没有同步, 不在拓扑中
+func Build(accountID, regionID string, inv *Inventory) (topo *navite.Topology) {
+	topo = &navite.Topology{
+		AccountID: accountID,
+		RegionID:  regionID,
+		Nodes:     []*navite.TopologyNode{},
+		Edges:     []*navite.TopologyEdge{},
+	}
+	nodes := map[string]bool{}
+	addNode := func(id, nodeType, name string, attributes map[string]string) {
+		if id == "" || nodes[id] {
+			return
+		}
+		nodes[id] = true
+		topo.Nodes = append(topo.Nodes, &navite.TopologyNode{
+			ID:         id,
+			Type:       nodeType,
+			Name:       name,
+			Attributes: attributes,
+		})
+	}
+	addEdge := func(from, to, relation string) {
+		if !nodes[from] || !nodes[to] {
+			return
+		}
+		topo.Edges = append(topo.Edges, &navite.TopologyEdge{From: from, To: to, Relation: relation})
+	}
+
+	for _, vpc := range inv.VPCs {
+		addNode(vpc.VPCID, navite.NodeVPC, vpc.VPCName, map[string]string{"cidrBlock": vpc.CidrBlock})
+	}
+	subnetsOfVPC := map[string][]*navite.Subnet{}
+	for _, subnet := range inv.Subnets {
+		addNode(subnet.SubnetID, navite.NodeSubnet, subnet.SubnetName, map[string]string{
+			"cidrBlock": subnet.CidrBlock,
+			"zoneId":    subnet.ZoneID,
+		})
+		addEdge(subnet.VPCID, subnet.SubnetID, "contains")
+		subnetsOfVPC[subnet.VPCID] = append(subnetsOfVPC[subnet.VPCID], subnet)
+	}
+	for _, sg := range inv.SecurityGroups {
+		addNode(sg.GroupID, navite.NodeSecurityGroup, sg.GroupName, map[string]string{"vpcId": sg.VPCID})
+	}
+	for _, instance := range inv.Instances {
+		addNode(instance.InstanceID, navite.NodeInstance, instance.InstanceName, map[string]string{
+			"innerIpAddress": instance.InnerIPAddress,
+			"eipAddress":     instance.EipAddress,
+			"instanceType":   instance.InstanceType,
+			"status":         instance.Status,
+		})
+		parent := instance.VPCID
+		for _, subnet := range subnetsOfVPC[instance.VPCID] {
+			if network.ContainsIP(subnet.CidrBlock, instance.InnerIPAddress) {
+				parent = subnet.SubnetID
+				break
+			}
+		}
+		addEdge(parent, instance.InstanceID, "contains")
+		for _, groupID := range instance.SecurityGroupList {
+			addEdge(instance.InstanceID, groupID, "member")
+		}
+	}
+	for _, disk := range inv.Disks {
+		addNode(disk.DiskID, navite.NodeDisk, disk.DiskName, map[string]string{
+			"diskType": disk.DiskType,
+			"size":     strconv.Itoa(disk.DiskSize),
+		})
+		addEdge(disk.AttachInstanceID, disk.DiskID, "attach")
+	}
+	for _, eip := range inv.Eips {
+		addNode(eip.AddressID, navite.NodeEip, eip.AddressName, map[string]string{"addressIp": eip.AddressIP})
+		addEdge(eip.BindInstanceID, eip.AddressID, "bind")
+	}
+	topo.Sort()
+	return topo
+}
+
+// query 读取表中符合filter的全部文档到result
+func query(rbd *mgo.Client, table string, filter bson.M, result interface{}) (err error) {
+	mctx := context.Background()
+	cur, err := rbd.Table(table).Query(filter, 0, 0, nil)
+	if err != nil {
+		log.Errorf("filter [%v] %s failed: %v", filter, table, err)
+		return err
+	}
+	defer cur.Close(mctx)
+	if err = cur.All(mctx, result); err != nil {
+		log.Errorf("decord mgo document failed: %v", err)
+	}
+	return err
+}
+
+// BuildTopology 使用mongo中同步的资源构建账号在地域下的拓扑
+//
+// * 不包含同步时已标记为删除的资源
+func BuildTopology(rbd *mgo.Client, accountID, regionID string) (topo *navite.Topology, err error) {
+	filter := bson.M{
+		"accountId": accountID,
+		"regionId":  regionID,
+		"deleted":   bson.M{"$ne": true},
+	}
+	inv := &Inventory{}
+	tables := []struct {
+		table  string
+		result interface{}
+	}{
+		{navite.VPCTable, &inv.VPCs},
+		{navite.SubnetTable, &inv.Subnets},
+		{navite.InstanceTable, &inv.Instances},
+		{navite.DiskTable, &inv.Disks},
+		{navite.EIPTable, &inv.Eips},
+		{navite.SecurityGroupTable, &inv.SecurityGroups},
+	}
+	for _, t := range tables {
+		if err = query(rbd, t.table, filter, t.result); err != nil {
+			return nil, err
+		}
+	}
+	return Build(accountID, regionID, inv), nil
+}
@@ -0,0 +1,91 @@
+package topology_test
+
+import (
+	"ark-common/resource/navite"
+	"ark-common/resource/topology"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newInventory() *topology.Inventory {
+	return &topology.Inventory{
+		VPCs: []*navite.VPC{
+			{VPCID: "vpc-1", VPCName: `prod "core"`, CidrBlock: "10.0.0.0/16"},
+		},
+		Subnets: []*navite.Subnet{
+			{SubnetID: "vsw-1", SubnetName: "web", VPCID: "vpc-1", CidrBlock: "10.0.1.0/24", ZoneID: "cn-beijing-g"},
+		},
+		Instances: []*navite.Instance{
+			{InstanceID: "i-2", InstanceName: "i-2", VPCID: "vpc-1", InnerIPAddress: "10.0.9.9"},
+			{InstanceID: "i-1", InstanceName: "web-1", VPCID: "vpc-1", InnerIPAddress: "10.0.1.5", SecurityGroupList: []string{"sg-1", "sg-unknown"}},
+		},
+		Disks: []*navite.Disk{
+			{DiskID: "d-1", AttachInstanceID: "i-1", DiskSize: 40},
+			{DiskID: "d-2", AttachInstanceID: "i-unknown"},
+		},
+		Eips: []*navite.Eip{
+			{AddressID: "eip-1", AddressIP: "1.2.3.4", BindInstanceID: "i-1"},
+		},
+		SecurityGroups: []*navite.SecurityGroup{
+			{GroupID: "sg-1", GroupName: "default", VPCID: "vpc-1"},
+		},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	Convey("根据资源构建拓扑", t, func() {
+		topo := topology.Build("acc", "cn-beijing", newInventory())
+
+		Convey("节点按类型和ID排序", func() {
+			ids := []string{}
+			for _, n := range topo.Nodes {
+				ids = append(ids, n.ID)
+			}
+			So(ids, ShouldResemble, []string{"vpc-1", "vsw-1", "i-1", "i-2", "d-1", "d-2", "eip-1", "sg-1"})
+			So(topo.Nodes[4].Attributes["size"], ShouldEqual, "40")
+		})
+
+		Convey("实例按内网IP挂在子网下, 找不到子网时挂在VPC下", func() {
+			edges := map[string]string{}
+			for _, e := range topo.Edges {
+				edges[e.From+"->"+e.To] = e.Relation
+			}
+			So(edges["vsw-1->i-1"], ShouldEqual, "contains")
+			So(edges["vpc-1->i-2"], ShouldEqual, "contains")
+			So(edges["vpc-1->vsw-1"], ShouldEqual, "contains")
+			So(edges["i-1->d-1"], ShouldEqual, "attach")
+			So(edges["i-1->eip-1"], ShouldEqual, "bind")
+			So(edges["i-1->sg-1"], ShouldEqual, "member")
+		})
+
+		Convey("不存在的节点不生成边", func() {
+			So(len(topo.Edges), ShouldEqual, 6)
+		})
+	})
+}
+
+func TestDOT(t *testing.T) {
+	Convey("输出DOT格式的拓扑", t, func() {
+		dot := topology.Build("acc", "cn-beijing", newInventory()).DOT()
+		expected := `digraph "acc/cn-beijing" {
+  rankdir=LR;
+  "vpc-1" [label="vpc\nvpc-1\nprod \"core\"", shape=box];
+  "vsw-1" [label="subnet\nvsw-1\nweb", shape=box];
+  "i-1" [label="instance\ni-1\nweb-1", shape=box];
+  "i-2" [label="instance\ni-2", shape=box];
+  "d-1" [label="disk\nd-1", shape=box];
+  "d-2" [label="disk\nd-2", shape=box];
+  "eip-1" [label="eip\neip-1", shape=box];
+  "sg-1" [label="securityGroup\nsg-1\ndefault", shape=box];
+  "i-1" -> "d-1" [label="attach"];
+  "i-1" -> "eip-1" [label="bind"];
+  "i-1" -> "sg-1" [label="member"];
+  "vpc-1" -> "i-2" [label="contains"];
+  "vpc-1" -> "vsw-1" [label="contains"];
+  "vsw-1" -> "i-1" [label="contains"];
+}
+`
+		So(dot, ShouldEqual, expected)
+	})
+}
@@ -0,0 +1,124 @@
+package network
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ParseCIDR 解析CIDR, 不带掩码的IP视为单个地址
+func ParseCIDR(cidr string) (*net.IPNet, error) {
+	cidr = strings.TrimSpace(cidr)
+	if !strings.Contains(cidr, "/") {
+		ip := net.ParseIP(cidr)
+		if ip == nil {
+			return nil, &net.ParseError{Type: "CIDR address", Text: cidr}
+		}
+		if ip.To4() != nil {
+			cidr = cidr + "/32"
+		} else {
+			cidr = cidr + "/128"
+		}
+	}
+	_, ipNet, err := net.ParseCIDR(cidr)
+	return ipNet, err
+}
+
+// IsAny 是否代表全部地址
+func IsAny(cidr string) bool {
+	cidr = strings.TrimSpace(cidr)
+	return cidr == "0.0.0.0/0" || cidr == "::/0"
+}
+
+// Contains outer是否完全包含inner
+func Contains(outer, inner string) bool {
+	o, err := ParseCIDR(outer)
+	if err != nil {
+		return false
+	}
+	i, err := ParseCIDR(inner)
+	if err != nil {
+		return false
+	}
+	oOnes, oBits := o.Mask.Size()
+	iOnes, iBits := i.Mask.Size()
+	return oBits == iBits && oOnes <= iOnes && o.Contains(i.IP)
+}
+
+// Overlaps 两个网段是否有重叠
+func Overlaps(a, b string) bool {
+	return Contains(a, b) || Contains(b, a)
+}
+
+// ContainsIP cidr是否包含ip
+func ContainsIP(cidr, ip string) bool {
+	n, err := ParseCIDR(cidr)
+	if err != nil {
+		return false
+	}
+	addr := net.ParseIP(strings.TrimSpace(ip))
+	return addr != nil && n.Contains(addr)
+}
+
+// NextFreeCidr 在supernet中按地址顺序找到第一个与used都不重叠、掩码长度为prefixLen的网段
+//
+// * 只支持IPv4
+func NextFreeCidr(supernet string, prefixLen int, used []string) (string, error) {
+	super, err := ParseCIDR(supernet)
+	if err != nil {
+		return "", err
+	}
+	superOnes, bits := super.Mask.Size()
+	if bits != 32 {
+		return "", fmt.Errorf("only ipv4 supernet is supported: %s", supernet)
+	}
+	if prefixLen < superOnes || prefixLen > 32 {
+		return "", fmt.Errorf("prefix length /%d out of supernet %s", prefixLen, supernet)
+	}
+
+	type block struct{ start, end uint64 }
+	usedBlocks := []block{}
+	for _, cidr := range used {
+		n, err := ParseCIDR(cidr)
+		if err != nil || n.IP.To4() == nil {
+			continue
+		}
+		ones, _ := n.Mask.Size()
+		start := uint64(ipToUint32(n.IP))
+		usedBlocks = append(usedBlocks, block{start, start + 1<<uint(32-ones) - 1})
+	}
+
+	size := uint64(1) << uint(32-prefixLen)
+	superEnd := uint64(ipToUint32(super.IP)) + 1<<uint(32-superOnes) - 1
+	for start := uint64(ipToUint32(super.IP)); start+size-1 <= superEnd; {
+		end := start + size - 1
+		next := start + size
+		overlapped := false
+		for _, u := range usedBlocks {
+			if u.start <= end && start <= u.end {
+				overlapped = true
+				// 跳过已使用的网段, 并对齐到size
+				if aligned := (u.end + size) / size * size; aligned > next {
+					next = aligned
+				}
+			}
+		}
+		if !overlapped {
+			return uint32ToIP(uint32(start)).String() + "/" + strconv.Itoa(prefixLen), nil
+		}
+		start = next
+	}
+	return "", fmt.Errorf("no free /%d block in %s", prefixLen, supernet)
+}
+
+func ipToUint32(ip net.IP) uint32 {
+	return binary.BigEndian.Uint32(ip.To4())
+}
+
+func uint32ToIP(n uint32) net.IP {
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, n)
+	return ip
+}
@@ -0,0 +1,48 @@
+package network_test
+
+import (
+	"ark-common/utils/network"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCIDR(t *testing.T) {
+	Convey("网段的包含和重叠", t, func() {
+		Convey("大网段包含小网段", func() {
+			So(network.Contains("10.0.0.0/8", "10.16.1.0/24"), ShouldBeTrue)
+			So(network.Contains("10.16.1.0/24", "10.0.0.0/8"), ShouldBeFalse)
+			So(network.Contains("0.0.0.0/0", "192.168.1.1"), ShouldBeTrue)
+		})
+		Convey("网段重叠", func() {
+			So(network.Overlaps("10.16.0.0/12", "10.20.0.0/16"), ShouldBeTrue)
+			So(network.Overlaps("10.16.0.0/16", "10.17.0.0/16"), ShouldBeFalse)
+		})
+		Convey("IPv4和IPv6互不包含", func() {
+			So(network.Contains("::/0", "10.0.0.0/8"), ShouldBeFalse)
+		})
+		Convey("网段包含IP", func() {
+			So(network.ContainsIP("172.16.0.0/12", "172.20.1.10"), ShouldBeTrue)
+			So(network.ContainsIP("172.16.0.0/12", "172.32.1.10"), ShouldBeFalse)
+		})
+
+		Convey("分配空闲网段", func() {
+			cidr, err := network.NextFreeCidr("10.0.0.0/8", 16, nil)
+			So(err, ShouldBeNil)
+			So(cidr, ShouldEqual, "10.0.0.0/16")
+
+			cidr, err = network.NextFreeCidr("10.0.0.0/8", 16, []string{"10.0.0.0/16", "10.1.128.0/24"})
+			So(err, ShouldBeNil)
+			So(cidr, ShouldEqual, "10.2.0.0/16")
+
+			cidr, err = network.NextFreeCidr("10.0.0.0/16", 24, []string{"10.0.0.0/23", "172.16.0.0/12"})
+			So(err, ShouldBeNil)
+			So(cidr, ShouldEqual, "10.0.2.0/24")
+
+			_, err = network.NextFreeCidr("10.0.0.0/24", 25, []string{"10.0.0.0/25", "10.0.0.128/25"})
+			So(err, ShouldNotBeNil)
+			_, err = network.NextFreeCidr("10.0.0.0/16", 8, nil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -1,6 +1,17 @@
 package system
 
-// GetHostName 返回当前运行节点的主机名
+import (
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// GetHostName 返回当前运行节点的主机名, 获取失败时返回localhost
 func GetHostName() string {
-	return "localhost"
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		log.Warnf("get hostname failed: %v", err)
+		return "localhost"
+	}
+	return hostname
 }
@@ -0,0 +1,48 @@
+// Package testenv 为需要外部服务的测试提供连接, 服务没有配置时跳过测试
+//
+// * mongo 读取 MGO_URL, 与mgo.NewMgo一致
+// * redis 读取 ARK_REDIS_ADDR 和 ARK_REDIS_PASSWD, 使用1号库
+package testenv
+
+import (
+	"ark-common/clients/mgo"
+	cache "ark-common/clients/redis"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/go-redis/redis"
+)
+
+var (
+	mgoOnce sync.Once
+	db      *mgo.Client
+)
+
+// Mongo 返回测试共用的mongo连接, 没有设置MGO_URL时跳过测试
+func Mongo(t *testing.T) *mgo.Client {
+	if os.Getenv("MGO_URL") == "" {
+		t.Skip("MGO_URL not set")
+	}
+	mgoOnce.Do(func() {
+		db = mgo.NewMgo("")
+	})
+	return db
+}
+
+// Redis 返回测试用的redis连接, 没有设置ARK_REDIS_ADDR时跳过测试
+func Redis(t *testing.T) *redis.Client {
+	if os.Getenv("ARK_REDIS_ADDR") == "" {
+		t.Skip("ARK_REDIS_ADDR not set")
+	}
+	opt := redis.Options{
+		Addr:     os.Getenv("ARK_REDIS_ADDR"),
+		Password: os.Getenv("ARK_REDIS_PASSWD"),
+		DB:       1,
+	}
+	client := cache.NewClient(&opt)
+	if client == nil {
+		t.Fatalf("redis connect failed")
+	}
+	return client
+}
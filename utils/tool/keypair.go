@@ -0,0 +1,149 @@
+package tool
+
+import (
+	"ark-common/constants"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// NewKeyPair 按类型生成一对SSH密钥对, 返回PEM格式的私钥和authorized_keys格式的公钥
+func NewKeyPair(keyType string) (privateKey, publicKey []byte, err error) {
+	switch keyType {
+	case constants.KeyTypeRSA, "":
+		privateKey, publicKey = NewRSAKeyPair()
+		if privateKey == nil {
+			return nil, nil, errors.New("generate rsa keypair failed")
+		}
+		return privateKey, publicKey, nil
+	case constants.KeyTypeEd25519:
+		return NewEd25519KeyPair()
+	case constants.KeyTypeECDSA:
+		return NewECDSAKeyPair()
+	}
+	return nil, nil, fmt.Errorf("not support key type %s", keyType)
+}
+
+// NewEd25519KeyPair 生成一对Ed25519密钥对, 私钥为PKCS#8格式
+func NewEd25519KeyPair() (privateKey, publicKey []byte, err error) {
+	pub, pk, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(pk)
+	if err != nil {
+		return nil, nil, err
+	}
+	privateKey = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, nil, err
+	}
+	return privateKey, ssh.MarshalAuthorizedKey(sshPub), nil
+}
+
+// NewECDSAKeyPair 生成一对P-256曲线的ECDSA密钥对
+func NewECDSAKeyPair() (privateKey, publicKey []byte, err error) {
+	pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(pk)
+	if err != nil {
+		return nil, nil, err
+	}
+	privateKey = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	sshPub, err := ssh.NewPublicKey(&pk.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return privateKey, ssh.MarshalAuthorizedKey(sshPub), nil
+}
+
+// Fingerprint 计算authorized_keys格式公钥的MD5和SHA256指纹
+//
+// * MD5指纹为 xx:xx:... 格式, SHA256指纹为 SHA256:base64 格式, 与ssh-keygen -l 输出一致
+func Fingerprint(publicKey string) (md5, sha string, err error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.TrimSpace(publicKey)))
+	if err != nil {
+		return "", "", err
+	}
+	return ssh.FingerprintLegacyMD5(pub), ssh.FingerprintSHA256(pub), nil
+}
+
+// PrivateKeyFingerprint 由PEM格式的私钥推导公钥, 返回公钥的SHA256指纹
+func PrivateKeyFingerprint(privateKey []byte) (sha string, err error) {
+	signer, err := ssh.ParsePrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	return ssh.FingerprintSHA256(signer.PublicKey()), nil
+}
+
+// NormalizeMD5Fingerprint 将云商返回的MD5指纹统一为小写的 xx:xx:... 格式
+func NormalizeMD5Fingerprint(fingerprint string) string {
+	fingerprint = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(fingerprint), "MD5:"))
+	fingerprint = strings.Replace(fingerprint, ":", "", -1)
+	if len(fingerprint) != 32 {
+		return fingerprint
+	}
+	parts := make([]string, 0, 16)
+	for i := 0; i < len(fingerprint); i += 2 {
+		parts = append(parts, fingerprint[i:i+2])
+	}
+	return strings.Join(parts, ":")
+}
+
+// Seal 使用AES-GCM加密数据, secret经过SHA256作为密钥, 返回hex格式的随机nonce和密文
+func Seal(secret string, data []byte) (string, error) {
+	aead, err := newGCM(secret)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(aead.Seal(nonce, nonce, data, nil)), nil
+}
+
+// Open 解密Seal加密的数据
+func Open(secret string, sealed string) ([]byte, error) {
+	aead, err := newGCM(secret)
+	if err != nil {
+		return nil, err
+	}
+	src, err := hex.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+	if len(src) < aead.NonceSize() {
+		return nil, errors.New("sealed data too short")
+	}
+	return aead.Open(nil, src[:aead.NonceSize()], src[aead.NonceSize():], nil)
+}
+
+func newGCM(secret string) (cipher.AEAD, error) {
+	if secret == "" {
+		return nil, errors.New("empty secret")
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -49,11 +49,34 @@ var mi = []string{
 	"S", "S", "P", "8", "f", "Z", "w", "2", "u", "G",
 }[21:37]
 
+const randomChars = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnpqrstuvwxyz23456789"
+
 // UUID 返回一个UUID
 func UUID() string {
 	return primitive.NewObjectID().Hex()
 }
 
+// RandomString 返回长度为n的随机字符串, 只包含字母和数字
+//
+// * 去掉了容易混淆的字符, 适合生成需要人工输入的密码
+// * 丢弃超出字符表整数倍的随机字节, 每个字符出现的概率相同
+func RandomString(n int) (string, error) {
+	limit := 256 - 256%len(randomChars)
+	b := make([]byte, 0, n)
+	buf := make([]byte, n)
+	for len(b) < n {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, c := range buf {
+			if int(c) < limit && len(b) < n {
+				b = append(b, randomChars[int(c)%len(randomChars)])
+			}
+		}
+	}
+	return string(b), nil
+}
+
 // TimeForISO8601 转换为time.Time类型
 func TimeForISO8601(t string) (rt time.Time) {
 	rt, _ = time.Parse(constants.ISO8601, t)
@@ -1,7 +1,9 @@
 package tool_test
 
 import (
+	"ark-common/constants"
 	"ark-common/utils/tool"
+	"strings"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -27,3 +29,69 @@ func TestNewRSAKeyPair(t *testing.T) {
 		So(publicKey, ShouldNotBeNil)
 	})
 }
+
+func TestNewKeyPair(t *testing.T) {
+	Convey("生成不同类型的密钥对并计算指纹", t, func() {
+		for _, keyType := range []string{constants.KeyTypeEd25519, constants.KeyTypeECDSA} {
+			privateKey, publicKey, err := tool.NewKeyPair(keyType)
+			So(err, ShouldBeNil)
+			So(privateKey, ShouldNotBeEmpty)
+
+			md5, sha, err := tool.Fingerprint(string(publicKey))
+			So(err, ShouldBeNil)
+			So(len(md5), ShouldEqual, 47)
+			So(sha, ShouldStartWith, "SHA256:")
+
+			derived, err := tool.PrivateKeyFingerprint(privateKey)
+			So(err, ShouldBeNil)
+			So(derived, ShouldEqual, sha)
+		}
+
+		privateKey, publicKey, err := tool.NewKeyPair(constants.KeyTypeRSA)
+		So(err, ShouldBeNil)
+		_, sha, _ := tool.Fingerprint(string(publicKey))
+		derived, err := tool.PrivateKeyFingerprint(privateKey)
+		So(err, ShouldBeNil)
+		So(derived, ShouldEqual, sha)
+
+		_, err = tool.PrivateKeyFingerprint([]byte("not a private key"))
+		So(err, ShouldNotBeNil)
+
+		_, _, err = tool.NewKeyPair("dsa")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("统一MD5指纹格式", t, func() {
+		So(tool.NormalizeMD5Fingerprint("89F0BA62AC6D21AF0A2E8FDE7C9E5C1B"), ShouldEqual,
+			"89:f0:ba:62:ac:6d:21:af:0a:2e:8f:de:7c:9e:5c:1b")
+	})
+}
+
+func TestSeal(t *testing.T) {
+	Convey("AES-GCM加密与解密", t, func() {
+		sealed, err := tool.Seal("secret", []byte("private key"))
+		So(err, ShouldBeNil)
+
+		data, err := tool.Open("secret", sealed)
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "private key")
+
+		_, err = tool.Open("other", sealed)
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestRandomString(t *testing.T) {
+	Convey("生成随机字符串", t, func() {
+		s, err := tool.RandomString(6)
+		So(err, ShouldBeNil)
+		So(len(s), ShouldEqual, 6)
+		other, _ := tool.RandomString(6)
+		So(s, ShouldNotEqual, other)
+
+		long, err := tool.RandomString(1000)
+		So(err, ShouldBeNil)
+		So(len(long), ShouldEqual, 1000)
+		So(strings.Trim(long, "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnpqrstuvwxyz23456789"), ShouldBeEmpty)
+	})
+}
@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/constants"
+	"ark-common/resource/navite"
+	"ark-common/utils/tool"
+	"encoding/json"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// publish 发布资源变更事件, 发布失败只记录日志不影响同步, 没有队列时(如测试)不发布
+func (w *SyncWorker) publish(event *navite.ResourceEvent) {
+	if w.q == nil {
+		return
+	}
+	event.EventID = tool.UUID()
+	event.Time = time.Now()
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Errorf("encode event [%+v] failed: %v", event, err)
+		return
+	}
+	if err = w.q.Push(constants.ResourceExchange, amqp.ExchangeTopic, event.RoutingKey(), body); err != nil {
+		log.Errorf("publish event [%s] failed: %v", event.RoutingKey(), err)
+	}
+}
+
+// upsertAndNotify 与mongo中已有的资源比较后写入, 并发布created/updated事件
+//
+// * stored为与doc同类型的空结构体指针, 用于读取已有的资源
+// * 读取已有的资源出错(不是不存在)时不写入, 避免把已有的资源当成新建发布created事件
+func (ctx *JobContext) upsertAndNotify(table, resourceType, resourceID string, filter bson.M, doc, stored interface{}) (err error) {
+	rbd := ctx.Worker.rbd
+	found := true
+	if err = rbd.Table(table).QueryOne(filter, stored, nil); err != nil {
+		if !mgo.IsNotFoundError(err) {
+			log.Errorf("filter [%v] %s failed: %v", filter, table, err)
+			return err
+		}
+		found = false
+	}
+	if err = upsert(rbd, table, filter, doc); err != nil {
+		return
+	}
+
+	event := &navite.ResourceEvent{
+		Event:        constants.EventCreated,
+		CloudName:    ctx.Job.CloudName,
+		AccountID:    ctx.Job.AccountID,
+		RegionID:     ctx.Job.RegionID,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+	}
+	if found {
+		event.Event = constants.EventUpdated
+		event.Changes = navite.DiffFields(stored, doc)
+		if len(event.Changes) == 0 {
+			return
+		}
+	} else {
+		event.Resource = doc
+	}
+	ctx.Worker.publish(event)
+	return
+}
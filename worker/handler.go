@@ -0,0 +1,414 @@
+package worker
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/constants"
+	"ark-common/misc"
+	"ark-common/resource/analyzer"
+	"ark-common/resource/navite"
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// fetchPages 分页拉取资源, 直到拉取的数量达到云端返回的总数或某一页为空
+//
+// * fetch 返回云端的总数和本页拉取到的数量
+// * 驱动出错时返回的空页与最后一页无法区分, 每页之后检查驱动的SyncError
+// * 每页之后上报进度作为作业的心跳, 作业已被取消或接管时停止拉取
+func fetchPages(ctx *JobContext, fetch func(pageSize, currentPage int) (count, got int)) (count, fetched int, err error) {
+	pageSize := ctx.Worker.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	for currentPage := 1; ; currentPage++ {
+		c, got := fetch(pageSize, currentPage)
+		if err = ctx.Driver.SyncError(); err != nil {
+			return count, fetched, err
+		}
+		count = c
+		fetched += got
+		if got == 0 || fetched >= count {
+			return
+		}
+		if err = ctx.Job.SetProgress(ctx.Worker.rbd, fetched, count, fmt.Sprintf("page %d", currentPage)); err != nil {
+			return count, fetched, err
+		}
+	}
+}
+
+// upsert 按filter更新或插入一条资源
+func upsert(rbd *mgo.Client, table string, filter bson.M, doc interface{}) (err error) {
+	if err = rbd.Table(table).Upsert(filter, doc); err != nil {
+		log.Errorf("upsert [%v] into %s failed: %v", filter, table, err)
+	}
+	return
+}
+
+// upsertFailed 返回写入失败时作业的错误
+func upsertFailed(table string, failed, total int) error {
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("upsert %d of %d %s failed", failed, total, table)
+}
+
+func syncRegion(ctx *JobContext) error {
+	rbd := ctx.Worker.rbd
+	list := ctx.Driver.GetRegionList()
+	syncErr := ctx.Driver.SyncError()
+	failed := 0
+	for _, region := range list {
+		filter := bson.M{
+			"cloudName": region.CloudName,
+			"regionId":  region.RegionID,
+		}
+		if upsert(rbd, navite.CloudRegionTable, filter, region) != nil {
+			failed++
+		}
+	}
+	if syncErr != nil {
+		return syncErr
+	}
+	return upsertFailed(navite.CloudRegionTable, failed, len(list))
+}
+
+func syncZone(ctx *JobContext) error {
+	rbd := ctx.Worker.rbd
+	list := ctx.Driver.GetZoneList()
+	syncErr := ctx.Driver.SyncError()
+	failed := 0
+	for _, zone := range list {
+		filter := bson.M{
+			"cloudName": zone.CloudName,
+			"regionId":  zone.RegionID,
+			"zoneId":    zone.ZoneID,
+		}
+		if upsert(rbd, navite.CloudZoneTable, filter, zone) != nil {
+			failed++
+		}
+	}
+	if syncErr != nil {
+		return syncErr
+	}
+	return upsertFailed(navite.CloudZoneTable, failed, len(list))
+}
+
+func syncInstanceSpec(ctx *JobContext) error {
+	rbd := ctx.Worker.rbd
+	list := ctx.Driver.GetInstanceSpecsList()
+	syncErr := ctx.Driver.SyncError()
+	failed := 0
+	for _, spec := range list {
+		filter := bson.M{
+			"accountId":      spec.AccountID,
+			"regionId":       spec.RegionID,
+			"zoneId":         spec.ZoneID,
+			"instanceSpecId": spec.InstanceSpecID,
+		}
+		if upsert(rbd, navite.InstanceSpecTable, filter, spec) != nil {
+			failed++
+		}
+	}
+	if syncErr != nil {
+		return syncErr
+	}
+	return upsertFailed(navite.InstanceSpecTable, failed, len(list))
+}
+
+func syncImage(ctx *JobContext) error {
+	rbd := ctx.Worker.rbd
+	list := []*navite.Image{}
+	_, _, syncErr := fetchPages(ctx, func(pageSize, currentPage int) (int, int) {
+		count, page := ctx.Driver.GetImageList(pageSize, currentPage)
+		list = append(list, page...)
+		return count, len(page)
+	})
+	failed := 0
+	for _, image := range list {
+		filter := bson.M{
+			"accountId": image.AccountID,
+			"regionId":  image.RegionID,
+			"imageId":   image.ImageID,
+		}
+		if upsert(rbd, navite.ImageTable, filter, image) != nil {
+			failed++
+		}
+	}
+	if syncErr != nil {
+		return syncErr
+	}
+	return upsertFailed(navite.ImageTable, failed, len(list))
+}
+
+func syncInstance(ctx *JobContext) error {
+	rbd := ctx.Worker.rbd
+	list := []*navite.Instance{}
+	count, _, syncErr := fetchPages(ctx, func(pageSize, currentPage int) (int, int) {
+		count, page := ctx.Driver.GetInstanceList(pageSize, currentPage)
+		list = append(list, page...)
+		return count, len(page)
+	})
+	failed := 0
+	for _, instance := range list {
+		filter := bson.M{
+			"accountId":  instance.AccountID,
+			"instanceId": instance.InstanceID,
+		}
+		if ctx.upsertAndNotify(navite.InstanceTable, constants.ResourceInstance, instance.InstanceID, filter, instance, &navite.Instance{}) != nil {
+			failed++
+		}
+	}
+	if syncErr != nil {
+		return syncErr
+	}
+	if err := upsertFailed(navite.InstanceTable, failed, len(list)); err != nil {
+		return err
+	}
+	seen := make([]string, 0, len(list))
+	for _, instance := range list {
+		seen = append(seen, instance.InstanceID)
+	}
+	return tombstone(ctx, navite.InstanceTable, constants.ResourceInstance, "instanceId", count, seen)
+}
+
+func syncSecurityGroup(ctx *JobContext) error {
+	rbd := ctx.Worker.rbd
+	list := []*navite.SecurityGroup{}
+	_, _, syncErr := fetchPages(ctx, func(pageSize, currentPage int) (int, int) {
+		count, page := ctx.Driver.GetSecurityGroupList(pageSize, currentPage)
+		list = append(list, page...)
+		return count, len(page)
+	})
+	failed := 0
+	for _, sg := range list {
+		filter := bson.M{
+			"accountId": sg.AccountID,
+			"groupId":   sg.GroupID,
+		}
+		if upsert(rbd, navite.SecurityGroupTable, filter, sg) != nil {
+			failed++
+		}
+	}
+	if syncErr != nil {
+		return syncErr
+	}
+	return upsertFailed(navite.SecurityGroupTable, failed, len(list))
+}
+
+// syncSecurityGroupRule 同步mongo中该地域所有安全组的规则, 每个安全组为一步, 完成后发送安全组风险检查作业
+func syncSecurityGroupRule(ctx *JobContext) error {
+	rbd := ctx.Worker.rbd
+	filter := bson.M{
+		"accountId": ctx.Job.AccountID,
+		"regionId":  ctx.Job.RegionID,
+	}
+	sgList := []*navite.SecurityGroup{}
+	mctx := context.Background()
+	cur, err := rbd.Table(navite.SecurityGroupTable).Query(filter, 0, 0, nil)
+	if err != nil {
+		log.Errorf("filter [%v] securityGroups failed: %v", filter, err)
+		return err
+	}
+	defer cur.Close(mctx)
+	if err = cur.All(mctx, &sgList); err != nil {
+		log.Errorf("decord mgo document failed: %v", err)
+		return err
+	}
+
+	// 拉取失败的安全组保留mongo中已有的规则, 不能用空列表覆盖
+	failed := 0
+	for i, sg := range sgList {
+		rules := ctx.Driver.GetSecurityGroupRuleList(sg.GroupID)
+		if e := ctx.Driver.SyncError(); e != nil {
+			log.Warnf("sync securityGroup [%s] rules failed, keep the stored rules: %v", sg.GroupID, e)
+			failed++
+			err = e
+		} else {
+			misc.SaveSecurityGroupRules(rbd, sg.GroupID, rules)
+		}
+		if e := ctx.Job.SetProgress(rbd, i+1, len(sgList), sg.GroupID); e != nil {
+			return e
+		}
+	}
+	if ctx.Worker.q != nil {
+		misc.SendLintSecurityGroupJob(rbd, ctx.Worker.q, ctx.Job.AccountID, ctx.Job.CloudName, ctx.Job.RegionID)
+	}
+	if failed > 0 {
+		log.Errorf("sync rules of %d of %d securityGroups failed", failed, len(sgList))
+		return err
+	}
+	return nil
+}
+
+func syncDisk(ctx *JobContext) error {
+	rbd := ctx.Worker.rbd
+	list := []*navite.Disk{}
+	count, _, syncErr := fetchPages(ctx, func(pageSize, currentPage int) (int, int) {
+		count, page := ctx.Driver.GetDiskList(pageSize, currentPage)
+		list = append(list, page...)
+		return count, len(page)
+	})
+	failed := 0
+	for _, disk := range list {
+		filter := bson.M{
+			"accountId": disk.AccountID,
+			"diskId":    disk.DiskID,
+		}
+		if ctx.upsertAndNotify(navite.DiskTable, constants.ResourceDisk, disk.DiskID, filter, disk, &navite.Disk{}) != nil {
+			failed++
+		}
+	}
+	if syncErr != nil {
+		return syncErr
+	}
+	if err := upsertFailed(navite.DiskTable, failed, len(list)); err != nil {
+		return err
+	}
+	seen := make([]string, 0, len(list))
+	for _, disk := range list {
+		seen = append(seen, disk.DiskID)
+	}
+	return tombstone(ctx, navite.DiskTable, constants.ResourceDisk, "diskId", count, seen)
+}
+
+func syncKeypair(ctx *JobContext) error {
+	rbd := ctx.Worker.rbd
+	list := []*navite.Keypair{}
+	_, _, syncErr := fetchPages(ctx, func(pageSize, currentPage int) (int, int) {
+		count, page := ctx.Driver.GetKeypairList(pageSize, currentPage)
+		list = append(list, page...)
+		return count, len(page)
+	})
+	failed := 0
+	for _, keypair := range list {
+		filter := bson.M{
+			"accountId": keypair.AccountID,
+			"regionId":  keypair.RegionID,
+			"keypairId": keypair.KeypairID,
+		}
+		if upsert(rbd, navite.KeyPairTable, filter, keypair) != nil {
+			failed++
+		}
+	}
+	if syncErr != nil {
+		return syncErr
+	}
+	return upsertFailed(navite.KeyPairTable, failed, len(list))
+}
+
+func syncVPC(ctx *JobContext) error {
+	rbd := ctx.Worker.rbd
+	list := []*navite.VPC{}
+	_, _, syncErr := fetchPages(ctx, func(pageSize, currentPage int) (int, int) {
+		count, page := ctx.Driver.GetVPCList(pageSize, currentPage)
+		list = append(list, page...)
+		return count, len(page)
+	})
+	failed := 0
+	for _, vpc := range list {
+		filter := bson.M{
+			"accountId": vpc.AccountID,
+			"vpcId":     vpc.VPCID,
+		}
+		if upsert(rbd, navite.VPCTable, filter, vpc) != nil {
+			failed++
+		}
+	}
+	if syncErr != nil {
+		return syncErr
+	}
+	return upsertFailed(navite.VPCTable, failed, len(list))
+}
+
+func syncSubnet(ctx *JobContext) error {
+	rbd := ctx.Worker.rbd
+	list := []*navite.Subnet{}
+	_, _, syncErr := fetchPages(ctx, func(pageSize, currentPage int) (int, int) {
+		count, page := ctx.Driver.GetSubnetList(pageSize, currentPage)
+		list = append(list, page...)
+		return count, len(page)
+	})
+	failed := 0
+	for _, subnet := range list {
+		filter := bson.M{
+			"accountId": subnet.AccountID,
+			"subnetId":  subnet.SubnetID,
+		}
+		if upsert(rbd, navite.SubnetTable, filter, subnet) != nil {
+			failed++
+		}
+	}
+	if syncErr != nil {
+		return syncErr
+	}
+	return upsertFailed(navite.SubnetTable, failed, len(list))
+}
+
+func syncEip(ctx *JobContext) error {
+	rbd := ctx.Worker.rbd
+	list := []*navite.Eip{}
+	count, _, syncErr := fetchPages(ctx, func(pageSize, currentPage int) (int, int) {
+		count, page := ctx.Driver.GetEipList(pageSize, currentPage)
+		list = append(list, page...)
+		return count, len(page)
+	})
+	failed := 0
+	for _, eip := range list {
+		filter := bson.M{
+			"accountId": eip.AccountID,
+			"addressId": eip.AddressID,
+		}
+		if ctx.upsertAndNotify(navite.EIPTable, constants.ResourceEip, eip.AddressID, filter, eip, &navite.Eip{}) != nil {
+			failed++
+		}
+	}
+	if syncErr != nil {
+		return syncErr
+	}
+	if err := upsertFailed(navite.EIPTable, failed, len(list)); err != nil {
+		return err
+	}
+	seen := make([]string, 0, len(list))
+	for _, eip := range list {
+		seen = append(seen, eip.AddressID)
+	}
+	return tombstone(ctx, navite.EIPTable, constants.ResourceEip, "addressId", count, seen)
+}
+
+func syncQuota(ctx *JobContext) error {
+	rbd := ctx.Worker.rbd
+	list := ctx.Driver.GetQuotaList()
+	syncErr := ctx.Driver.SyncError()
+	failed := 0
+	for _, quota := range list {
+		filter := bson.M{
+			"accountId": quota.AccountID,
+			"regionId":  quota.RegionID,
+			"zoneId":    quota.ZoneID,
+			"quotaName": quota.QuotaName,
+		}
+		if upsert(rbd, navite.QuotaTable, filter, quota) != nil {
+			failed++
+		}
+	}
+	if syncErr != nil {
+		return syncErr
+	}
+	return upsertFailed(navite.QuotaTable, failed, len(list))
+}
+
+// collectMetric 采集最近两个降采样周期的监控数据, 重叠部分按时间点覆盖
+func collectMetric(ctx *JobContext) error {
+	endTime := time.Now()
+	startTime := endTime.Add(-2 * navite.MetricInterval)
+	return misc.CollectInstanceMetric(ctx.Worker.rbd, ctx.Account, startTime, endTime)
+}
+
+func lintSecurityGroup(ctx *JobContext) error {
+	_, err := analyzer.LintSecurityGroups(ctx.Worker.rbd, ctx.Job.AccountID, ctx.Job.RegionID)
+	return err
+}
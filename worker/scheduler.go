@@ -0,0 +1,252 @@
+package worker
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/clients/rabbitmq"
+	"ark-common/clients/redis"
+	"ark-common/constants"
+	"ark-common/misc"
+	"ark-common/plugin"
+	"ark-common/resource/manage"
+	"ark-common/resource/navite"
+	"math/rand"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// DefaultIntervals 各类作业默认的同步间隔, 未配置的作业使用DefaultInterval
+var DefaultIntervals = map[string]time.Duration{
+	constants.HandleSyncRegion:            24 * time.Hour,
+	constants.HandleSyncZone:              24 * time.Hour,
+	constants.HandleSyncInstanceSpec:      24 * time.Hour,
+	constants.HandleSyncImage:             6 * time.Hour,
+	constants.HandleSyncQuota:             6 * time.Hour,
+	constants.HandleSyncKeypair:           time.Hour,
+	constants.HandleSyncVPC:               30 * time.Minute,
+	constants.HandleSyncSubnet:            30 * time.Minute,
+	constants.HandleSyncSecurityGroup:     15 * time.Minute,
+	constants.HandleSyncSecurityGroupRule: 15 * time.Minute,
+	constants.HandleSyncInstance:          5 * time.Minute,
+	constants.HandleSyncDisk:              5 * time.Minute,
+	constants.HandleSyncEip:               5 * time.Minute,
+}
+
+const (
+	// DefaultInterval 未配置间隔的作业的同步间隔
+	DefaultInterval = time.Hour
+	// DefaultJitter 同步间隔的随机抖动比例
+	DefaultJitter = 0.1
+	// DefaultTick 调度器检查到期作业的间隔
+	DefaultTick = 30 * time.Second
+	// DefaultStaleAfter 超过该时长没有状态变更或进度上报的pending/working作业视为已丢失, 不再阻塞调度
+	DefaultStaleAfter = time.Hour
+)
+
+// NextRunTime 返回下一次执行的时间, 在interval上增加±jitter比例的随机抖动
+func NextRunTime(last time.Time, interval time.Duration, jitter float64) time.Time {
+	if jitter <= 0 {
+		return last.Add(interval)
+	}
+	if jitter > 1 {
+		jitter = 1
+	}
+	delta := time.Duration(float64(interval) * jitter * (2*rand.Float64() - 1))
+	return last.Add(interval + delta)
+}
+
+// Scheduler 定时为所有可用账号发送驱动SyncJobs()中的同步作业
+//
+// * 地域同步按账号调度, 其余作业按账号已同步的每个地域调度
+// * 同一账号同一地域的同一作业仍在pending/working时跳过本次调度
+type Scheduler struct {
+	rbd *mgo.Client
+	q   misc.JobQueue
+
+	mu   sync.Mutex
+	next map[string]time.Time // key为 accountId/regionId/action
+
+	Intervals  map[string]time.Duration
+	Jitter     float64
+	Tick       time.Duration
+	StaleAfter time.Duration
+
+	// 多副本部署时只有leader发送作业, 为nil时总是发送
+	Elector *redis.Elector
+}
+
+// NewScheduler 返回使用默认间隔的调度器
+func NewScheduler(rbd *mgo.Client, q *rabbitmq.RabbitQueue) *Scheduler {
+	intervals := make(map[string]time.Duration, len(DefaultIntervals))
+	for action, interval := range DefaultIntervals {
+		intervals[action] = interval
+	}
+	return &Scheduler{
+		rbd:        rbd,
+		q:          q,
+		next:       map[string]time.Time{},
+		Intervals:  intervals,
+		Jitter:     DefaultJitter,
+		Tick:       DefaultTick,
+		StaleAfter: DefaultStaleAfter,
+	}
+}
+
+// Run 按Tick检查并发送到期的作业, 直到stop被关闭
+func (s *Scheduler) Run(stop <-chan struct{}) {
+	tick := s.Tick
+	if tick <= 0 {
+		tick = DefaultTick
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	s.Schedule(time.Now())
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			s.Schedule(now)
+		}
+	}
+}
+
+// Schedule 为所有可用账号发送在now之前到期的作业, 非leader节点不发送
+//
+// * 发送前巡检父作业, 超过StaleAfter的子作业被取消, 父作业不会一直停留在working
+// * 每个账号发送前用本轮开始时的fencing token确认租约, 租约已被其他节点取得时停止本轮调度, 避免旧leader与新leader同时发送
+func (s *Scheduler) Schedule(now time.Time) {
+	var token int64
+	if s.Elector != nil {
+		if token = s.Elector.Token(); token == 0 {
+			return
+		}
+	}
+	if err := navite.SweepParentJobs(s.rbd, s.staleAfter(), now); err != nil {
+		log.Warnf("sweep parent jobs failed: %v", err)
+	}
+	_, accountList := manage.GetAvailableCloudAccount(s.rbd)
+	for _, ac := range accountList {
+		if s.Elector != nil {
+			if err := s.Elector.Fence(token); err != nil {
+				log.Warnf("fencing token %d rejected, stop schedule: %v", token, err)
+				return
+			}
+		}
+		s.scheduleAccount(ac, now, nil)
+	}
+}
+
+// SyncNow 立即发送账号的所有同步作业, 仍在执行中的作业除外
+//
+// * 发送的作业挂在一个HandleSyncAccount父作业下, 可通过父作业查看整体进度
+func (s *Scheduler) SyncNow(accountID string) (parent *navite.Job, errCode int) {
+	ac, errCode := manage.GetCloudAccount(s.rbd, accountID)
+	if errCode != constants.Success {
+		return nil, errCode
+	}
+	parent, err := misc.NewParentJob(s.rbd, constants.HandleSyncAccount, accountID, ac.CloudName, constants.SYSTEMUSER)
+	if err != nil {
+		return nil, constants.ServerError
+	}
+	sent := s.scheduleAccount(ac, time.Now(), parent)
+	if err = misc.SealParentJob(s.rbd, parent, sent); err != nil {
+		log.Errorf("seal parent job [%s] failed: %v", parent.JobID, err)
+	}
+	return parent, constants.Success
+}
+
+// scheduleAccount 发送账号到期的作业, parent非nil时立即发送并作为子作业, 返回发送的作业数
+func (s *Scheduler) scheduleAccount(ac *navite.CloudAccount, now time.Time, parent *navite.Job) (sent int) {
+	accountID := ac.AccountID()
+	if s.scheduleJob(ac, "", constants.HandleSyncRegion, now, parent) {
+		sent++
+	}
+
+	regionList := manage.GetRegions(s.rbd, ac)
+	if len(regionList) == 0 {
+		return
+	}
+	ac.RunRegionID = regionList[0].RegionID
+	driver := plugin.GetCloudDriver(ac)
+	if driver == nil {
+		log.Warnf("account [%s] has no driver, skip schedule", accountID)
+		return
+	}
+	for _, region := range regionList {
+		for _, action := range driver.SyncJobs() {
+			if s.scheduleJob(ac, region.RegionID, action, now, parent) {
+				sent++
+			}
+		}
+	}
+	return
+}
+
+func (s *Scheduler) scheduleJob(ac *navite.CloudAccount, regionID, action string, now time.Time, parent *navite.Job) bool {
+	accountID := ac.AccountID()
+	key := accountID + "/" + regionID + "/" + action
+	interval, ok := s.Intervals[action]
+	if !ok {
+		interval = DefaultInterval
+	}
+	force := parent != nil
+
+	s.mu.Lock()
+	next, scheduled := s.next[key]
+	if !scheduled && !force {
+		// 首次调度分散在一个抖动范围内, 避免所有账号同时同步
+		next = now.Add(time.Duration(float64(interval) * s.Jitter * rand.Float64()))
+		s.next[key] = next
+	}
+	due := force || !next.After(now)
+	s.mu.Unlock()
+	if !due {
+		return false
+	}
+
+	if s.running(accountID, regionID, action, now) {
+		log.Infof("job [%s] of account [%s] region [%s] still running, skip", action, accountID, regionID)
+		return false
+	}
+	var job *navite.Job
+	if parent != nil {
+		job = misc.SendChildJob(s.rbd, s.q, parent, regionID, action)
+	} else {
+		job = misc.SendSyncJob(s.rbd, s.q, accountID, ac.CloudName, regionID, action)
+	}
+	if job == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	s.next[key] = NextRunTime(now, interval, s.Jitter)
+	s.mu.Unlock()
+	return true
+}
+
+func (s *Scheduler) staleAfter() time.Duration {
+	if s.StaleAfter <= 0 {
+		return DefaultStaleAfter
+	}
+	return s.StaleAfter
+}
+
+// running 判断是否有未过期的pending/working/retrying作业
+func (s *Scheduler) running(accountID, regionID, action string, now time.Time) bool {
+	filter := bson.M{
+		"accountId":     accountID,
+		"regionId":      regionID,
+		"action":        action,
+		"status":        bson.M{"$in": []string{constants.PENDINGJOB, constants.WORKINGJOB, constants.RETRYJOB}},
+		"heartbeatTime": bson.M{"$gt": now.Add(-s.staleAfter())},
+	}
+	count, err := s.rbd.Table(navite.JobTable).Count(filter, nil)
+	if err != nil {
+		log.Errorf("count [%v] jobs failed: %v", filter, err)
+		return true
+	}
+	return count > 0
+}
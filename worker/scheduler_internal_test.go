@@ -0,0 +1,106 @@
+package worker
+
+import (
+	"ark-common/constants"
+	"ark-common/misc"
+	"ark-common/resource/navite"
+	"ark-common/utils/testenv"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeQueue 记录发布的作业, err非nil时发布失败
+type fakeQueue struct {
+	jobs []*navite.Job
+	err  error
+}
+
+func (q *fakeQueue) Push(exchangeName, exchangeKind string, routingKey string, data []byte) error {
+	if q.err != nil {
+		return q.err
+	}
+	job := &navite.Job{}
+	if err := json.Unmarshal(data, job); err != nil {
+		return err
+	}
+	q.jobs = append(q.jobs, job)
+	return nil
+}
+
+func TestScheduleJob(t *testing.T) {
+	rbd := testenv.Mongo(t)
+	Convey("调度账号地域下的一个同步作业", t, func() {
+		ac := &navite.CloudAccount{ID: primitive.NewObjectID(), CloudName: constants.Aliyun}
+		accountID := ac.AccountID()
+		defer rbd.Table(navite.JobTable).DeleteMany(bson.M{"accountId": accountID})
+
+		q := &fakeQueue{}
+		s := NewScheduler(rbd, nil)
+		s.q = q
+		s.Jitter = 0
+		regionID, action := "cn-beijing", constants.HandleSyncInstance
+		key := accountID + "/" + regionID + "/" + action
+		now := time.Now()
+
+		Convey("首次调度没有抖动时立即发送, 并按间隔推进下一次时间", func() {
+			So(s.scheduleJob(ac, regionID, action, now, nil), ShouldBeTrue)
+			So(len(q.jobs), ShouldEqual, 1)
+			So(q.jobs[0].Action, ShouldEqual, action)
+			So(s.next[key], ShouldEqual, now.Add(s.Intervals[action]))
+		})
+
+		Convey("未到期时不发送", func() {
+			s.next[key] = now.Add(time.Minute)
+			So(s.scheduleJob(ac, regionID, action, now, nil), ShouldBeFalse)
+			So(q.jobs, ShouldBeEmpty)
+		})
+
+		Convey("上一次的作业仍在pending时跳过", func() {
+			So(s.scheduleJob(ac, regionID, action, now, nil), ShouldBeTrue)
+			s.next[key] = now
+			So(s.scheduleJob(ac, regionID, action, now, nil), ShouldBeFalse)
+			So(len(q.jobs), ShouldEqual, 1)
+
+			Convey("作业超过StaleAfter没有心跳时不再阻塞调度", func() {
+				later := now.Add(s.staleAfter() + time.Minute)
+				s.next[key] = later
+				So(s.scheduleJob(ac, regionID, action, later, nil), ShouldBeTrue)
+				So(len(q.jobs), ShouldEqual, 2)
+			})
+		})
+
+		Convey("有父作业时忽略下一次时间立即发送子作业", func() {
+			s.next[key] = now.Add(time.Hour)
+			parent, err := misc.NewParentJob(rbd, constants.HandleSyncAccount, accountID, ac.CloudName, constants.SYSTEMUSER)
+			So(err, ShouldBeNil)
+			So(s.scheduleJob(ac, regionID, action, now, parent), ShouldBeTrue)
+			So(len(q.jobs), ShouldEqual, 1)
+			So(q.jobs[0].ParentID, ShouldEqual, parent.JobID)
+
+			Convey("子作业仍在执行时不重复发送", func() {
+				So(s.scheduleJob(ac, regionID, action, now, parent), ShouldBeFalse)
+				So(len(q.jobs), ShouldEqual, 1)
+			})
+		})
+
+		Convey("发布失败时取消作业, 不推进下一次时间", func() {
+			q.err = errors.New("channel closed")
+			So(s.scheduleJob(ac, regionID, action, now, nil), ShouldBeFalse)
+			So(s.next[key], ShouldEqual, now)
+
+			stored := &navite.Job{}
+			So(rbd.Table(navite.JobTable).QueryOne(bson.M{"accountId": accountID, "action": action}, stored, nil), ShouldBeNil)
+			So(stored.Status, ShouldEqual, constants.CANCELJOB)
+
+			q.err = nil
+			So(s.scheduleJob(ac, regionID, action, now, nil), ShouldBeTrue)
+			So(len(q.jobs), ShouldEqual, 1)
+		})
+	})
+}
@@ -0,0 +1,24 @@
+package worker_test
+
+import (
+	"ark-common/worker"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNextRunTime(t *testing.T) {
+	Convey("计算下一次同步的时间", t, func() {
+		last := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		Convey("没有抖动时按间隔执行", func() {
+			So(worker.NextRunTime(last, 5*time.Minute, 0), ShouldEqual, last.Add(5*time.Minute))
+		})
+		Convey("抖动不超过间隔的比例", func() {
+			for i := 0; i < 100; i++ {
+				next := worker.NextRunTime(last, 10*time.Minute, 0.1)
+				So(next, ShouldHappenOnOrBetween, last.Add(9*time.Minute), last.Add(11*time.Minute))
+			}
+		})
+	})
+}
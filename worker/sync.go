@@ -0,0 +1,245 @@
+package worker
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/clients/rabbitmq"
+	"ark-common/constants"
+	"ark-common/misc"
+	"ark-common/plugin"
+	"ark-common/resource/manage"
+	"ark-common/resource/navite"
+	"ark-common/utils/system"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// DefaultPageSize 分页同步时每页的数量
+const DefaultPageSize = 50
+
+// JobContext 作业执行时的上下文
+type JobContext struct {
+	Job     *navite.Job
+	Account *navite.CloudAccount // RunRegionID 为作业的地域
+	Driver  plugin.ResourceDriver
+	Worker  *SyncWorker
+}
+
+// Handler 作业的处理函数
+type Handler func(ctx *JobContext) error
+
+// SyncWorker 消费LeaderExchange中的同步作业, 调用对应云商的驱动并把结果写入mongo
+type SyncWorker struct {
+	rbd       *mgo.Client
+	q         *rabbitmq.RabbitQueue
+	queueName string
+	handlers  map[string]Handler
+
+	ID          string                                              // worker标识, 作业进入working时记录
+	NewDriver   func(ac *navite.CloudAccount) plugin.ResourceDriver // 返回账号的云商驱动, 默认为plugin.GetCloudDriver
+	PageSize    int                                                 // 分页同步时每页的数量
+	Concurrency int                                                 // 同时执行的作业数
+}
+
+// NewSyncWorker 返回同步作业的消费者, 已注册所有Handle*作业的处理函数
+func NewSyncWorker(rbd *mgo.Client, q *rabbitmq.RabbitQueue, queueName string) *SyncWorker {
+	w := &SyncWorker{
+		rbd:         rbd,
+		q:           q,
+		queueName:   queueName,
+		handlers:    map[string]Handler{},
+		ID:          fmt.Sprintf("%s-%d", system.GetHostName(), os.Getpid()),
+		NewDriver:   plugin.GetCloudDriver,
+		PageSize:    DefaultPageSize,
+		Concurrency: 1,
+	}
+	w.Register(constants.HandleSyncRegion, syncRegion)
+	w.Register(constants.HandleSyncZone, syncZone)
+	w.Register(constants.HandleSyncInstanceSpec, syncInstanceSpec)
+	w.Register(constants.HandleSyncImage, syncImage)
+	w.Register(constants.HandleSyncInstance, syncInstance)
+	w.Register(constants.HandleSyncSecurityGroup, syncSecurityGroup)
+	w.Register(constants.HandleSyncSecurityGroupRule, syncSecurityGroupRule)
+	w.Register(constants.HandleSyncDisk, syncDisk)
+	w.Register(constants.HandleSyncKeypair, syncKeypair)
+	w.Register(constants.HandleSyncVPC, syncVPC)
+	w.Register(constants.HandleSyncSubnet, syncSubnet)
+	w.Register(constants.HandleSyncEip, syncEip)
+	w.Register(constants.HandleSyncQuota, syncQuota)
+	w.Register(constants.HandleCollectMetric, collectMetric)
+	w.Register(constants.HandleLintSecurityGroup, lintSecurityGroup)
+	return w
+}
+
+// Register 注册或替换作业的处理函数
+func (w *SyncWorker) Register(action string, handler Handler) {
+	w.handlers[action] = handler
+}
+
+// Run 绑定队列并开始消费作业, 直到stop被关闭
+func (w *SyncWorker) Run(stop <-chan struct{}) (err error) {
+	if err = w.q.BindExchange(constants.LeaderExchange, w.queueName, constants.SyncJobRoutingKey); err != nil {
+		log.Errorf("bind queue [%s] to exchange [%s] failed: %v", w.queueName, constants.LeaderExchange, err)
+		return err
+	}
+	if err = w.q.BindExchange(constants.DeadLetterExchange, constants.DeadLetterQueue, constants.DeadLetterRoutingKey); err != nil {
+		log.Errorf("bind dead letter queue [%s] failed: %v", constants.DeadLetterQueue, err)
+		return err
+	}
+	if err = misc.SetupMetricIndex(w.rbd); err != nil {
+		log.Warnf("setup metric index failed, expired metrics will not be removed: %v", err)
+	}
+	message := make(chan []byte)
+	if err = w.q.Listen(w.queueName, constants.SyncJobRoutingKey, message); err != nil {
+		log.Errorf("listen queue [%s] failed: %v", w.queueName, err)
+		return err
+	}
+
+	concurrency := w.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	wg := &sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				case body := <-message:
+					job := &navite.Job{}
+					if e := json.Unmarshal(body, job); e != nil {
+						log.Errorf("decode job [%s] failed: %v", string(body), e)
+						continue
+					}
+					w.Handle(w.loadJob(job))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// loadJob 从mongo中读取完整的作业, 消息体中不包含Params
+func (w *SyncWorker) loadJob(job *navite.Job) *navite.Job {
+	stored := &navite.Job{}
+	filter := bson.M{
+		"jobId": job.JobID,
+	}
+	if err := w.rbd.Table(navite.JobTable).QueryOne(filter, stored, nil); err != nil {
+		log.Warnf("query job [%s] failed, use the message instead: %v", job.JobID, err)
+		return job
+	}
+	return stored
+}
+
+// Handle 领取并执行一个作业, 并更新作业的状态
+//
+// * 作业已被其他worker领取或已结束时返回SetWorking的错误, 不执行
+func (w *SyncWorker) Handle(job *navite.Job) (err error) {
+	if err = job.SetWorking(w.rbd, w.ID); err != nil {
+		return err
+	}
+	defer func() {
+		// 子作业结束后汇总父作业, 等待重试时不汇总
+		if job.ParentID != "" && job.Status != constants.RETRYJOB {
+			navite.AggregateParentJob(w.rbd, job.ParentID)
+		}
+	}()
+	handler, ok := w.handlers[job.Action]
+	if !ok {
+		err = fmt.Errorf("not support action %s", job.Action)
+		job.SetCancel(w.rbd, err.Error())
+		return err
+	}
+	ac, errCode := manage.GetCloudAccount(w.rbd, job.AccountID)
+	if errCode != constants.Success {
+		job.SetCancel(w.rbd, constants.CodeMessage[errCode][constants.EN])
+		return fmt.Errorf("get account %s failed: %d", job.AccountID, errCode)
+	}
+	ac.RunRegionID = job.RegionID
+	driver := w.NewDriver(ac)
+	if driver == nil {
+		err = fmt.Errorf("not support cloud %s", ac.CloudName)
+		job.SetCancel(w.rbd, err.Error())
+		return err
+	}
+
+	err = handler(&JobContext{
+		Job:     job,
+		Account: ac,
+		Driver:  driver,
+		Worker:  w,
+	})
+	if err != nil {
+		log.Errorf("handle job [%s] action [%s] failed: %v", job.JobID, job.Action, err)
+		if e := w.fail(job, err); e != nil {
+			return e
+		}
+		return err
+	}
+	job.Reason, job.ErrorClass = "", ""
+	return job.SetSuccess(w.rbd)
+}
+
+// fail 可重试的错误延时后重新发布作业, 否则置为失败并投递到死信队列
+//
+// * cause为处理函数返回的错误, 由plugin.ClassifyError判断错误类型
+// * 云商接口的错误(driver.SyncError)按错误码分类, 限流、网络和服务端错误可以重试
+// * 部分资源写入mongo失败(upsertFailed)和其他错误为unknown, 不重试
+// * 重新发布失败时作业从retrying置为failed并投递到死信队列, 不会停留在retrying
+// * 没有配置队列时(如测试中NewSyncWorker(rbd, nil, ...))不重试, 只将作业置为failed
+func (w *SyncWorker) fail(job *navite.Job, cause error) (err error) {
+	job.Reason = cause.Error()
+	job.ErrorClass = plugin.ClassifyError(cause)
+	retryable := job.Retryable(job.ErrorClass)
+	if retryable && w.q == nil {
+		log.Warnf("job [%s] is retryable but the worker has no queue to republish it", job.JobID)
+		job.Reason = fmt.Sprintf("%s; retry failed: no queue", job.Reason)
+	} else if retryable {
+		delay := job.NextBackoff()
+		if err = job.SetRetrying(w.rbd); err != nil {
+			return err
+		}
+		body, _ := json.Marshal(job)
+		if err = w.q.PushDelay(constants.LeaderExchange, constants.SyncJobRoutingKey, body, delay); err == nil {
+			log.Warnf("job [%s] attempt %d failed with %s, retry after %v", job.JobID, job.Attempt, job.ErrorClass, delay)
+			return nil
+		}
+		log.Errorf("retry job [%s] after %v failed, dead letter it: %v", job.JobID, delay, err)
+		job.Reason = fmt.Sprintf("%s; retry failed: %v", job.Reason, err)
+	}
+
+	if err = job.SetFailed(w.rbd); err != nil {
+		return err
+	}
+	if w.q == nil {
+		log.Warnf("job [%s] failed without a queue to dead letter it: %s", job.JobID, job.Reason)
+		return nil
+	}
+	body, _ := json.Marshal(job)
+	if err = w.q.Push(constants.DeadLetterExchange, amqp.ExchangeTopic, "deadjob."+job.Action, body); err != nil {
+		log.Errorf("dead letter job [%s] failed: %v", job.JobID, err)
+		return err
+	}
+	return job.SetDeadLettered(w.rbd)
+}
+
+// Actions 返回已注册处理函数的作业, 用作服务注册时的能力
+func (w *SyncWorker) Actions() []string {
+	actions := make([]string, 0, len(w.handlers))
+	for action := range w.handlers {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
@@ -0,0 +1,145 @@
+package worker_test
+
+import (
+	"ark-common/clients/mgo"
+	"ark-common/constants"
+	"ark-common/plugin"
+	"ark-common/resource/navite"
+	"ark-common/utils/testenv"
+	"ark-common/utils/tool"
+	"ark-common/worker"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeDriver 只实现同步实例的驱动
+type fakeDriver struct {
+	plugin.ResourceDriver
+	instances []*navite.Instance
+	err       error
+}
+
+func (d *fakeDriver) SyncError() error {
+	return d.err
+}
+
+func (d *fakeDriver) GetInstanceList(pageSize, currentPage int) (count int, instanceList []*navite.Instance) {
+	start := (currentPage - 1) * pageSize
+	if start >= len(d.instances) {
+		return len(d.instances), nil
+	}
+	end := start + pageSize
+	if end > len(d.instances) {
+		end = len(d.instances)
+	}
+	return len(d.instances), d.instances[start:end]
+}
+
+func newTestAccount(rbd *mgo.Client) *navite.CloudAccount {
+	ac := &navite.CloudAccount{
+		ID:          primitive.NewObjectID(),
+		AccountName: "worker-test",
+		CloudName:   constants.Aliyun,
+		Healthy:     true,
+		CreatedTime: time.Now(),
+	}
+	rbd.Table(navite.CloudAccountTable).Insert(ac)
+	return ac
+}
+
+func newTestJob(rbd *mgo.Client, accountID, action string) *navite.Job {
+	job := &navite.Job{
+		Action:    action,
+		CloudName: constants.Aliyun,
+		AccountID: accountID,
+		RegionID:  "cn-beijing",
+		JobID:     tool.UUID(),
+		Owner:     constants.SYSTEMUSER,
+	}
+	job.SetPending(rbd)
+	return job
+}
+
+func newTestInstance(accountID, instanceID string) *navite.Instance {
+	return &navite.Instance{
+		CloudName:  constants.Aliyun,
+		AccountID:  accountID,
+		RegionID:   "cn-beijing",
+		InstanceID: instanceID,
+		Status:     "Running",
+		SyncedTime: time.Now(),
+	}
+}
+
+func cleanupAccount(rbd *mgo.Client, accountID string) {
+	filter := bson.M{
+		"accountId": accountID,
+	}
+	rbd.Table(navite.InstanceTable).DeleteMany(filter)
+	rbd.Table(navite.JobTable).DeleteMany(filter)
+	accountObjectID, _ := primitive.ObjectIDFromHex(accountID)
+	rbd.Table(navite.CloudAccountTable).DeleteMany(bson.M{"_id": accountObjectID})
+}
+
+func TestSyncInstance(t *testing.T) {
+	rbd := testenv.Mongo(t)
+	Convey("同步实例到空的集合", t, func() {
+		ac := newTestAccount(rbd)
+		accountID := ac.AccountID()
+		defer cleanupAccount(rbd, accountID)
+
+		driver := &fakeDriver{
+			instances: []*navite.Instance{
+				newTestInstance(accountID, "i-test-1"),
+				newTestInstance(accountID, "i-test-2"),
+				newTestInstance(accountID, "i-test-3"),
+			},
+		}
+		w := worker.NewSyncWorker(rbd, nil, "")
+		w.PageSize = 2
+		w.NewDriver = func(*navite.CloudAccount) plugin.ResourceDriver {
+			return driver
+		}
+
+		job := newTestJob(rbd, accountID, constants.HandleSyncInstance)
+		So(w.Handle(job), ShouldBeNil)
+		So(job.Status, ShouldEqual, constants.SUCCESSJOB)
+		count, err := rbd.Table(navite.InstanceTable).Count(bson.M{"accountId": accountID}, nil)
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 3)
+
+		Convey("再次同步时更新已有的实例", func() {
+			driver.instances[0].Status = "Stopped"
+			job := newTestJob(rbd, accountID, constants.HandleSyncInstance)
+			So(w.Handle(job), ShouldBeNil)
+			stored := &navite.Instance{}
+			err := rbd.Table(navite.InstanceTable).QueryOne(bson.M{"accountId": accountID, "instanceId": "i-test-1"}, stored, nil)
+			So(err, ShouldBeNil)
+			So(stored.Status, ShouldEqual, "Stopped")
+		})
+
+		Convey("云端删除的实例被标记为已删除", func() {
+			driver.instances = driver.instances[:2]
+			job := newTestJob(rbd, accountID, constants.HandleSyncInstance)
+			So(w.Handle(job), ShouldBeNil)
+			stored := &navite.Instance{}
+			err := rbd.Table(navite.InstanceTable).QueryOne(bson.M{"accountId": accountID, "instanceId": "i-test-3"}, stored, nil)
+			So(err, ShouldBeNil)
+			So(stored.Deleted, ShouldBeTrue)
+		})
+
+		Convey("没有队列时可重试的错误不重试, 作业置为失败", func() {
+			driver.err = errors.New("Throttling.User: Request was denied due to user flow control")
+			job := newTestJob(rbd, accountID, constants.HandleSyncInstance)
+			So(w.Handle(job), ShouldNotBeNil)
+			So(job.Status, ShouldEqual, constants.FAILEDJOB)
+			So(job.ErrorClass, ShouldEqual, constants.ErrorThrottling)
+			So(job.Reason, ShouldContainSubstring, "no queue")
+		})
+	})
+}
@@ -0,0 +1,90 @@
+package worker
+
+import (
+	"ark-common/constants"
+	"ark-common/resource/navite"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// tombstone 把账号地域下本次同步中没有出现的资源标记为已删除, 并发布deleted事件
+//
+// * 仅在完整的同步后执行, 见syncComplete
+// * 总数为0时不做标记, 因此地域下最后一个资源在云端删除后不会被标记为删除, 会一直保留到云端再出现资源
+func tombstone(ctx *JobContext, table, resourceType, idField string, count int, seen []string) (err error) {
+	accountID, regionID := ctx.Job.AccountID, ctx.Job.RegionID
+	ids, complete := syncComplete(count, seen)
+	if !complete {
+		log.Warnf("sync %s of account [%s] region [%s] incomplete (%d/%d), skip tombstone", table, accountID, regionID, len(ids), count)
+		return nil
+	}
+
+	filter := bson.M{
+		"accountId": accountID,
+		"regionId":  regionID,
+		idField:     bson.M{"$nin": ids},
+		"deleted":   bson.M{"$ne": true},
+	}
+	rbd := ctx.Worker.rbd
+	goneList := []bson.M{}
+	mctx := context.Background()
+	cur, err := rbd.Table(table).Query(filter, 0, 0, nil)
+	if err != nil {
+		log.Errorf("filter [%v] %s failed: %v", filter, table, err)
+		return err
+	}
+	defer cur.Close(mctx)
+	if err = cur.All(mctx, &goneList); err != nil {
+		log.Errorf("decord mgo document failed: %v", err)
+		return err
+	}
+	if len(goneList) == 0 {
+		return nil
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"deleted":     true,
+			"deletedTime": time.Now(),
+		},
+	}
+	if err = rbd.Table(table).Update(filter, update, nil); err != nil {
+		log.Errorf("tombstone [%v] %s failed: %v", filter, table, err)
+		return err
+	}
+	for _, gone := range goneList {
+		delete(gone, "_id")
+		ctx.Worker.publish(&navite.ResourceEvent{
+			Event:        constants.EventDeleted,
+			CloudName:    ctx.Job.CloudName,
+			AccountID:    accountID,
+			RegionID:     regionID,
+			ResourceType: resourceType,
+			ResourceID:   fmt.Sprint(gone[idField]),
+			Resource:     gone,
+		})
+	}
+	return nil
+}
+
+// syncComplete 返回本次同步拉取到的不重复资源ID, 以及同步是否完整
+//
+// * 云端返回的总数大于0, 且拉取到的不重复资源数不少于总数时才算完整
+// * 旧版驱动在接口出错时返回的总数为0, 无法与云端确实没有资源区分, 因此总数为0时视为不完整
+func syncComplete(count int, seen []string) (ids []string, complete bool) {
+	unique := map[string]bool{}
+	ids = []string{}
+	for _, id := range seen {
+		if !unique[id] {
+			unique[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, count > 0 && len(ids) >= count
+}
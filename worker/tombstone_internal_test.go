@@ -0,0 +1,31 @@
+package worker
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSyncComplete(t *testing.T) {
+	Convey("判断同步是否完整", t, func() {
+		Convey("拉取到全部资源", func() {
+			ids, complete := syncComplete(3, []string{"i-3", "i-1", "i-2"})
+			So(complete, ShouldBeTrue)
+			So(ids, ShouldResemble, []string{"i-1", "i-2", "i-3"})
+		})
+		Convey("重复的资源只计算一次", func() {
+			ids, complete := syncComplete(3, []string{"i-1", "i-2", "i-2"})
+			So(complete, ShouldBeFalse)
+			So(len(ids), ShouldEqual, 2)
+		})
+		Convey("同步过程中新增了资源", func() {
+			_, complete := syncComplete(2, []string{"i-1", "i-2", "i-3"})
+			So(complete, ShouldBeTrue)
+		})
+		Convey("总数为0时视为不完整", func() {
+			ids, complete := syncComplete(0, nil)
+			So(complete, ShouldBeFalse)
+			So(ids, ShouldBeEmpty)
+		})
+	})
+}